@@ -0,0 +1,67 @@
+package activation
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/syncloud/platform/backup"
+	"go.uber.org/zap"
+)
+
+type PlatformBackup interface {
+	UploadWithKeys(fileName string, reader io.Reader, keys backup.Encryption) (backup.Verification, error)
+	RestoreWithKeys(fileName string, keys backup.Encryption) error
+	Remove(fileName string, remote bool) error
+}
+
+type RestoreActivation interface {
+	Activate(fileName string, reader io.Reader, keys backup.Encryption) error
+}
+
+// Restore activates a fresh device from a platform backup of another device.
+type Restore struct {
+	backup         PlatformBackup
+	internalMemory InternalMemory
+	logger         *zap.Logger
+}
+
+func NewRestore(backup PlatformBackup, internalMemory InternalMemory, logger *zap.Logger) *Restore {
+	return &Restore{
+		backup:         backup,
+		internalMemory: internalMemory,
+		logger:         logger,
+	}
+}
+
+// Activate restores the uploaded backup, an encrypted one needs its passphrase or identity in keys
+// as the fresh device has no backup encryption configured.
+func (r *Restore) Activate(fileName string, reader io.Reader, keys backup.Encryption) error {
+	r.logger.Info("activate from backup", zap.String("file", fileName))
+	file, err := backup.Parse("", fileName)
+	if err != nil {
+		return err
+	}
+	if file.App != backup.PlatformApp {
+		return fmt.Errorf("%s is not a platform backup", fileName)
+	}
+
+	err = r.internalMemory.BootExtend()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.backup.UploadWithKeys(fileName, reader, keys)
+	if err != nil {
+		return err
+	}
+	err = r.backup.RestoreWithKeys(fileName, keys)
+	if err != nil {
+		removeErr := r.backup.Remove(fileName, false)
+		if removeErr != nil {
+			r.logger.Warn("cannot remove uploaded backup", zap.Error(removeErr))
+		}
+		return err
+	}
+	r.logger.Info("activation from backup completed")
+	return nil
+}
@@ -0,0 +1,78 @@
+package activation
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/backup"
+	"github.com/syncloud/platform/log"
+)
+
+type PlatformBackupStub struct {
+	uploaded   string
+	keys       backup.Encryption
+	restoreErr error
+	removed    bool
+}
+
+func (b *PlatformBackupStub) UploadWithKeys(fileName string, _ io.Reader, _ backup.Encryption) (backup.Verification, error) {
+	b.uploaded = fileName
+	return backup.Verification{File: fileName, App: backup.PlatformApp}, nil
+}
+
+func (b *PlatformBackupStub) RestoreWithKeys(_ string, keys backup.Encryption) error {
+	b.keys = keys
+	return b.restoreErr
+}
+
+func (b *PlatformBackupStub) Remove(_ string, _ bool) error {
+	b.removed = true
+	return nil
+}
+
+type InternalMemoryStub struct {
+	extended bool
+}
+
+func (m *InternalMemoryStub) BootExtend() error {
+	m.extended = true
+	return nil
+}
+
+func TestRestore_Activate(t *testing.T) {
+	platformBackup := &PlatformBackupStub{}
+	memory := &InternalMemoryStub{}
+	restore := NewRestore(platformBackup, memory, log.Default())
+	err := restore.Activate("platform-2001-0203-040506.tar.gz", strings.NewReader("backup"), backup.Encryption{})
+	assert.NoError(t, err)
+	assert.Equal(t, "platform-2001-0203-040506.tar.gz", platformBackup.uploaded)
+	assert.True(t, memory.extended)
+	assert.False(t, platformBackup.removed)
+}
+
+func TestRestore_Activate_Keys(t *testing.T) {
+	platformBackup := &PlatformBackupStub{}
+	restore := NewRestore(platformBackup, &InternalMemoryStub{}, log.Default())
+	err := restore.Activate("platform-2001-0203-040506.tar.gz.age", strings.NewReader("backup"), backup.Encryption{Passphrase: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", platformBackup.keys.Passphrase)
+}
+
+func TestRestore_Activate_NotPlatform(t *testing.T) {
+	platformBackup := &PlatformBackupStub{}
+	restore := NewRestore(platformBackup, &InternalMemoryStub{}, log.Default())
+	err := restore.Activate("files-2001-0203-040506.tar.gz", strings.NewReader("backup"), backup.Encryption{})
+	assert.ErrorContains(t, err, "not a platform backup")
+	assert.Empty(t, platformBackup.uploaded)
+}
+
+func TestRestore_Activate_Failed(t *testing.T) {
+	platformBackup := &PlatformBackupStub{restoreErr: fmt.Errorf("error")}
+	restore := NewRestore(platformBackup, &InternalMemoryStub{}, log.Default())
+	err := restore.Activate("platform-2001-0203-040506.tar.gz", strings.NewReader("backup"), backup.Encryption{})
+	assert.Error(t, err)
+	assert.True(t, platformBackup.removed)
+}
@@ -0,0 +1,106 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/syncloud/platform/config"
+)
+
+const mailRelayPort = "465"
+
+type MailRelayConfig interface {
+	IsMailRelayEnabled() bool
+	GetDomainUpdateToken() *string
+	GetDeviceDomain() string
+}
+
+type MailRedirect interface {
+	Domain() string
+	UserEmail() *string
+}
+
+// Email sends alerts through the mail relay of the redirect service, the same one the mail app uses.
+// The recipient is the channel address or the email of the redirect account.
+type Email struct {
+	config   MailRelayConfig
+	redirect MailRedirect
+}
+
+func NewEmail(config MailRelayConfig, redirect MailRedirect) *Email {
+	return &Email{
+		config:   config,
+		redirect: redirect,
+	}
+}
+
+func (e *Email) Send(channel config.AlertChannel, alert Alert) error {
+	token := e.config.GetDomainUpdateToken()
+	if !e.config.IsMailRelayEnabled() || token == nil {
+		return fmt.Errorf("mail relay is not enabled")
+	}
+	to := channel.To
+	if to == "" {
+		email := e.redirect.UserEmail()
+		if email == nil {
+			return fmt.Errorf("no recipient")
+		}
+		to = *email
+	}
+	domain := e.config.GetDeviceDomain()
+	from := fmt.Sprintf("alerts@%s", domain)
+	host := fmt.Sprintf("mail-relay.%s", e.redirect.Domain())
+
+	conn, err := tls.Dial("tcp", net.JoinHostPort(host, mailRelayPort), &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	err = client.Auth(smtp.PlainAuth("", domain, *token, host))
+	if err != nil {
+		return err
+	}
+	err = client.Mail(from)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(to)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(message(from, to, alert.Title(), alert.Message, alert.Since))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func message(from string, to string, subject string, body string, date time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", to))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
+	buf.WriteString(fmt.Sprintf("Date: %s\r\n", date.Format(time.RFC1123Z)))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(body)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,473 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/syncloud/platform/cert"
+	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/date"
+	"github.com/syncloud/platform/health"
+	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/storage/btrfs"
+	"github.com/syncloud/platform/storage/model"
+	"go.uber.org/zap"
+)
+
+const (
+	RuleDiskUsage         = "disk_usage"
+	RuleMemoryUsage       = "memory_usage"
+	RuleBtrfsErrors       = "btrfs_errors"
+	RuleCertificateExpiry = "certificate_expiry"
+	RuleStabilityEvent    = "stability_event"
+)
+
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// DefaultEventWindow is how long a stability event keeps its rule firing when the rule has no threshold.
+const DefaultEventWindow = time.Hour
+
+const interval = time.Minute
+
+type Alert struct {
+	Key     string    `json:"key"`
+	Rule    string    `json:"rule"`
+	Subject string    `json:"subject,omitempty"`
+	Message string    `json:"message"`
+	State   string    `json:"state"`
+	Since   time.Time `json:"since"`
+	Device  string    `json:"device"`
+}
+
+func (a Alert) Title() string {
+	return fmt.Sprintf("%s %s on %s", a.Rule, a.State, a.Device)
+}
+
+type Config interface {
+	Rules() ([]config.AlertRule, error)
+	AddRule(rule config.AlertRule) error
+	RemoveRule(name string) error
+	Channels() ([]config.AlertChannel, error)
+	AddChannel(channel config.AlertChannel) error
+	RemoveChannel(name string) error
+}
+
+type DeviceConfig interface {
+	GetDeviceDomain() string
+}
+
+type Snapshots interface {
+	Snapshot() (health.Snapshot, error)
+}
+
+type DiskSpace interface {
+	Status() model.DiskSpace
+}
+
+type DeviceErrors interface {
+	DeviceErrors() (*btrfs.DeviceStats, error)
+}
+
+type CertificateInfo interface {
+	ReadCertificateInfo() *cert.Info
+}
+
+type Events interface {
+	Recent(limit int) ([]stability.Event, error)
+}
+
+type Sender interface {
+	Send(channel config.AlertChannel, alert Alert) error
+}
+
+// Monitor evaluates the alert rules every minute and notifies the channels when an alert starts firing
+// or is resolved, alerts which keep firing are not repeated.
+// Firing alerts are saved to a file so a restart does not notify again.
+type Monitor struct {
+	config      Config
+	device      DeviceConfig
+	snapshots   Snapshots
+	diskSpace   DiskSpace
+	btrfs       DeviceErrors
+	certificate CertificateInfo
+	events      Events
+	sender      Sender
+	file        string
+	firing      map[string]Alert
+	mutex       *sync.Mutex
+	provider    date.Provider
+	logger      *zap.Logger
+}
+
+func NewMonitor(config Config, device DeviceConfig, snapshots Snapshots, diskSpace DiskSpace, btrfs DeviceErrors,
+	certificate CertificateInfo, events Events, sender Sender, file string, provider date.Provider, logger *zap.Logger) *Monitor {
+	m := &Monitor{
+		config:      config,
+		device:      device,
+		snapshots:   snapshots,
+		diskSpace:   diskSpace,
+		btrfs:       btrfs,
+		certificate: certificate,
+		events:      events,
+		sender:      sender,
+		file:        file,
+		firing:      make(map[string]Alert),
+		mutex:       &sync.Mutex{},
+		provider:    provider,
+		logger:      logger,
+	}
+	m.load()
+	return m
+}
+
+func (m *Monitor) load() {
+	data, err := os.ReadFile(m.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Warn("cannot read alerts", zap.Error(err))
+		}
+		return
+	}
+	err = json.Unmarshal(data, &m.firing)
+	if err != nil {
+		m.logger.Warn("cannot parse alerts", zap.Error(err))
+		m.firing = make(map[string]Alert)
+	}
+}
+
+func (m *Monitor) save() {
+	data, err := json.Marshal(m.firing)
+	if err != nil {
+		m.logger.Warn("cannot save alerts", zap.Error(err))
+		return
+	}
+	tmp := m.file + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err == nil {
+		err = os.Rename(tmp, m.file)
+	}
+	if err != nil {
+		m.logger.Warn("cannot save alerts", zap.Error(err))
+	}
+}
+
+func (m *Monitor) Start() error {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := m.Evaluate()
+			if err != nil {
+				m.logger.Warn("alert evaluation failed", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+type condition struct {
+	subject string
+	message string
+}
+
+func (m *Monitor) Evaluate() error {
+	rules, err := m.config.Rules()
+	if err != nil {
+		return err
+	}
+	active := make(map[string]Alert)
+	unknown := make(map[string]bool)
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		conditions, err := m.check(rule)
+		if err != nil {
+			m.logger.Debug("cannot check alert rule", zap.String("rule", rule.Name), zap.Error(err))
+			unknown[rule.Name] = true
+			continue
+		}
+		for _, c := range conditions {
+			key := rule.Name
+			if c.subject != "" {
+				key = fmt.Sprintf("%s/%s", rule.Name, c.subject)
+			}
+			active[key] = Alert{Key: key, Rule: rule.Name, Subject: c.subject, Message: c.message}
+		}
+	}
+
+	now := m.provider.Now()
+	device := m.device.GetDeviceDomain()
+	var changes []Alert
+	m.mutex.Lock()
+	for key, alert := range active {
+		if _, ok := m.firing[key]; ok {
+			continue
+		}
+		alert.State = StateFiring
+		alert.Since = now
+		alert.Device = device
+		m.firing[key] = alert
+		changes = append(changes, alert)
+	}
+	for key, alert := range m.firing {
+		if _, ok := active[key]; ok || unknown[alert.Rule] {
+			continue
+		}
+		delete(m.firing, key)
+		alert.State = StateResolved
+		alert.Since = now
+		changes = append(changes, alert)
+	}
+	if len(changes) > 0 {
+		m.save()
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	for _, alert := range changes {
+		m.logger.Info("alert", zap.String("key", alert.Key), zap.String("state", alert.State), zap.String("message", alert.Message))
+		m.notify(alert)
+	}
+	return nil
+}
+
+func (m *Monitor) notify(alert Alert) {
+	channels, err := m.config.Channels()
+	if err != nil {
+		m.logger.Warn("cannot read alert channels", zap.Error(err))
+		return
+	}
+	for _, channel := range channels {
+		if !channel.Enabled {
+			continue
+		}
+		err = m.sender.Send(channel, alert)
+		if err != nil {
+			m.logger.Warn("cannot send alert", zap.String("channel", channel.Name), zap.Error(err))
+		}
+	}
+}
+
+func (m *Monitor) check(rule config.AlertRule) ([]condition, error) {
+	switch rule.Type {
+	case RuleDiskUsage:
+		return m.checkDiskUsage(rule), nil
+	case RuleMemoryUsage:
+		return m.checkMemoryUsage(rule)
+	case RuleBtrfsErrors:
+		return m.checkBtrfsErrors()
+	case RuleCertificateExpiry:
+		return m.checkCertificateExpiry(rule), nil
+	case RuleStabilityEvent:
+		return m.checkStabilityEvent(rule)
+	}
+	return nil, fmt.Errorf("unknown rule type %s", rule.Type)
+}
+
+func (m *Monitor) checkDiskUsage(rule config.AlertRule) []condition {
+	var conditions []condition
+	for _, mount := range m.diskSpace.Status().Mounts {
+		if mount.TotalKB == 0 {
+			continue
+		}
+		used := 100 * float64(mount.TotalKB-mount.FreeKB) / float64(mount.TotalKB)
+		if used >= rule.Threshold {
+			conditions = append(conditions, condition{
+				subject: mount.Kind,
+				message: fmt.Sprintf("%s disk (%s) is %.0f%% full", mount.Kind, mount.Path, used),
+			})
+		}
+	}
+	return conditions
+}
+
+func (m *Monitor) checkMemoryUsage(rule config.AlertRule) ([]condition, error) {
+	snapshot, err := m.snapshots.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	memory := snapshot.Memory
+	if memory.TotalKB == 0 {
+		return nil, nil
+	}
+	used := 100 * float64(memory.TotalKB-memory.AvailableKB) / float64(memory.TotalKB)
+	if used < rule.Threshold {
+		return nil, nil
+	}
+	return []condition{{message: fmt.Sprintf("memory is %.0f%% used", used)}}, nil
+}
+
+func (m *Monitor) checkBtrfsErrors() ([]condition, error) {
+	stats, err := m.btrfs.DeviceErrors()
+	if err != nil {
+		return nil, err
+	}
+	var conditions []condition
+	for _, device := range stats.DeviceStats {
+		if !stats.HasErrors(device.Device) {
+			continue
+		}
+		conditions = append(conditions, condition{
+			subject: device.Device,
+			message: fmt.Sprintf("btrfs device %s has errors: write %s, read %s, flush %s, corruption %s, generation %s",
+				device.Device, device.WriteIoErrs, device.ReadIoErrs, device.FlushIoErrs, device.CorruptionErrs, device.GenerationErrs),
+		})
+	}
+	return conditions, nil
+}
+
+// checkCertificateExpiry only looks at real certificates, a self-signed one is regenerated by the certificate job.
+func (m *Monitor) checkCertificateExpiry(rule config.AlertRule) []condition {
+	info := m.certificate.ReadCertificateInfo()
+	if info.Subject == "" || !info.IsReal || float64(info.ValidForDays) > rule.Threshold {
+		return nil
+	}
+	return []condition{{
+		subject: info.Subject,
+		message: fmt.Sprintf("certificate for %s expires in %d days", info.Subject, info.ValidForDays),
+	}}
+}
+
+// checkStabilityEvent fires for every app with an event of the rule kind within the last threshold minutes.
+func (m *Monitor) checkStabilityEvent(rule config.AlertRule) ([]condition, error) {
+	events, err := m.events.Recent(1000)
+	if err != nil {
+		return nil, err
+	}
+	window := DefaultEventWindow
+	if rule.Threshold > 0 {
+		window = time.Duration(rule.Threshold * float64(time.Minute))
+	}
+	since := m.provider.Now().Add(-window)
+	latest := make(map[string]stability.Event)
+	for _, event := range events {
+		if string(event.Kind) != rule.Event || event.Time.Before(since) {
+			continue
+		}
+		subject := event.App
+		if subject == "" {
+			subject = event.Comm
+		}
+		if previous, ok := latest[subject]; ok && previous.Time.After(event.Time) {
+			continue
+		}
+		latest[subject] = event
+	}
+	var conditions []condition
+	for subject, event := range latest {
+		message := fmt.Sprintf("%s %s", event.Kind, subject)
+		if event.Message != "" {
+			message = fmt.Sprintf("%s: %s", message, event.Message)
+		}
+		conditions = append(conditions, condition{subject: subject, message: message})
+	}
+	return conditions, nil
+}
+
+// Firing returns the alerts which are firing now.
+func (m *Monitor) Firing() []Alert {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	alerts := make([]Alert, 0, len(m.firing))
+	for _, alert := range m.firing {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Key < alerts[j].Key })
+	return alerts
+}
+
+func (m *Monitor) Rules() ([]config.AlertRule, error) {
+	return m.config.Rules()
+}
+
+func (m *Monitor) AddRule(rule config.AlertRule) error {
+	err := ValidateRule(rule)
+	if err != nil {
+		return err
+	}
+	return m.config.AddRule(rule)
+}
+
+func (m *Monitor) RemoveRule(name string) error {
+	return m.config.RemoveRule(name)
+}
+
+func (m *Monitor) Channels() ([]config.AlertChannel, error) {
+	channels, err := m.config.Channels()
+	if err != nil {
+		return nil, err
+	}
+	for i := range channels {
+		channels[i].Token = ""
+	}
+	return channels, nil
+}
+
+func (m *Monitor) AddChannel(channel config.AlertChannel) error {
+	err := ValidateChannel(channel)
+	if err != nil {
+		return err
+	}
+	return m.config.AddChannel(channel)
+}
+
+func (m *Monitor) RemoveChannel(name string) error {
+	return m.config.RemoveChannel(name)
+}
+
+// TestChannel sends a test alert to a channel, also a disabled one, so it can be checked before enabling.
+func (m *Monitor) TestChannel(name string) error {
+	channels, err := m.config.Channels()
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if channel.Name != name {
+			continue
+		}
+		return m.sender.Send(channel, Alert{
+			Key:     "test",
+			Rule:    "test",
+			Message: "test notification",
+			State:   StateFiring,
+			Since:   m.provider.Now(),
+			Device:  m.device.GetDeviceDomain(),
+		})
+	}
+	return fmt.Errorf("channel %s is not found", name)
+}
+
+func ValidateRule(rule config.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch rule.Type {
+	case RuleDiskUsage, RuleMemoryUsage:
+		if rule.Threshold <= 0 || rule.Threshold > 100 {
+			return fmt.Errorf("threshold should be a percent between 0 and 100")
+		}
+	case RuleBtrfsErrors:
+	case RuleCertificateExpiry:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("threshold should be a number of days")
+		}
+	case RuleStabilityEvent:
+		if rule.Event == "" {
+			return fmt.Errorf("event is required")
+		}
+		if rule.Threshold < 0 {
+			return fmt.Errorf("threshold should be a number of minutes")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %s", rule.Type)
+	}
+	return nil
+}
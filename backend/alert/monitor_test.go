@@ -0,0 +1,263 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/cert"
+	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/health"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/storage/btrfs"
+	"github.com/syncloud/platform/storage/model"
+)
+
+type ConfigStub struct {
+	rules    []config.AlertRule
+	channels []config.AlertChannel
+}
+
+func (c *ConfigStub) Rules() ([]config.AlertRule, error) {
+	return c.rules, nil
+}
+
+func (c *ConfigStub) AddRule(rule config.AlertRule) error {
+	c.rules = append(c.rules, rule)
+	return nil
+}
+
+func (c *ConfigStub) RemoveRule(_ string) error {
+	return nil
+}
+
+func (c *ConfigStub) Channels() ([]config.AlertChannel, error) {
+	channels := make([]config.AlertChannel, len(c.channels))
+	copy(channels, c.channels)
+	return channels, nil
+}
+
+func (c *ConfigStub) AddChannel(channel config.AlertChannel) error {
+	c.channels = append(c.channels, channel)
+	return nil
+}
+
+func (c *ConfigStub) RemoveChannel(_ string) error {
+	return nil
+}
+
+type DeviceConfigStub struct {
+}
+
+func (d *DeviceConfigStub) GetDeviceDomain() string {
+	return "example.com"
+}
+
+type SnapshotsStub struct {
+	memory health.Memory
+}
+
+func (s *SnapshotsStub) Snapshot() (health.Snapshot, error) {
+	return health.Snapshot{Memory: s.memory}, nil
+}
+
+type DiskSpaceStub struct {
+	mounts []model.DiskSpaceMount
+}
+
+func (d *DiskSpaceStub) Status() model.DiskSpace {
+	return model.DiskSpace{Mounts: d.mounts}
+}
+
+type DeviceErrorsStub struct {
+	output string
+}
+
+func (d *DeviceErrorsStub) DeviceErrors() (*btrfs.DeviceStats, error) {
+	if d.output == "" {
+		return nil, errors.New("not btrfs")
+	}
+	var stats btrfs.DeviceStats
+	err := json.Unmarshal([]byte(d.output), &stats)
+	return &stats, err
+}
+
+type CertificateInfoStub struct {
+	info cert.Info
+}
+
+func (c *CertificateInfoStub) ReadCertificateInfo() *cert.Info {
+	return &c.info
+}
+
+type EventsStub struct {
+	events []stability.Event
+}
+
+func (e *EventsStub) Recent(_ int) ([]stability.Event, error) {
+	return e.events, nil
+}
+
+type SenderStub struct {
+	sent []string
+}
+
+func (s *SenderStub) Send(channel config.AlertChannel, alert Alert) error {
+	s.sent = append(s.sent, channel.Name+":"+alert.Key+":"+alert.State)
+	return nil
+}
+
+type ProviderStub struct {
+	now time.Time
+}
+
+func (p *ProviderStub) Now() time.Time {
+	return p.now
+}
+
+type fixture struct {
+	config      *ConfigStub
+	snapshots   *SnapshotsStub
+	diskSpace   *DiskSpaceStub
+	btrfs       *DeviceErrorsStub
+	certificate *CertificateInfoStub
+	events      *EventsStub
+	sender      *SenderStub
+	provider    *ProviderStub
+	file        string
+}
+
+func newFixture(t *testing.T, rules ...config.AlertRule) *fixture {
+	return &fixture{
+		config:      &ConfigStub{rules: rules, channels: []config.AlertChannel{{Name: "phone", Type: ChannelNtfy, Enabled: true}, {Name: "off", Type: ChannelNtfy}}},
+		snapshots:   &SnapshotsStub{memory: health.Memory{TotalKB: 1000, AvailableKB: 500}},
+		diskSpace:   &DiskSpaceStub{},
+		btrfs:       &DeviceErrorsStub{},
+		certificate: &CertificateInfoStub{},
+		events:      &EventsStub{},
+		sender:      &SenderStub{},
+		provider:    &ProviderStub{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		file:        path.Join(t.TempDir(), "alerts.json"),
+	}
+}
+
+func (f *fixture) monitor() *Monitor {
+	return NewMonitor(f.config, &DeviceConfigStub{}, f.snapshots, f.diskSpace, f.btrfs, f.certificate, f.events, f.sender, f.file, f.provider, log.Default())
+}
+
+func TestEvaluate_FiresOnceAndResolves(t *testing.T) {
+	f := newFixture(t, config.AlertRule{Name: "disk_full", Type: RuleDiskUsage, Threshold: 95, Enabled: true})
+	f.diskSpace.mounts = []model.DiskSpaceMount{{Kind: model.DiskSpaceData, Path: "/data", TotalKB: 100, FreeKB: 2}}
+	monitor := f.monitor()
+
+	require.NoError(t, monitor.Evaluate())
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, []string{"phone:disk_full/data:firing"}, f.sender.sent)
+	firing := monitor.Firing()
+	assert.Len(t, firing, 1)
+	assert.Equal(t, "data disk (/data) is 98% full", firing[0].Message)
+	assert.Equal(t, "example.com", firing[0].Device)
+
+	f.diskSpace.mounts[0].FreeKB = 50
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, []string{"phone:disk_full/data:firing", "phone:disk_full/data:resolved"}, f.sender.sent)
+	assert.Len(t, monitor.Firing(), 0)
+}
+
+func TestEvaluate_FiringSurvivesRestart(t *testing.T) {
+	f := newFixture(t, config.AlertRule{Name: "memory_full", Type: RuleMemoryUsage, Threshold: 40, Enabled: true})
+	require.NoError(t, f.monitor().Evaluate())
+
+	restarted := f.monitor()
+	require.NoError(t, restarted.Evaluate())
+	assert.Equal(t, []string{"phone:memory_full:firing"}, f.sender.sent)
+	assert.Len(t, restarted.Firing(), 1)
+}
+
+func TestEvaluate_CheckErrorKeepsFiring(t *testing.T) {
+	f := newFixture(t, config.AlertRule{Name: "btrfs_errors", Type: RuleBtrfsErrors, Enabled: true})
+	f.btrfs.output = `{"device-stats": [
+		{"device": "/dev/sda", "write_io_errs": "0", "read_io_errs": "0", "flush_io_errs": "0", "corruption_errs": "3", "generation_errs": "0"},
+		{"device": "/dev/sdb", "write_io_errs": "0", "read_io_errs": "0", "flush_io_errs": "0", "corruption_errs": "0", "generation_errs": "0"}]}`
+	monitor := f.monitor()
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, []string{"phone:btrfs_errors//dev/sda:firing"}, f.sender.sent)
+
+	f.btrfs.output = ""
+	require.NoError(t, monitor.Evaluate())
+	assert.Len(t, f.sender.sent, 1)
+	assert.Len(t, monitor.Firing(), 1)
+}
+
+func TestEvaluate_DisabledRuleResolves(t *testing.T) {
+	f := newFixture(t, config.AlertRule{Name: "memory_full", Type: RuleMemoryUsage, Threshold: 40, Enabled: true})
+	monitor := f.monitor()
+	require.NoError(t, monitor.Evaluate())
+	f.config.rules[0].Enabled = false
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, []string{"phone:memory_full:firing", "phone:memory_full:resolved"}, f.sender.sent)
+}
+
+func TestEvaluate_CertificateExpiry(t *testing.T) {
+	f := newFixture(t, config.AlertRule{Name: "certificate_expiry", Type: RuleCertificateExpiry, Threshold: 5, Enabled: true})
+	f.certificate.info = cert.Info{Subject: "example.com", IsReal: true, IsValid: true, ValidForDays: 6}
+	monitor := f.monitor()
+	require.NoError(t, monitor.Evaluate())
+	assert.Len(t, f.sender.sent, 0)
+
+	f.certificate.info.ValidForDays = 5
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, []string{"phone:certificate_expiry/example.com:firing"}, f.sender.sent)
+
+	f.certificate.info = cert.Info{Subject: "example.com", IsValid: true, ValidForDays: 1}
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, "phone:certificate_expiry/example.com:resolved", f.sender.sent[1])
+}
+
+func TestEvaluate_StabilityEvent(t *testing.T) {
+	f := newFixture(t, config.AlertRule{Name: "app_killed", Type: RuleStabilityEvent, Event: string(stability.EventKindVictimSigkill), Threshold: 60, Enabled: true})
+	now := f.provider.now
+	f.events.events = []stability.Event{
+		{Time: now.Add(-2 * time.Hour), Kind: stability.EventKindVictimSigkill, App: "old"},
+		{Time: now.Add(-10 * time.Minute), Kind: stability.EventKindVictimSigterm, App: "nextcloud"},
+		{Time: now.Add(-5 * time.Minute), Kind: stability.EventKindVictimSigkill, App: "nextcloud", Message: "rss 900MB"},
+	}
+	monitor := f.monitor()
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, []string{"phone:app_killed/nextcloud:firing"}, f.sender.sent)
+	assert.Equal(t, "victim_sigkill nextcloud: rss 900MB", monitor.Firing()[0].Message)
+
+	f.provider.now = now.Add(time.Hour)
+	require.NoError(t, monitor.Evaluate())
+	assert.Equal(t, "phone:app_killed/nextcloud:resolved", f.sender.sent[1])
+}
+
+func TestChannels_HidesToken(t *testing.T) {
+	f := newFixture(t)
+	f.config.channels = []config.AlertChannel{{Name: "gotify", Type: ChannelGotify, Url: "https://gotify.example.com", Token: "secret"}}
+	channels, err := f.monitor().Channels()
+	require.NoError(t, err)
+	assert.Equal(t, "", channels[0].Token)
+	assert.Equal(t, "secret", f.config.channels[0].Token)
+}
+
+func TestTestChannel(t *testing.T) {
+	f := newFixture(t)
+	monitor := f.monitor()
+	require.NoError(t, monitor.TestChannel("off"))
+	assert.Equal(t, []string{"off:test:firing"}, f.sender.sent)
+	assert.Error(t, monitor.TestChannel("unknown"))
+}
+
+func TestValidateRule(t *testing.T) {
+	assert.Nil(t, ValidateRule(config.AlertRule{Name: "disk", Type: RuleDiskUsage, Threshold: 90}))
+	assert.NotNil(t, ValidateRule(config.AlertRule{Name: "disk", Type: RuleDiskUsage, Threshold: 190}))
+	assert.NotNil(t, ValidateRule(config.AlertRule{Type: RuleBtrfsErrors}))
+	assert.NotNil(t, ValidateRule(config.AlertRule{Name: "event", Type: RuleStabilityEvent}))
+	assert.NotNil(t, ValidateRule(config.AlertRule{Name: "unknown", Type: "unknown"}))
+}
@@ -0,0 +1,139 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syncloud/platform/config"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelNtfy    = "ntfy"
+	ChannelGotify  = "gotify"
+)
+
+// Notifier sends an alert to a channel by the channel type.
+type Notifier struct {
+	email  Sender
+	client *http.Client
+}
+
+func NewNotifier(email Sender) *Notifier {
+	return &Notifier{
+		email:  email,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (n *Notifier) Send(channel config.AlertChannel, alert Alert) error {
+	switch channel.Type {
+	case ChannelEmail:
+		return n.email.Send(channel, alert)
+	case ChannelWebhook:
+		return n.webhook(channel, alert)
+	case ChannelNtfy:
+		return n.ntfy(channel, alert)
+	case ChannelGotify:
+		return n.gotify(channel, alert)
+	}
+	return fmt.Errorf("unknown channel type %s", channel.Type)
+}
+
+// webhook posts the alert as json.
+func (n *Notifier) webhook(channel config.AlertChannel, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, channel.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if channel.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+channel.Token)
+	}
+	return n.do(req)
+}
+
+// ntfy publishes the message to the topic url.
+func (n *Notifier) ntfy(channel config.AlertChannel, alert Alert) error {
+	req, err := http.NewRequest(http.MethodPost, channel.Url, strings.NewReader(alert.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", alert.Title())
+	if alert.State == StateFiring {
+		req.Header.Set("Priority", "high")
+		req.Header.Set("Tags", "warning")
+	} else {
+		req.Header.Set("Priority", "default")
+		req.Header.Set("Tags", "white_check_mark")
+	}
+	if channel.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+channel.Token)
+	}
+	return n.do(req)
+}
+
+// gotify sends the message to the server url with an application token.
+func (n *Notifier) gotify(channel config.AlertChannel, alert Alert) error {
+	priority := 4
+	if alert.State == StateFiring {
+		priority = 8
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    alert.Title(),
+		"message":  alert.Message,
+		"priority": priority,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(channel.Url, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", channel.Token)
+	return n.do(req)
+}
+
+func (n *Notifier) do(req *http.Request) error {
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+func ValidateChannel(channel config.AlertChannel) error {
+	if channel.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch channel.Type {
+	case ChannelEmail:
+		return nil
+	case ChannelWebhook, ChannelNtfy, ChannelGotify:
+		parsed, err := url.Parse(channel.Url)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("url should be an http or https url")
+		}
+		if channel.Type == ChannelGotify && channel.Token == "" {
+			return fmt.Errorf("token is required")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown channel type %s", channel.Type)
+}
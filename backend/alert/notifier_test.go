@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/config"
+)
+
+type request struct {
+	path    string
+	headers http.Header
+	body    string
+}
+
+func server(t *testing.T, status int) (*httptest.Server, *[]request) {
+	var requests []request
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{path: r.URL.Path, headers: r.Header, body: string(body)})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+var testAlert = Alert{Key: "disk_full/data", Rule: "disk_full", Subject: "data", Message: "data disk is 98% full", State: StateFiring, Device: "example.com"}
+
+func TestNotifier_Webhook(t *testing.T) {
+	s, requests := server(t, http.StatusOK)
+	notifier := NewNotifier(&SenderStub{})
+
+	err := notifier.Send(config.AlertChannel{Type: ChannelWebhook, Url: s.URL + "/hook", Token: "secret"}, testAlert)
+	require.NoError(t, err)
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "/hook", (*requests)[0].path)
+	assert.Equal(t, "Bearer secret", (*requests)[0].headers.Get("Authorization"))
+	var alert Alert
+	require.NoError(t, json.Unmarshal([]byte((*requests)[0].body), &alert))
+	assert.Equal(t, testAlert.Key, alert.Key)
+}
+
+func TestNotifier_Ntfy(t *testing.T) {
+	s, requests := server(t, http.StatusOK)
+	notifier := NewNotifier(&SenderStub{})
+
+	err := notifier.Send(config.AlertChannel{Type: ChannelNtfy, Url: s.URL + "/syncloud"}, testAlert)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/syncloud", (*requests)[0].path)
+	assert.Equal(t, "disk_full firing on example.com", (*requests)[0].headers.Get("Title"))
+	assert.Equal(t, "high", (*requests)[0].headers.Get("Priority"))
+	assert.Equal(t, "", (*requests)[0].headers.Get("Authorization"))
+	assert.Equal(t, testAlert.Message, (*requests)[0].body)
+}
+
+func TestNotifier_Gotify(t *testing.T) {
+	s, requests := server(t, http.StatusOK)
+	notifier := NewNotifier(&SenderStub{})
+
+	resolved := testAlert
+	resolved.State = StateResolved
+	err := notifier.Send(config.AlertChannel{Type: ChannelGotify, Url: s.URL + "/", Token: "app"}, resolved)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/message", (*requests)[0].path)
+	assert.Equal(t, "app", (*requests)[0].headers.Get("X-Gotify-Key"))
+	assert.True(t, strings.Contains((*requests)[0].body, `"priority":4`))
+}
+
+func TestNotifier_Error(t *testing.T) {
+	s, _ := server(t, http.StatusForbidden)
+	notifier := NewNotifier(&SenderStub{})
+
+	err := notifier.Send(config.AlertChannel{Type: ChannelWebhook, Url: s.URL}, testAlert)
+	assert.Error(t, err)
+}
+
+func TestNotifier_Email(t *testing.T) {
+	email := &SenderStub{}
+	notifier := NewNotifier(email)
+
+	err := notifier.Send(config.AlertChannel{Name: "admin", Type: ChannelEmail}, testAlert)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin:disk_full/data:firing"}, email.sent)
+}
+
+func TestValidateChannel(t *testing.T) {
+	assert.Nil(t, ValidateChannel(config.AlertChannel{Name: "admin", Type: ChannelEmail}))
+	assert.Nil(t, ValidateChannel(config.AlertChannel{Name: "phone", Type: ChannelNtfy, Url: "https://ntfy.sh/topic"}))
+	assert.NotNil(t, ValidateChannel(config.AlertChannel{Name: "phone", Type: ChannelNtfy, Url: "ntfy.sh/topic"}))
+	assert.NotNil(t, ValidateChannel(config.AlertChannel{Name: "gotify", Type: ChannelGotify, Url: "https://gotify.example.com"}))
+	assert.NotNil(t, ValidateChannel(config.AlertChannel{Type: ChannelEmail}))
+	assert.NotNil(t, ValidateChannel(config.AlertChannel{Name: "sms", Type: "sms"}))
+}
+
+func TestMessage(t *testing.T) {
+	text := string(message("alerts@example.com", "user@example.com", "subject", "body", testAlert.Since))
+	assert.Contains(t, text, "Subject: subject\r\n")
+	assert.True(t, strings.HasSuffix(text, "\r\n\r\nbody\r\n"))
+}
@@ -53,6 +53,7 @@ type Authelia struct {
 	secretFile     string
 	jwksKeyFile    string
 	hmacSecretFile string
+	storageFile    string
 	socketPath     string
 	userConfig     UserConfig
 	oidc           OIDC
@@ -85,10 +86,11 @@ type PasswordGenerator interface {
 }
 
 const (
-	KeyFile    = "authelia.storage.encryption.key"
-	SecretFile = "authelia.jwt.secret"
-	JwksKey    = "authelia.jwks.key"
-	HmacSecret = "authelia.hmac_secret.key"
+	KeyFile     = "authelia.storage.encryption.key"
+	SecretFile  = "authelia.jwt.secret"
+	JwksKey     = "authelia.jwks.key"
+	HmacSecret  = "authelia.hmac_secret.key"
+	StorageFile = "authelia.sqlite3"
 )
 
 func NewAuthelia(
@@ -112,6 +114,7 @@ func NewAuthelia(
 		secretFile:     path.Join(outSecretDir, SecretFile),
 		jwksKeyFile:    path.Join(outSecretDir, JwksKey),
 		hmacSecretFile: path.Join(outSecretDir, HmacSecret),
+		storageFile:    path.Join(outSecretDir, StorageFile),
 		socketPath:     socketPath,
 		userConfig:     userConfig,
 		oidc:           oidc,
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+func (w *Authelia) secretFiles() []string {
+	return []string{w.keyFile, w.secretFile, w.jwksKeyFile, w.hmacSecretFile}
+}
+
+// Export copies the secrets and a snapshot of the storage (TOTP, consents) into dir.
+func (w *Authelia) Export(dir string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, file := range w.secretFiles() {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(path.Join(dir, filepath.Base(file)), data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := os.Stat(w.storageFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	db, err := sql.Open("sqlite", autheliaDSN(w.storageFile))
+	if err != nil {
+		return fmt.Errorf("failed to open authelia db: %w", err)
+	}
+	defer db.Close()
+	_, err = db.Exec("VACUUM INTO ?", path.Join(dir, StorageFile))
+	return err
+}
+
+// Import puts exported secrets and storage in place and restarts authelia with them.
+func (w *Authelia) Import(dir string) error {
+	err := w.importFiles(dir)
+	if err != nil {
+		return err
+	}
+	err = w.InitConfig()
+	if err != nil {
+		return err
+	}
+	return w.WaitForReady()
+}
+
+func (w *Authelia) importFiles(dir string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, file := range append(w.secretFiles(), w.storageFile) {
+		data, err := os.ReadFile(path.Join(dir, filepath.Base(file)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		tmp := file + ".restore"
+		err = os.WriteFile(tmp, data, 0644)
+		if err != nil {
+			return err
+		}
+		for _, suffix := range []string{"-wal", "-shm"} {
+			err = os.Remove(file + suffix)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		err = os.Rename(tmp, file)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"database/sql"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+)
+
+func TestAutheliaExportImport(t *testing.T) {
+	userConfig := &UserConfigStub{domain: "example.com", activated: true}
+	secretDir := t.TempDir()
+	err := os.WriteFile(path.Join(secretDir, KeyFile), []byte("key"), 0644)
+	assert.NoError(t, err)
+	db, err := sql.Open("sqlite", autheliaDSN(path.Join(secretDir, StorageFile)))
+	assert.NoError(t, err)
+	_, err = db.Exec("create table totp_configurations (username varchar)")
+	assert.NoError(t, err)
+	_, err = db.Exec("insert into totp_configurations values ('user')")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	source := NewAuthelia("../../config/authelia", t.TempDir(), secretDir, "/tmp/authelia.socket", userConfig, &OIDCStub{}, &SystemdStub{}, &PasswordGeneratorStub{}, &ExecutorStub{}, &AutheliaHealthStub{}, log.Default())
+	exportDir := t.TempDir()
+	err = source.Export(exportDir)
+	assert.NoError(t, err)
+
+	targetDir := t.TempDir()
+	err = os.WriteFile(path.Join(targetDir, KeyFile), []byte("new"), 0644)
+	assert.NoError(t, err)
+	target := NewAuthelia("../../config/authelia", t.TempDir(), targetDir, "/tmp/authelia.socket", userConfig, &OIDCStub{}, &SystemdStub{}, &PasswordGeneratorStub{}, &ExecutorStub{}, &AutheliaHealthStub{}, log.Default())
+	err = target.Import(exportDir)
+	assert.NoError(t, err)
+
+	key, err := os.ReadFile(path.Join(targetDir, KeyFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "key", string(key))
+	totp := NewTOTP(&ExecutorStub{}, targetDir, log.Default())
+	has, err := totp.Has("user")
+	assert.NoError(t, err)
+	assert.True(t, has)
+}
@@ -119,61 +119,75 @@ func (i *Initializer) applyConfigOnce(uri string) error {
 func (i *Initializer) Reset(name string, user string, password string, email string) error {
 	log.Println("resetting ldap")
 
-	err := i.snapService.Stop("platform.openldap")
+	err := i.recreate()
 	if err != nil {
 		return err
 	}
-	err = os.RemoveAll(i.userConfDir)
+
+	passwordHash := i.passwordHasher.Hash(password)
+
+	tmpFile, err := os.CreateTemp("", "")
 	if err != nil {
 		return err
 	}
-
-	err = os.RemoveAll(i.userDataDir)
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	file, err := os.ReadFile(path.Join(i.configDir, "ldap", "init.ldif"))
 	if err != nil {
 		return err
 	}
-	err = os.MkdirAll(i.userDataDir, 755)
+	ldif := string(file)
+	ldif = strings.ReplaceAll(ldif, "${name}", name)
+	ldif = strings.ReplaceAll(ldif, "${user}", user)
+	ldif = strings.ReplaceAll(ldif, "${email}", email)
+	ldif = strings.ReplaceAll(ldif, "${password}", passwordHash)
+	err = os.WriteFile(tmpFile.Name(), []byte(ldif), 644)
 	if err != nil {
 		return err
 	}
 
-	err = i.Init()
+	err = i.initDb(tmpFile.Name())
 	if err != nil {
 		return err
 	}
-	err = i.snapService.Start("platform.openldap")
+
+	err = i.passwordChanger.Change(password)
+	return err
+}
+
+// Import replaces the directory with the entries of an LDIF export.
+func (i *Initializer) Import(filename string) error {
+	log.Println("importing ldap")
+	err := i.recreate()
 	if err != nil {
 		return err
 	}
+	return i.initDb(filename)
+}
 
-	passwordHash := i.passwordHasher.Hash(password)
-
-	tmpFile, err := os.CreateTemp("", "")
+func (i *Initializer) recreate() error {
+	err := i.snapService.Stop("platform.openldap")
 	if err != nil {
 		return err
 	}
-	defer func() { _ = os.Remove(tmpFile.Name()) }()
-	file, err := os.ReadFile(path.Join(i.configDir, "ldap", "init.ldif"))
+	err = os.RemoveAll(i.userConfDir)
 	if err != nil {
 		return err
 	}
-	ldif := string(file)
-	ldif = strings.ReplaceAll(ldif, "${name}", name)
-	ldif = strings.ReplaceAll(ldif, "${user}", user)
-	ldif = strings.ReplaceAll(ldif, "${email}", email)
-	ldif = strings.ReplaceAll(ldif, "${password}", passwordHash)
-	err = os.WriteFile(tmpFile.Name(), []byte(ldif), 644)
+
+	err = os.RemoveAll(i.userDataDir)
 	if err != nil {
 		return err
 	}
-
-	err = i.initDb(tmpFile.Name())
+	err = os.MkdirAll(i.userDataDir, 755)
 	if err != nil {
 		return err
 	}
 
-	err = i.passwordChanger.Change(password)
-	return err
+	err = i.Init()
+	if err != nil {
+		return err
+	}
+	return i.snapService.Start("platform.openldap")
 }
 
 func (i *Initializer) initDb(filename string) error {
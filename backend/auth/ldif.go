@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Export writes the whole directory as LDIF which can be loaded back with Import.
+func (c *LdapClient) Export(file string) error {
+	conn, err := c.Connect()
+	if err != nil {
+		return err
+	}
+	defer c.Disconnect(conn)
+	request := ldap.NewSearchRequest(
+		Domain,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)",
+		[]string{"*"},
+		nil,
+	)
+	result, err := conn.SearchWithPaging(request, 500)
+	if err != nil {
+		return fmt.Errorf("ldap export: %w", err)
+	}
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = WriteLdif(out, result.Entries)
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// WriteLdif writes entries with parents before children so ldapadd can create them in order.
+func WriteLdif(w io.Writer, entries []*ldap.Entry) error {
+	sorted := make([]*ldap.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dnDepth(sorted[i].DN) < dnDepth(sorted[j].DN)
+	})
+	writer := bufio.NewWriter(w)
+	for _, entry := range sorted {
+		writeLdifValue(writer, "dn", []byte(entry.DN))
+		for _, attribute := range entry.Attributes {
+			for _, value := range attribute.ByteValues {
+				writeLdifValue(writer, attribute.Name, value)
+			}
+		}
+		_, _ = writer.WriteString("\n")
+	}
+	return writer.Flush()
+}
+
+func dnDepth(dn string) int {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.Count(dn, ",")
+	}
+	return len(parsed.RDNs)
+}
+
+func writeLdifValue(w *bufio.Writer, name string, value []byte) {
+	if isLdifSafe(value) {
+		_, _ = fmt.Fprintf(w, "%s: %s\n", name, value)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s:: %s\n", name, base64.StdEncoding.EncodeToString(value))
+}
+
+// isLdifSafe follows SAFE-STRING of RFC 2849, other values are base64 encoded.
+func isLdifSafe(value []byte) bool {
+	if len(value) == 0 {
+		return true
+	}
+	switch value[0] {
+	case ' ', ':', '<':
+		return false
+	}
+	if value[len(value)-1] == ' ' {
+		return false
+	}
+	for _, b := range value {
+		if b == 0 || b == '\n' || b == '\r' || b >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteLdif(t *testing.T) {
+	entries := []*ldap.Entry{
+		ldap.NewEntry("cn=user,ou=users,dc=syncloud,dc=org", map[string][]string{
+			"cn":          {"user"},
+			"description": {"Zoë"},
+		}),
+		ldap.NewEntry("ou=users,dc=syncloud,dc=org", map[string][]string{"ou": {"users"}}),
+		ldap.NewEntry("dc=syncloud,dc=org", map[string][]string{"dc": {"syncloud"}}),
+	}
+	buf := &bytes.Buffer{}
+	err := WriteLdif(buf, entries)
+	assert.NoError(t, err)
+	assert.Equal(t, `dn: dc=syncloud,dc=org
+dc: syncloud
+
+dn: ou=users,dc=syncloud,dc=org
+ou: users
+
+dn: cn=user,ou=users,dc=syncloud,dc=org
+cn: user
+description:: Wm/Dqw==
+
+`, buf.String())
+}
+
+func TestIsLdifSafe(t *testing.T) {
+	assert.True(t, isLdifSafe([]byte("{SSHA}abc")))
+	assert.False(t, isLdifSafe([]byte(" leading")))
+	assert.False(t, isLdifSafe([]byte(":colon")))
+	assert.False(t, isLdifSafe([]byte("line\nbreak")))
+	assert.False(t, isLdifSafe([]byte("trailing ")))
+}
@@ -28,7 +28,7 @@ func NewTOTP(executor cli.Executor, dataDir string, logger *zap.Logger) *TOTP {
 }
 
 func (t *TOTP) sqlitePath() string {
-	return path.Join(t.dataDir, "authelia.sqlite3")
+	return path.Join(t.dataDir, StorageFile)
 }
 
 func (t *TOTP) Generate(username string) (string, error) {
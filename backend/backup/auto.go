@@ -1,7 +1,44 @@
 package backup
 
+import (
+	"fmt"
+
+	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/cron/expression"
+)
+
+const (
+	AutoNo      = "no"
+	AutoBackup  = "backup"
+	AutoRestore = "restore"
+)
+
 type Auto struct {
-	Auto string `json:"auto"`
-	Day  int    `json:"day"`
-	Hour int    `json:"hour"`
+	Auto string                          `json:"auto"`
+	Day  int                             `json:"day"`
+	Hour int                             `json:"hour"`
+	Apps map[string]config.BackupAppAuto `json:"apps,omitempty"`
+}
+
+type AppAuto struct {
+	App string `json:"app"`
+	config.BackupAppAuto
+}
+
+func ValidateAppAuto(auto AppAuto) error {
+	if auto.App == "" {
+		return fmt.Errorf("app is missing")
+	}
+	switch auto.Mode {
+	case "", AutoBackup, AutoRestore:
+	default:
+		return fmt.Errorf("mode should be %s or %s: %s", AutoBackup, AutoRestore, auto.Mode)
+	}
+	if auto.Schedule != "" {
+		_, err := expression.Parse(auto.Schedule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
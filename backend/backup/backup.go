@@ -3,10 +3,13 @@ package backup
 import (
 	"archive/tar"
 	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
 	cp "github.com/otiai10/copy"
 	df "github.com/ricochet2200/go-disk-usage/du"
 	"github.com/syncloud/platform/cli"
+	"github.com/syncloud/platform/config"
 	"github.com/syncloud/platform/date"
 	"github.com/syncloud/platform/du"
 	"github.com/syncloud/platform/snap/model"
@@ -15,14 +18,17 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 )
 
 type SnapService interface {
 	Stop(name string) error
 	Start(name string) error
-	RunCmdIfExists(snap model.Snap, cmd string) error
+	RunCmdIfExists(snap model.Snap, cmd string, args ...string) error
 }
 
 type SnapInfo interface {
@@ -36,6 +42,29 @@ type UserConfig interface {
 	SetBackupAutoDay(day int)
 	GetBackupAutoHour() int
 	SetBackupAutoHour(hour int)
+	IsBackupIncremental() bool
+	SetBackupIncremental(enabled bool)
+	IsBackupStreaming() bool
+	SetBackupStreaming(enabled bool)
+	GetBackupPassphrase() string
+	SetBackupPassphrase(passphrase string)
+	GetBackupRecipients() []string
+	SetBackupRecipients(recipients []string)
+	GetBackupIdentities() []string
+	SetBackupIdentities(identities []string)
+	GetBackupRetention(app string) (config.BackupRetention, bool)
+	SetBackupRetention(app string, retention config.BackupRetention)
+	RemoveBackupRetention(app string)
+	ListBackupRetentionApps() []string
+	GetBackupAppAuto(app string) (config.BackupAppAuto, bool)
+	SetBackupAppAuto(app string, auto config.BackupAppAuto)
+	RemoveBackupAppAuto(app string)
+	ListBackupAutoApps() []string
+}
+
+type PlatformState interface {
+	Export(dir string) error
+	Import(dir string) error
 }
 
 type Backup struct {
@@ -46,12 +75,17 @@ type Backup struct {
 	snapServer   SnapInfo
 	diskusage    du.DiskUsage
 	userConfig   UserConfig
+	targets      TargetConfig
+	platform     PlatformState
 	timeProvider date.Provider
+	progress     Progress
+	chunks       *ChunkStore
 	logger       *zap.Logger
 }
 
 const (
 	Dir              = "/data/platform/backup"
+	ArchiveExt       = ".tar.gz"
 	VarDir           = "/var/snap"
 	CreatePreStop    = "backup-pre-stop"
 	CreatePostStop   = "backup-post-stop"
@@ -59,6 +93,9 @@ const (
 	RestorePostStop  = "restore-post-stop"
 	RestorePreStart  = "restore-pre-start"
 	RestorePostStart = "restore-post-start"
+	RestoreMigrate   = "restore-migrate"
+	// restoreMargin is the part of the payload size added for file system overhead when restoring.
+	restoreMargin = 10
 )
 
 func New(dir string,
@@ -68,7 +105,10 @@ func New(dir string,
 	snapCli SnapService,
 	snapServer SnapInfo,
 	userConfig UserConfig,
+	targets TargetConfig,
+	platform PlatformState,
 	timeProvider date.Provider,
+	progress Progress,
 	logger *zap.Logger) *Backup {
 	return &Backup{
 		backupDir:    dir,
@@ -78,7 +118,11 @@ func New(dir string,
 		snapCli:      snapCli,
 		snapServer:   snapServer,
 		userConfig:   userConfig,
+		targets:      targets,
+		platform:     platform,
 		timeProvider: timeProvider,
+		progress:     progress,
+		chunks:       NewChunkStore(filepath.Join(dir, ChunksDir)),
 		logger:       logger,
 	}
 }
@@ -95,11 +139,18 @@ func (b *Backup) Start() error {
 }
 
 func (b *Backup) Auto() Auto {
-	return Auto{
+	auto := Auto{
 		Auto: b.userConfig.GetBackupAuto(),
 		Day:  b.userConfig.GetBackupAutoDay(),
 		Hour: b.userConfig.GetBackupAutoHour(),
+		Apps: make(map[string]config.BackupAppAuto),
+	}
+	for _, app := range b.userConfig.ListBackupAutoApps() {
+		if appAuto, ok := b.userConfig.GetBackupAppAuto(app); ok {
+			auto.Apps[app] = appAuto
+		}
 	}
+	return auto
 }
 
 func (b *Backup) SetAuto(auto Auto) {
@@ -108,6 +159,210 @@ func (b *Backup) SetAuto(auto Auto) {
 	b.userConfig.SetBackupAutoHour(auto.Hour)
 }
 
+// SetAppAuto overrides the global auto settings for an app.
+func (b *Backup) SetAppAuto(auto AppAuto) error {
+	err := ValidateAppAuto(auto)
+	if err != nil {
+		return err
+	}
+	b.userConfig.SetBackupAppAuto(auto.App, auto.BackupAppAuto)
+	return nil
+}
+
+func (b *Backup) RemoveAppAuto(app string) {
+	b.userConfig.RemoveBackupAppAuto(app)
+}
+
+func (b *Backup) encryption() Encryption {
+	return Encryption{
+		Passphrase: b.userConfig.GetBackupPassphrase(),
+		Recipients: b.userConfig.GetBackupRecipients(),
+		Identities: b.userConfig.GetBackupIdentities(),
+	}
+}
+
+// decryption falls back to the configured encryption when no keys are given.
+func (b *Backup) decryption(keys Encryption) Encryption {
+	if keys.Passphrase == "" && len(keys.Identities) == 0 {
+		return b.encryption()
+	}
+	return keys
+}
+
+func (b *Backup) Encryption() EncryptionInfo {
+	return b.encryption().Info()
+}
+
+func (b *Backup) SetEncryption(encryption Encryption) error {
+	err := encryption.Validate()
+	if err != nil {
+		return err
+	}
+	if encryption.Enabled() && b.userConfig.IsBackupIncremental() {
+		return ErrIncrementalEncrypted
+	}
+	b.userConfig.SetBackupPassphrase(encryption.Passphrase)
+	b.userConfig.SetBackupRecipients(encryption.Recipients)
+	b.userConfig.SetBackupIdentities(encryption.Identities)
+	return nil
+}
+
+func (b *Backup) Retention() RetentionConfig {
+	global, _ := b.userConfig.GetBackupRetention("")
+	retention := RetentionConfig{Global: global, Apps: make(map[string]config.BackupRetention)}
+	for _, app := range b.userConfig.ListBackupRetentionApps() {
+		if appRetention, ok := b.userConfig.GetBackupRetention(app); ok {
+			retention.Apps[app] = appRetention
+		}
+	}
+	return retention
+}
+
+// SetRetention sets the policy of an app, an empty app sets the global policy.
+func (b *Backup) SetRetention(retention AppRetention) error {
+	err := ValidateRetention(retention.BackupRetention)
+	if err != nil {
+		return err
+	}
+	b.userConfig.SetBackupRetention(retention.App, retention.BackupRetention)
+	return nil
+}
+
+func (b *Backup) RemoveRetention(app string) {
+	b.userConfig.RemoveBackupRetention(app)
+}
+
+func (b *Backup) appRetention(app string) config.BackupRetention {
+	retention, ok := b.userConfig.GetBackupRetention(app)
+	if ok {
+		return retention
+	}
+	retention, _ = b.userConfig.GetBackupRetention("")
+	return retention
+}
+
+// PlanRetention previews which local backups the retention policy keeps and removes, an empty app covers all apps.
+// Copies on remote targets are not affected by the policy.
+func (b *Backup) PlanRetention(app string) (RetentionPlan, error) {
+	plan := RetentionPlan{Keep: []File{}, Remove: []File{}}
+	files, err := b.List()
+	if err != nil {
+		return plan, err
+	}
+	byApp := make(map[string][]File)
+	var apps []string
+	for _, file := range files {
+		if file.Target != "" || app != "" && file.App != app {
+			continue
+		}
+		if _, ok := byApp[file.App]; !ok {
+			apps = append(apps, file.App)
+		}
+		byApp[file.App] = append(byApp[file.App], file)
+	}
+	sort.Strings(apps)
+	for _, name := range apps {
+		appPlan := PlanRetention(b.appRetention(name), byApp[name])
+		plan.Keep = append(plan.Keep, appPlan.Keep...)
+		plan.Remove = append(plan.Remove, appPlan.Remove...)
+	}
+	return plan, nil
+}
+
+func (b *Backup) ApplyRetention(app string) (RetentionPlan, error) {
+	plan, err := b.PlanRetention(app)
+	if err != nil {
+		return plan, err
+	}
+	var errs []error
+	for _, file := range plan.Remove {
+		b.logger.Info("Removing backup by retention policy", zap.String("file", file.File))
+		err = b.Remove(file.File, false)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return plan, errors.Join(errs...)
+}
+
+// Targets returns configured remote targets without their secrets.
+func (b *Backup) Targets() ([]config.BackupTarget, error) {
+	targets, err := b.targets.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range targets {
+		targets[i].Password = ""
+	}
+	return targets, nil
+}
+
+func (b *Backup) AddTarget(target config.BackupTarget) error {
+	err := ValidateTarget(target)
+	if err != nil {
+		return err
+	}
+	if b.userConfig.IsBackupIncremental() {
+		return ErrIncrementalRemote
+	}
+	return b.targets.Add(target)
+}
+
+func (b *Backup) RemoveTarget(name string) error {
+	return b.targets.Remove(name)
+}
+
+func (b *Backup) Mode() Mode {
+	return Mode{
+		Incremental: b.userConfig.IsBackupIncremental(),
+		Streaming:   b.userConfig.IsBackupStreaming(),
+	}
+}
+
+// SetMode changes how app backups are written, incremental backups are kept in the local chunk store
+// unencrypted so they are rejected while encryption or remote targets are configured.
+func (b *Backup) SetMode(mode Mode) error {
+	err := b.validateMode(mode)
+	if err != nil {
+		return err
+	}
+	b.userConfig.SetBackupIncremental(mode.Incremental)
+	b.userConfig.SetBackupStreaming(mode.Streaming)
+	return nil
+}
+
+// ValidateMode rejects backup settings which cannot be honored when creating a backup of an app.
+func (b *Backup) ValidateMode(app string) error {
+	if app == PlatformApp {
+		return nil
+	}
+	return b.validateMode(b.Mode())
+}
+
+func (b *Backup) validateMode(mode Mode) error {
+	if !mode.Incremental {
+		return nil
+	}
+	if b.encryption().Enabled() {
+		return ErrIncrementalEncrypted
+	}
+	targets, err := b.targets.List()
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		return ErrIncrementalRemote
+	}
+	return nil
+}
+
+func (b *Backup) archiveExt() string {
+	if b.encryption().Enabled() {
+		return ArchiveExt + EncryptedExt
+	}
+	return ArchiveExt
+}
+
 func (b *Backup) List() ([]File, error) {
 	files, err := os.ReadDir(b.backupDir)
 	if err != nil {
@@ -116,6 +371,9 @@ func (b *Backup) List() ([]File, error) {
 	}
 	var names []File
 	for _, x := range files {
+		if x.IsDir() || strings.HasPrefix(x.Name(), ".") {
+			continue
+		}
 		file, err := Parse(b.backupDir, x.Name())
 		if err != nil {
 			b.logger.Error("Cannot parse file name", zap.String("file", x.Name()), zap.Error(err))
@@ -124,12 +382,109 @@ func (b *Backup) List() ([]File, error) {
 		}
 	}
 
-	return names, nil
+	return append(names, b.listRemote(names)...), nil
+}
+
+func (b *Backup) listRemote(local []File) []File {
+	seen := make(map[string]bool)
+	for _, file := range local {
+		seen[file.File] = true
+	}
+	var files []File
+	for _, target := range b.remoteTargets() {
+		remote, err := target.List()
+		if err != nil {
+			b.logger.Warn("Cannot list backup target", zap.String("target", target.Name()), zap.Error(err))
+			continue
+		}
+		for _, name := range remote {
+			if seen[name] {
+				continue
+			}
+			file, err := Parse(b.backupDir, name)
+			if err != nil {
+				b.logger.Error("Cannot parse file name", zap.String("target", target.Name()), zap.String("file", name), zap.Error(err))
+				continue
+			}
+			seen[name] = true
+			file.Target = target.Name()
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (b *Backup) remoteTargets() []Target {
+	configs, err := b.targets.List()
+	if err != nil {
+		b.logger.Error("Cannot get backup targets", zap.Error(err))
+		return nil
+	}
+	var targets []Target
+	for _, targetConfig := range configs {
+		target, err := NewTarget(targetConfig)
+		if err != nil {
+			b.logger.Error("Invalid backup target", zap.String("target", targetConfig.Name), zap.Error(err))
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func (b *Backup) upload(file string) error {
+	var errs []error
+	for _, target := range b.remoteTargets() {
+		b.phase(PhaseUpload)
+		b.logger.Info("Uploading backup", zap.String("target", target.Name()), zap.String("file", file))
+		err := target.Upload(file, filepath.Base(file))
+		if err != nil {
+			b.logger.Error("Backup upload failed", zap.String("target", target.Name()), zap.Error(err))
+			errs = append(errs, fmt.Errorf("upload to %s: %w", target.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fetch downloads a backup which only exists on a remote target into the backup dir.
+func (b *Backup) fetch(file File) (bool, error) {
+	if _, err := os.Stat(file.FullName); err == nil {
+		return false, nil
+	}
+	for _, target := range b.remoteTargets() {
+		names, err := target.List()
+		if err != nil {
+			b.logger.Warn("Cannot list backup target", zap.String("target", target.Name()), zap.Error(err))
+			continue
+		}
+		for _, name := range names {
+			if name != file.File {
+				continue
+			}
+			b.phase(PhaseDownload)
+			b.logger.Info("Downloading backup", zap.String("target", target.Name()), zap.String("file", file.File))
+			return true, download(target, name, file.FullName)
+		}
+	}
+	return false, fmt.Errorf("backup not found: %s", file.File)
 }
 
 func (b *Backup) Create(app string) error {
-	now := b.timeProvider.Now().Format("2006-0102-150405")
-	file := fmt.Sprintf("%s/%s-%s.tar.gz", b.backupDir, app, now)
+	if app == PlatformApp {
+		return b.createPlatform()
+	}
+	err := b.ValidateMode(app)
+	if err != nil {
+		return err
+	}
+	if b.userConfig.IsBackupIncremental() {
+		return b.createSnapshot(app)
+	}
+	if b.userConfig.IsBackupStreaming() {
+		return b.createStreaming(app)
+	}
+	now := b.timeProvider.Now()
+	file := b.fileName(app, now, b.archiveExt())
 	b.logger.Info("Running backup create", zap.String("app", app), zap.String("file", file))
 
 	tempDir, err := os.MkdirTemp("", "")
@@ -169,11 +524,13 @@ func (b *Backup) Create(app string) error {
 		return fmt.Errorf("app not found: %s", app)
 	}
 
+	b.phase(PhasePreStop)
 	err = b.snapCli.RunCmdIfExists(*snap, CreatePreStop)
 	if err != nil {
 		return err
 	}
 
+	b.phase(PhaseStop)
 	err = b.snapCli.Stop(app)
 	if err != nil {
 		return err
@@ -184,6 +541,7 @@ func (b *Backup) Create(app string) error {
 		return err
 	}
 
+	b.phase(PhaseCopy)
 	tempCurrentDir := fmt.Sprintf("%s/current", tempDir)
 	b.logger.Info(fmt.Sprintf("temp dir %s", tempCurrentDir))
 	err = os.Mkdir(tempCurrentDir, 0755)
@@ -213,12 +571,16 @@ func (b *Backup) Create(app string) error {
 		return err
 	}
 
+	b.phase(PhaseStart)
 	err = b.snapCli.Start(app)
 	if err != nil {
 		return err
 	}
 
-	err = createTarGz(file, tempDir)
+	err = createTarGz(file, []Source{
+		{Name: "current", Dir: tempCurrentDir},
+		{Name: "common", Dir: tempCommonDir},
+	}, b.encryption(), Manifest{App: app, Version: snap.Version, Created: now}, b.counter(PhaseCompress, appCurrentSize+appCommonSize))
 	if err != nil {
 		return err
 	}
@@ -229,7 +591,104 @@ func (b *Backup) Create(app string) error {
 		return err
 	}
 
-	return nil
+	return b.upload(file)
+}
+
+func (b *Backup) fileName(app string, now time.Time, ext string) string {
+	return fmt.Sprintf("%s/%s-%s%s", b.backupDir, app, now.Format("2006-0102-150405"), ext)
+}
+
+func (b *Backup) createSnapshot(app string) error {
+	now := b.timeProvider.Now()
+	file := b.fileName(app, now, SnapshotExt)
+	b.logger.Info("Running incremental backup create", zap.String("app", app), zap.String("file", file))
+	return b.createStopped(app, now, func(sources []Source, manifest Manifest, progress io.Writer) error {
+		return b.chunks.WriteSnapshot(file, manifest, sources, progress)
+	})
+}
+
+func (b *Backup) createStreaming(app string) error {
+	now := b.timeProvider.Now()
+	file := b.fileName(app, now, b.archiveExt())
+	b.logger.Info("Running streaming backup create", zap.String("app", app), zap.String("file", file))
+	err := b.createStopped(app, now, func(sources []Source, manifest Manifest, progress io.Writer) error {
+		return createTarGz(file, sources, b.encryption(), manifest, progress)
+	})
+	if err != nil {
+		return err
+	}
+	return b.upload(file)
+}
+
+func (b *Backup) createStopped(app string, created time.Time, write func(sources []Source, manifest Manifest, progress io.Writer) error) error {
+	appBaseDir := fmt.Sprintf("%s/%s", b.varDir, app)
+	versionDir, err := filepath.EvalSymlinks(fmt.Sprintf("%s/current", appBaseDir))
+	if err != nil {
+		return err
+	}
+	commonDir := fmt.Sprintf("%s/common", appBaseDir)
+	size := b.size(versionDir, commonDir)
+
+	snap, err := b.snapServer.FindInstalled(app)
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return fmt.Errorf("app not found: %s", app)
+	}
+
+	b.phase(PhasePreStop)
+	err = b.snapCli.RunCmdIfExists(*snap, CreatePreStop)
+	if err != nil {
+		return err
+	}
+
+	b.phase(PhaseStop)
+	err = b.snapCli.Stop(app)
+	if err != nil {
+		return err
+	}
+
+	err = b.snapCli.RunCmdIfExists(*snap, CreatePostStop)
+	if err != nil {
+		return err
+	}
+
+	err = write([]Source{
+		{Name: "current", Dir: versionDir},
+		{Name: "common", Dir: commonDir},
+	}, Manifest{App: app, Version: snap.Version, Created: created}, b.counter(PhaseCompress, size))
+	b.phase(PhaseStart)
+	startErr := b.snapCli.Start(app)
+	if err != nil {
+		b.logger.Error("cannot write backup", zap.Error(err))
+		return err
+	}
+	return startErr
+}
+
+// size returns the disk usage of dirs, it is only used for progress so errors are logged and ignored.
+func (b *Backup) size(dirs ...string) uint64 {
+	var total uint64
+	for _, dir := range dirs {
+		used, err := b.diskusage.Used(dir)
+		if err != nil {
+			b.logger.Warn("cannot get dir size", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		total += used
+	}
+	return total
+}
+
+func (b *Backup) phase(phase string) {
+	b.logger.Info("backup phase", zap.String("phase", phase))
+	b.progress.Phase(phase)
+}
+
+func (b *Backup) counter(phase string, total uint64) io.Writer {
+	b.phase(phase)
+	return &progressCounter{progress: b.progress, total: int64(total)}
 }
 
 func (b *Backup) options() cp.Options {
@@ -249,140 +708,370 @@ func (b *Backup) options() cp.Options {
 }
 
 func (b *Backup) Restore(fileName string) error {
+	return b.RestoreWithKeys(fileName, Encryption{})
+}
+
+// RestoreWithKeys restores a backup decrypting it with the given passphrase or identities instead of the configured ones,
+// a fresh device restoring a platform backup has no encryption configured yet.
+func (b *Backup) RestoreWithKeys(fileName string, keys Encryption) error {
+	keys = b.decryption(keys)
 	file, err := Parse(b.backupDir, fileName)
 	if err != nil {
 		return err
 	}
 	b.logger.Info("Running backup restore", zap.String("app", file.App), zap.String("file", file.FullName))
-
-	tempDir, err := os.MkdirTemp("", "")
+	fetched, err := b.fetch(file)
 	if err != nil {
-		b.logger.Info("cannot create tmp dir", zap.Error(err))
 		return err
 	}
-
-	fileStat, err := os.Stat(file.FullName)
+	if fetched {
+		defer os.Remove(file.FullName)
+	}
+	verification, err := b.verify(file, keys)
 	if err != nil {
 		return err
 	}
+	if file.App == PlatformApp {
+		return b.restorePlatform(file, keys)
+	}
 
-	tempSpaceLeft := df.NewDiskUsage(tempDir).Available()
-	tempSpaceNeeded := uint64(fileStat.Size()) * 2
-	b.logger.Info(fmt.Sprintf("temp space left: %d", tempSpaceLeft))
-	b.logger.Info(fmt.Sprintf("temp space needed: %d", tempSpaceNeeded))
+	snap, err := b.snapServer.FindInstalled(file.App)
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return fmt.Errorf("%s is not installed, install it before restoring the backup", file.App)
+	}
+	if verification.Version != "" && verification.Version != snap.Version {
+		b.logger.Info("restoring backup of another app version",
+			zap.String("backup", verification.Version), zap.String("installed", snap.Version))
+	}
 
-	if tempSpaceLeft < tempSpaceNeeded {
-		return fmt.Errorf("not enough temp space for the restore")
+	appBaseDir := fmt.Sprintf("%s/%s", b.varDir, file.App)
+	currentDir, err := filepath.EvalSymlinks(fmt.Sprintf("%s/current", appBaseDir))
+	if err != nil {
+		return err
 	}
+	commonDir := fmt.Sprintf("%s/common", appBaseDir)
 
-	err = extractTarGz(file.FullName, tempDir)
+	spaceNeeded, err := b.restoreSpaceNeeded(file, verification)
 	if err != nil {
 		return err
 	}
+	spaceLeft := df.NewDiskUsage(appBaseDir).Available()
+	b.logger.Info(fmt.Sprintf("space left: %d", spaceLeft))
+	b.logger.Info(fmt.Sprintf("space needed: %d", spaceNeeded))
+	if spaceLeft < spaceNeeded {
+		return fmt.Errorf("not enough space for the restore")
+	}
 
-	snap, err := b.snapServer.FindInstalled(file.App)
+	dirs := NewDirSwap(currentDir, commonDir)
+	err = dirs.Prepare()
 	if err != nil {
 		return err
 	}
-	if snap == nil {
-		return fmt.Errorf("app not found: %s", file.App)
+	err = b.stage(file, dirs, currentDir, commonDir, keys)
+	if err != nil {
+		b.cleanup(dirs)
+		return err
 	}
 
+	b.phase(PhasePreStop)
 	err = b.snapCli.RunCmdIfExists(*snap, RestorePreStop)
 	if err != nil {
+		b.cleanup(dirs)
 		return err
 	}
 
+	b.phase(PhaseStop)
 	err = b.snapCli.Stop(file.App)
+	if err == nil {
+		err = b.swap(file.App, *snap, verification.Version, dirs)
+	}
 	if err != nil {
+		b.rollback(file.App, dirs)
 		return err
 	}
 
-	err = b.snapCli.RunCmdIfExists(*snap, RestorePostStop)
+	err = dirs.Commit()
 	if err != nil {
-		return err
+		b.logger.Warn("cannot remove previous app data", zap.Error(err))
 	}
+	return nil
+}
 
-	appBaseDir := fmt.Sprintf("%s/%s", b.varDir, file.App)
-
-	currentDir := fmt.Sprintf("%s/current", appBaseDir)
-	targetCurrentDir, err := filepath.EvalSymlinks(currentDir)
+func (b *Backup) stage(file File, dirs *DirSwap, currentDir string, commonDir string, keys Encryption) error {
+	layout := Layout{"current": dirs.Staging(currentDir), "common": dirs.Staging(commonDir)}
+	b.logger.Info("extracting backup", zap.String("current", layout["current"]), zap.String("common", layout["common"]))
+	size, err := b.archiveSize(file)
 	if err != nil {
 		return err
 	}
-	err = b.recreateDir(targetCurrentDir, file.App)
+	err = b.extract(file, layout, keys, b.counter(PhaseExtract, size))
 	if err != nil {
 		return err
 	}
-	tempCurrentDir := fmt.Sprintf("%s/current", tempDir)
-	b.logger.Info(fmt.Sprintf("copy %s to %s", tempCurrentDir, targetCurrentDir))
-	err = cp.Copy(tempCurrentDir, targetCurrentDir, b.options())
+	for _, dir := range layout {
+		err = b.chown(dir, file.App)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Backup) swap(app string, snap model.Snap, version string, dirs *DirSwap) error {
+	err := b.snapCli.RunCmdIfExists(snap, RestorePostStop)
 	if err != nil {
 		return err
 	}
-	err = b.chown(targetCurrentDir, file.App)
+
+	b.phase(PhaseSwap)
+	err = dirs.Apply()
 	if err != nil {
 		return err
 	}
 
-	commonDir := fmt.Sprintf("%s/common", appBaseDir)
-	err = b.recreateDir(commonDir, file.App)
+	err = b.migrate(snap, version)
 	if err != nil {
 		return err
 	}
-	tempCommonDir := fmt.Sprintf("%s/common", tempDir)
-	b.logger.Info(fmt.Sprintf("copy %s to %s", tempCommonDir, commonDir))
-	err = cp.Copy(tempCommonDir, commonDir, b.options())
+
+	err = b.snapCli.RunCmdIfExists(snap, RestorePreStart)
 	if err != nil {
 		return err
 	}
-	err = b.chown(commonDir, file.App)
+
+	b.phase(PhaseStart)
+	err = b.snapCli.Start(app)
 	if err != nil {
 		return err
 	}
 
-	err = b.snapCli.RunCmdIfExists(*snap, RestorePreStart)
+	return b.snapCli.RunCmdIfExists(snap, RestorePostStart)
+}
+
+// migrate lets the app convert restored data when the backup was made by another app version.
+func (b *Backup) migrate(snap model.Snap, version string) error {
+	if version == "" || version == snap.Version {
+		return nil
+	}
+	if snap.FindCommand(RestoreMigrate) == nil {
+		b.logger.Warn("app has no restore migrate hook, restored data is used as is",
+			zap.String("backup", version), zap.String("installed", snap.Version))
+		return nil
+	}
+	b.phase(PhaseMigrate)
+	b.logger.Info("migrating restored data", zap.String("from", version), zap.String("to", snap.Version))
+	return b.snapCli.RunCmdIfExists(snap, RestoreMigrate, version, snap.Version)
+}
+
+func (b *Backup) cleanup(dirs *DirSwap) {
+	err := dirs.Cleanup()
 	if err != nil {
-		return err
+		b.logger.Warn("cannot remove staged restore", zap.Error(err))
 	}
+}
 
-	err = b.snapCli.Start(file.App)
+func (b *Backup) rollback(app string, dirs *DirSwap) {
+	b.logger.Error("restore failed, rolling back", zap.String("app", app))
+	err := b.snapCli.Stop(app)
 	if err != nil {
-		return err
+		b.logger.Error("cannot stop app for rollback", zap.String("app", app), zap.Error(err))
+	}
+	err = dirs.Rollback()
+	if err != nil {
+		b.logger.Error("rollback failed", zap.String("app", app), zap.Error(err))
 	}
+	err = b.snapCli.Start(app)
+	if err != nil {
+		b.logger.Error("cannot start app after rollback", zap.String("app", app), zap.Error(err))
+	}
+}
 
-	err = b.snapCli.RunCmdIfExists(*snap, RestorePostStart)
+// Verify checks that a backup is complete and matches its manifest.
+func (b *Backup) Verify(fileName string) (Verification, error) {
+	file, err := Parse(b.backupDir, fileName)
 	if err != nil {
-		return err
+		return Verification{}, err
 	}
+	fetched, err := b.fetch(file)
+	if err != nil {
+		return Verification{}, err
+	}
+	if fetched {
+		defer os.Remove(file.FullName)
+	}
+	return b.verify(file, b.encryption())
+}
 
-	err = os.RemoveAll(tempDir)
+func (b *Backup) verify(file File, keys Encryption) (Verification, error) {
+	b.logger.Info("verifying backup", zap.String("file", file.FullName))
+	verification := Verification{File: file.File, App: file.App}
+	size, err := b.archiveSize(file)
 	if err != nil {
-		return err
+		return verification, err
+	}
+	progress := b.counter(PhaseVerify, size)
+	if IsSnapshot(file.File) {
+		snapshot, err := b.chunks.VerifySnapshot(file.FullName, progress)
+		if err != nil {
+			return verification, fmt.Errorf("backup verification failed: %w", err)
+		}
+		verification = snapshot.Verification(file)
+	} else {
+		manifest, found, err := verifyTarGz(file.FullName, file.Encrypted, keys, progress)
+		if err != nil {
+			return verification, fmt.Errorf("backup verification failed: %w", err)
+		}
+		if !found {
+			b.logger.Warn("backup has no manifest, only the archive structure is verified", zap.String("file", file.File))
+			return verification, nil
+		}
+		verification = manifest.Verification(file)
 	}
+	if verification.App != file.App {
+		return verification, fmt.Errorf("backup verification failed: backup of %s is named as %s", verification.App, file.App)
+	}
+	return verification, nil
+}
 
-	return nil
+func (b *Backup) extract(file File, layout Layout, keys Encryption, progress io.Writer) error {
+	if IsSnapshot(file.File) {
+		return b.chunks.ExtractSnapshot(file.FullName, layout, progress)
+	}
+	return extractTarGz(file.FullName, file.Encrypted, layout, keys, progress)
 }
 
-func (b *Backup) recreateDir(dir, app string) error {
-	b.logger.Info(fmt.Sprintf("recreate dir %s", dir))
-	err := os.RemoveAll(dir)
+// archiveSize is the amount of bytes reported as progress while reading a backup.
+func (b *Backup) archiveSize(file File) (uint64, error) {
+	if IsSnapshot(file.File) {
+		snapshot, err := ReadSnapshot(file.FullName)
+		if err != nil {
+			return 0, err
+		}
+		return snapshot.Size(), nil
+	}
+	fileStat, err := os.Stat(file.FullName)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return os.MkdirAll(dir, 0755)
+	return uint64(fileStat.Size()), nil
 }
 
-func (b *Backup) Remove(fileName string) error {
+// restoreSpaceNeeded is the size of the extracted payload from the manifest, archives made before
+// manifests were introduced do not record it so twice the compressed size is assumed.
+func (b *Backup) restoreSpaceNeeded(file File, verification Verification) (uint64, error) {
+	if verification.Manifest {
+		return verification.Size + verification.Size/restoreMargin, nil
+	}
+	size, err := b.archiveSize(file)
+	if err != nil {
+		return 0, err
+	}
+	return size * 2, nil
+}
+
+// Upload stores a backup made on another device, it is kept only if it passes verification.
+func (b *Backup) Upload(fileName string, reader io.Reader) (Verification, error) {
+	return b.UploadWithKeys(fileName, reader, Encryption{})
+}
+
+// UploadWithKeys is Upload verifying an encrypted backup with the given passphrase or identities.
+func (b *Backup) UploadWithKeys(fileName string, reader io.Reader, keys Encryption) (Verification, error) {
+	keys = b.decryption(keys)
+	if fileName == "" || filepath.Base(fileName) != fileName || strings.HasPrefix(fileName, ".") {
+		return Verification{}, fmt.Errorf("invalid backup file name: %s", fileName)
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(fileName, EncryptedExt), ArchiveExt) {
+		return Verification{}, fmt.Errorf("only %s backups can be uploaded", ArchiveExt)
+	}
+	file, err := Parse(b.backupDir, fileName)
+	if err != nil {
+		return Verification{}, err
+	}
+	_, err = os.Stat(file.FullName)
+	if err == nil {
+		return Verification{}, fmt.Errorf("backup already exists: %s", fileName)
+	}
+	b.logger.Info("Receiving backup upload", zap.String("file", file.FullName))
+	part := partFile(file.FullName)
+	err = writeFile(part, reader)
+	if err != nil {
+		_ = os.Remove(part)
+		return Verification{}, err
+	}
+	uploaded := file
+	uploaded.FullName = part
+	verification, err := b.verify(uploaded, keys)
+	if err != nil {
+		_ = os.Remove(part)
+		return verification, err
+	}
+	err = os.Rename(part, file.FullName)
+	if err != nil {
+		return verification, err
+	}
+	b.logger.Info("Backup upload completed", zap.String("file", fileName), zap.String("version", verification.Version))
+	return verification, nil
+}
+
+// Remove deletes a backup from the backup dir, copies on remote targets are only deleted when remote is set.
+func (b *Backup) Remove(fileName string, remote bool) error {
 	file := fmt.Sprintf("%s/%s", b.backupDir, fileName)
 	b.logger.Info("Removing backup file", zap.String("file", file))
+	found := false
 	err := os.Remove(file)
-	if err != nil {
+	if err == nil {
+		found = true
+	} else if !os.IsNotExist(err) {
 		b.logger.Info("Backup remove failed", zap.Error(err))
-	} else {
-		b.logger.Info("Backup remove completed")
+		return err
 	}
-	return err
+	if IsSnapshot(fileName) {
+		removed, err := b.chunks.Prune(b.backupDir)
+		if err != nil {
+			b.logger.Info("Backup chunks cleanup failed", zap.Error(err))
+			return err
+		}
+		b.logger.Info("Backup chunks cleanup", zap.Int("removed", removed))
+	}
+	if remote {
+		removed, err := b.removeRemote(fileName)
+		if err != nil {
+			return err
+		}
+		found = found || removed
+	}
+	if !found {
+		return fmt.Errorf("backup not found: %s", fileName)
+	}
+	b.logger.Info("Backup remove completed")
+	return nil
+}
+
+func (b *Backup) removeRemote(fileName string) (bool, error) {
+	found := false
+	for _, target := range b.remoteTargets() {
+		names, err := target.List()
+		if err != nil {
+			b.logger.Warn("Cannot list backup target", zap.String("target", target.Name()), zap.Error(err))
+			continue
+		}
+		for _, name := range names {
+			if name != fileName {
+				continue
+			}
+			found = true
+			b.logger.Info("Removing remote backup file", zap.String("target", target.Name()), zap.String("file", fileName))
+			err = target.Delete(name)
+			if err != nil {
+				b.logger.Info("Backup remove failed", zap.String("target", target.Name()), zap.Error(err))
+				return found, err
+			}
+		}
+	}
+	return found, nil
 }
 
 func (b *Backup) chown(dir, app string) error {
@@ -414,35 +1103,82 @@ func (b *Backup) chown(dir, app string) error {
 
 }
 
-func createTarGz(outputFile, sourceDir string) error {
-	f, err := os.Create(outputFile)
+func createTarGz(outputFile string, sources []Source, encryption Encryption, manifest Manifest, progress io.Writer) error {
+	part := partFile(outputFile)
+	f, err := os.Create(part)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	gw := gzip.NewWriter(f)
-	defer gw.Close()
 
+	var w io.WriteCloser = f
+	if IsEncrypted(outputFile) {
+		w, err = encryption.Encrypt(f)
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(part)
+			return err
+		}
+	}
+	gw := gzip.NewWriter(w)
 	tw := tar.NewWriter(gw)
-	defer tw.Close()
 
-	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+	for _, source := range sources {
+		var files []ManifestFile
+		files, err = writeTar(tw, source, progress)
 		if err != nil {
-			return err
+			break
 		}
-		rel, err := filepath.Rel(sourceDir, path)
+		manifest.Files = append(manifest.Files, files...)
+	}
+	if err == nil {
+		err = writeManifest(tw, manifest)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gw.Close()
+	}
+	if err == nil && w != f {
+		err = w.Close()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	return os.Rename(part, outputFile)
+}
+
+func writeTar(tw *tar.Writer, source Source, progress io.Writer) ([]ManifestFile, error) {
+	var files []ManifestFile
+	err := filepath.Walk(source.Dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if rel == "." {
+		if info.Mode()&os.ModeSocket != 0 {
 			return nil
 		}
-		header, err := tar.FileInfoHeader(info, "")
+		rel, err := filepath.Rel(source.Dir, path)
 		if err != nil {
 			return err
 		}
-		header.Name = "./" + rel
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(source.Name, rel)
+		header.Name = "./" + name
 		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
 			header.Uid = int(stat.Uid)
 			header.Gid = int(stat.Gid)
@@ -450,7 +1186,7 @@ func createTarGz(outputFile, sourceDir string) error {
 		if err := tw.WriteHeader(header); err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if !info.Mode().IsRegular() {
 			return nil
 		}
 		file, err := os.Open(path)
@@ -458,40 +1194,89 @@ func createTarGz(outputFile, sourceDir string) error {
 			return err
 		}
 		defer file.Close()
-		_, err = io.Copy(tw, file)
+		hash := newHashWriter()
+		_, err = io.Copy(io.MultiWriter(tw, hash, progress), file)
+		files = append(files, hash.File(name))
 		return err
 	})
+	return files, err
 }
 
-func extractTarGz(archiveFile, destDir string) error {
-	f, err := os.Open(archiveFile)
+func writeManifest(tw *tar.Writer, manifest Manifest) error {
+	data, err := json.Marshal(manifest)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	gr, err := gzip.NewReader(f)
+	err = tw.WriteHeader(&tar.Header{
+		Name:     "./" + ManifestName,
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+		Size:     int64(len(data)),
+		ModTime:  manifest.Created,
+	})
 	if err != nil {
 		return err
 	}
-	defer gr.Close()
+	_, err = tw.Write(data)
+	return err
+}
 
-	tr := tar.NewReader(gr)
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
+// verifyTarGz reads the whole archive and compares file checksums with the manifest,
+// archives created before manifests were introduced only get the structure checked.
+// encrypted comes from the backup name as archiveFile can be a temporary file.
+func verifyTarGz(archiveFile string, encrypted bool, encryption Encryption, progress io.Writer) (Manifest, bool, error) {
+	var manifest *Manifest
+	found := make(map[string]ManifestFile)
+	err := readTarGz(archiveFile, encrypted, encryption, progress, func(header *tar.Header, reader io.Reader) error {
+		name := entryName(header.Name)
+		if manifest != nil {
+			return fmt.Errorf("unexpected entry after manifest: %s", header.Name)
 		}
+		if name == ManifestName {
+			manifest = &Manifest{}
+			return json.NewDecoder(reader).Decode(manifest)
+		}
+		_, err := entryLayout.Target(header.Name)
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		hash := newHashWriter()
+		_, err = io.Copy(hash, reader)
+		found[name] = hash.File(name)
+		return err
+	})
+	if err != nil {
+		return Manifest{}, false, err
+	}
+	if manifest == nil {
+		return Manifest{}, false, nil
+	}
+	return *manifest, true, manifest.Verify(found)
+}
+
+func extractTarGz(archiveFile string, encrypted bool, layout Layout, encryption Encryption, progress io.Writer) error {
+	return readTarGz(archiveFile, encrypted, encryption, progress, func(header *tar.Header, reader io.Reader) error {
+		if entryName(header.Name) == ManifestName {
+			return nil
+		}
+		target, err := layout.Target(header.Name)
 		if err != nil {
 			return err
 		}
-		target := filepath.Join(destDir, header.Name)
 		switch header.Typeflag {
 		case tar.TypeDir:
 			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
 				return err
 			}
-			os.Chown(target, header.Uid, header.Gid)
+			_ = os.Chown(target, header.Uid, header.Gid)
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+			_ = os.Lchown(target, header.Uid, header.Gid)
 		case tar.TypeReg:
 			dir := filepath.Dir(target)
 			if err := os.MkdirAll(dir, 0755); err != nil {
@@ -501,13 +1286,50 @@ func extractTarGz(archiveFile, destDir string) error {
 			if err != nil {
 				return err
 			}
-			if _, err := io.Copy(file, tr); err != nil {
+			if _, err := io.Copy(file, reader); err != nil {
 				file.Close()
 				return err
 			}
 			file.Close()
-			os.Chown(target, header.Uid, header.Gid)
+			_ = os.Chown(target, header.Uid, header.Gid)
+		}
+		return nil
+	})
+}
+
+func readTarGz(archiveFile string, encrypted bool, encryption Encryption, progress io.Writer, handle func(header *tar.Header, reader io.Reader) error) error {
+	f, err := os.Open(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = io.TeeReader(f, progress)
+	if encrypted {
+		r, err = encryption.Decrypt(r)
+		if err != nil {
+			return err
 		}
 	}
-	return nil
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := handle(header, tr); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(io.Discard, gr)
+	return err
 }
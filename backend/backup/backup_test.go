@@ -2,11 +2,13 @@ package backup
 
 import (
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/syncloud/golib/linux"
 	"github.com/syncloud/platform/cli"
+	"github.com/syncloud/platform/config"
 	"github.com/syncloud/platform/log"
 	"github.com/syncloud/platform/snap/model"
 )
@@ -28,20 +31,30 @@ func (e *DiskUsageStub) Used(_ string) (uint64, error) {
 
 type SnapServiceStub struct {
 	versionDir string
+	stopped    int
+	started    int
+	failCmd    string
+	cmds       []string
 }
 
 func (s *SnapServiceStub) Stop(_ string) error {
 	fmt.Println("stop")
+	s.stopped++
 	return nil
 }
 
 func (s *SnapServiceStub) Start(_ string) error {
 	fmt.Println("start")
+	s.started++
 	return nil
 }
 
-func (s *SnapServiceStub) RunCmdIfExists(_ model.Snap, cmd string) error {
-	fmt.Println("run cmd", cmd)
+func (s *SnapServiceStub) RunCmdIfExists(_ model.Snap, cmd string, args ...string) error {
+	fmt.Println("run cmd", cmd, args)
+	s.cmds = append(s.cmds, strings.TrimSpace(cmd+" "+strings.Join(args, " ")))
+	if cmd == s.failCmd {
+		return fmt.Errorf("%s failed", cmd)
+	}
 	if cmd == CreatePreStop {
 		backupFile := filepath.Join(s.versionDir, "backup.file")
 		if err := os.WriteFile(backupFile, []byte("backup"), 0666); err != nil {
@@ -52,16 +65,23 @@ func (s *SnapServiceStub) RunCmdIfExists(_ model.Snap, cmd string) error {
 }
 
 type SnapInfoStub struct {
+	snap model.Snap
 }
 
 func (s *SnapInfoStub) FindInstalled(_ string) (*model.Snap, error) {
-	return &model.Snap{}, nil
+	snap := s.snap
+	return &snap, nil
 }
 
 type UserConfigStub struct {
-	auto string
-	day  int
-	hour int
+	auto        string
+	day         int
+	hour        int
+	incremental bool
+	streaming   bool
+	encryption  Encryption
+	retention   map[string]config.BackupRetention
+	appAuto     map[string]config.BackupAppAuto
 }
 
 func (u *UserConfigStub) GetBackupAuto() string {
@@ -88,6 +108,149 @@ func (u *UserConfigStub) SetBackupAutoHour(hour int) {
 	u.hour = hour
 }
 
+func (u *UserConfigStub) IsBackupIncremental() bool {
+	return u.incremental
+}
+
+func (u *UserConfigStub) SetBackupIncremental(enabled bool) {
+	u.incremental = enabled
+}
+
+func (u *UserConfigStub) IsBackupStreaming() bool {
+	return u.streaming
+}
+
+func (u *UserConfigStub) SetBackupStreaming(enabled bool) {
+	u.streaming = enabled
+}
+
+func (u *UserConfigStub) GetBackupPassphrase() string {
+	return u.encryption.Passphrase
+}
+
+func (u *UserConfigStub) SetBackupPassphrase(passphrase string) {
+	u.encryption.Passphrase = passphrase
+}
+
+func (u *UserConfigStub) GetBackupRecipients() []string {
+	return u.encryption.Recipients
+}
+
+func (u *UserConfigStub) SetBackupRecipients(recipients []string) {
+	u.encryption.Recipients = recipients
+}
+
+func (u *UserConfigStub) GetBackupIdentities() []string {
+	return u.encryption.Identities
+}
+
+func (u *UserConfigStub) SetBackupIdentities(identities []string) {
+	u.encryption.Identities = identities
+}
+
+func (u *UserConfigStub) GetBackupRetention(app string) (config.BackupRetention, bool) {
+	retention, ok := u.retention[app]
+	return retention, ok
+}
+
+func (u *UserConfigStub) SetBackupRetention(app string, retention config.BackupRetention) {
+	if u.retention == nil {
+		u.retention = make(map[string]config.BackupRetention)
+	}
+	u.retention[app] = retention
+}
+
+func (u *UserConfigStub) RemoveBackupRetention(app string) {
+	delete(u.retention, app)
+}
+
+func (u *UserConfigStub) ListBackupRetentionApps() []string {
+	var apps []string
+	for app := range u.retention {
+		if app != "" {
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+func (u *UserConfigStub) GetBackupAppAuto(app string) (config.BackupAppAuto, bool) {
+	auto, ok := u.appAuto[app]
+	return auto, ok
+}
+
+func (u *UserConfigStub) SetBackupAppAuto(app string, auto config.BackupAppAuto) {
+	if u.appAuto == nil {
+		u.appAuto = make(map[string]config.BackupAppAuto)
+	}
+	u.appAuto[app] = auto
+}
+
+func (u *UserConfigStub) RemoveBackupAppAuto(app string) {
+	delete(u.appAuto, app)
+}
+
+func (u *UserConfigStub) ListBackupAutoApps() []string {
+	var apps []string
+	for app := range u.appAuto {
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+type TargetConfigStub struct {
+	targets []config.BackupTarget
+}
+
+func (c *TargetConfigStub) Add(target config.BackupTarget) error {
+	c.targets = append(c.targets, target)
+	return nil
+}
+
+func (c *TargetConfigStub) Remove(name string) error {
+	var targets []config.BackupTarget
+	for _, target := range c.targets {
+		if target.Name != name {
+			targets = append(targets, target)
+		}
+	}
+	c.targets = targets
+	return nil
+}
+
+func (c *TargetConfigStub) List() ([]config.BackupTarget, error) {
+	return c.targets, nil
+}
+
+type PlatformStateStub struct {
+	imported string
+}
+
+func (p *PlatformStateStub) Export(dir string) error {
+	return os.WriteFile(filepath.Join(dir, "platform.db"), []byte("config"), 0600)
+}
+
+func (p *PlatformStateStub) Import(dir string) error {
+	content, err := os.ReadFile(filepath.Join(dir, "platform.db"))
+	p.imported = string(content)
+	return err
+}
+
+type ProgressStub struct {
+	phases []string
+	done   int64
+	total  int64
+}
+
+func (p *ProgressStub) Phase(phase string) {
+	p.phases = append(p.phases, phase)
+}
+
+func (p *ProgressStub) Bytes(done int64, total int64) {
+	p.done = done
+	p.total = total
+}
+
 type ProviderStub struct {
 	now time.Time
 }
@@ -111,9 +274,12 @@ func TestBackup_Remove(t *testing.T) {
 		&SnapServiceStub{},
 		&SnapInfoStub{},
 		&UserConfigStub{},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
 		&ProviderStub{},
+		&ProgressStub{},
 		log.Default())
-	err = backup.Remove("tmpfile")
+	err = backup.Remove("tmpfile", false)
 	assert.Nil(t, err)
 	list, err := backup.List()
 	assert.Nil(t, err)
@@ -167,7 +333,10 @@ func TestBackup_Create(t *testing.T) {
 		&SnapServiceStub{versionDir: versionDir},
 		&SnapInfoStub{},
 		&UserConfigStub{},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
 		&ProviderStub{},
+		&ProgressStub{},
 		log.Default())
 	err = backup.Start()
 	assert.NoError(t, err)
@@ -261,7 +430,10 @@ func TestBackup_Auto(t *testing.T) {
 		&SnapServiceStub{},
 		&SnapInfoStub{},
 		&UserConfigStub{auto: "no", day: 0, hour: 0},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
 		&ProviderStub{},
+		&ProgressStub{},
 		log.Default())
 
 	auto := backup.Auto()
@@ -276,3 +448,685 @@ func TestBackup_Auto(t *testing.T) {
 	assert.Equal(t, 1, auto.Day)
 	assert.Equal(t, 2, auto.Hour)
 }
+
+func TestBackup_CreateIncremental(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	app := "test-app"
+	appDir := filepath.Join(varDir, app)
+	versionDir := filepath.Join(appDir, "x1")
+	_ = os.MkdirAll(versionDir, 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	commonDir := filepath.Join(appDir, "common")
+	_ = os.Mkdir(commonDir, 0750)
+	commonFile := filepath.Join(commonDir, "common.file")
+	err := os.WriteFile(commonFile, []byte("common"), 0666)
+	assert.NoError(t, err)
+
+	err = linux.CreateUser(app)
+	assert.NoError(t, err)
+
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{versionDir: versionDir},
+		&SnapInfoStub{},
+		&UserConfigStub{incremental: true},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	err = backup.Create(app)
+	assert.NoError(t, err)
+	backups, err := backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.True(t, IsSnapshot(backups[0].File))
+
+	err = os.Remove(commonFile)
+	assert.NoError(t, err)
+
+	err = backup.Restore(backups[0].File)
+	assert.NoError(t, err)
+	commonFileContent, err := os.ReadFile(commonFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "common", string(commonFileContent))
+
+	err = backup.Remove(backups[0].File, false)
+	assert.NoError(t, err)
+	chunks, err := os.ReadDir(filepath.Join(backupDir, ChunksDir))
+	assert.NoError(t, err)
+	for _, dir := range chunks {
+		files, err := os.ReadDir(filepath.Join(backupDir, ChunksDir, dir.Name()))
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	}
+}
+
+func TestBackup_CreateStreaming(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	app := "test-app"
+	appDir := filepath.Join(varDir, app)
+	versionDir := filepath.Join(appDir, "x1")
+	_ = os.MkdirAll(versionDir, 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	commonDir := filepath.Join(appDir, "common")
+	_ = os.Mkdir(commonDir, 0750)
+	_, err := net.Listen("unix", filepath.Join(commonDir, "web.socket"))
+	assert.NoError(t, err)
+	commonFile := filepath.Join(commonDir, "common.file")
+	err = os.WriteFile(commonFile, []byte("common"), 0666)
+	assert.NoError(t, err)
+	err = os.Symlink("common.file", filepath.Join(commonDir, "common.link"))
+	assert.NoError(t, err)
+
+	err = linux.CreateUser(app)
+	assert.NoError(t, err)
+
+	progress := &ProgressStub{}
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{versionDir: versionDir},
+		&SnapInfoStub{},
+		&UserConfigStub{streaming: true},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		progress,
+		log.Default())
+
+	err = backup.Create(app)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{PhasePreStop, PhaseStop, PhaseCompress, PhaseStart}, progress.phases)
+	assert.Equal(t, int64(200), progress.total)
+	assert.Equal(t, int64(len("common")+len("backup")), progress.done)
+	backups, err := backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+	verification, err := backup.Verify(backups[0].File)
+	assert.NoError(t, err)
+	assert.True(t, verification.Manifest)
+	assert.Equal(t, app, verification.App)
+	assert.Equal(t, 2, verification.Files)
+	assert.Equal(t, uint64(len("common")+len("backup")), verification.Size)
+
+	toDeleteFile := filepath.Join(commonDir, "file.to.delete")
+	err = os.WriteFile(toDeleteFile, []byte("test"), 0666)
+	assert.NoError(t, err)
+	err = os.Remove(commonFile)
+	assert.NoError(t, err)
+
+	progress.phases = nil
+	err = backup.Restore(backups[0].File)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{PhaseVerify, PhaseExtract, PhasePreStop, PhaseStop, PhaseSwap, PhaseStart}, progress.phases)
+
+	_, err = os.Stat(toDeleteFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	commonFileContent, err := os.ReadFile(commonFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "common", string(commonFileContent))
+	link, err := os.Readlink(filepath.Join(commonDir, "common.link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "common.file", link)
+	backupFileContent, err := os.ReadFile(filepath.Join(versionDir, "backup.file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "backup", string(backupFileContent))
+}
+
+func TestBackup_RestoreStreaming_Corrupted(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	appDir := filepath.Join(varDir, "test-app")
+	_ = os.MkdirAll(filepath.Join(appDir, "x1"), 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	_ = os.Mkdir(filepath.Join(appDir, "common"), 0750)
+	err := os.WriteFile(filepath.Join(backupDir, "test-app-2001-0203-040506.tar.gz"), []byte("corrupted"), 0644)
+	assert.NoError(t, err)
+
+	snapService := &SnapServiceStub{}
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		snapService,
+		&SnapInfoStub{},
+		&UserConfigStub{streaming: true},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	err = backup.Restore("test-app-2001-0203-040506.tar.gz")
+	assert.Error(t, err)
+	assert.Equal(t, 0, snapService.stopped)
+}
+
+func TestBackup_RestoreSpaceNeeded(t *testing.T) {
+	backupDir := t.TempDir()
+	file, err := Parse(backupDir, "test-app-2001-0203-040506.tar.gz")
+	assert.NoError(t, err)
+	err = os.WriteFile(file.FullName, make([]byte, 100), 0644)
+	assert.NoError(t, err)
+	backup := New(backupDir, t.TempDir(), cli.New(log.Default()), &DiskUsageStub{}, &SnapServiceStub{}, &SnapInfoStub{},
+		&UserConfigStub{}, &TargetConfigStub{}, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+
+	needed, err := backup.restoreSpaceNeeded(file, Verification{Manifest: true, Size: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1100), needed)
+
+	needed, err = backup.restoreSpaceNeeded(file, Verification{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(200), needed)
+}
+
+func TestBackup_CreateEncrypted(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	app := "test-app"
+	appDir := filepath.Join(varDir, app)
+	versionDir := filepath.Join(appDir, "x1")
+	_ = os.MkdirAll(versionDir, 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	commonDir := filepath.Join(appDir, "common")
+	_ = os.Mkdir(commonDir, 0750)
+	commonFile := filepath.Join(commonDir, "common.file")
+	err := os.WriteFile(commonFile, []byte("common"), 0666)
+	assert.NoError(t, err)
+
+	err = linux.CreateUser(app)
+	assert.NoError(t, err)
+
+	userConfig := &UserConfigStub{streaming: true}
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{versionDir: versionDir},
+		&SnapInfoStub{},
+		userConfig,
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+	err = backup.SetEncryption(Encryption{Passphrase: "secret"})
+	assert.NoError(t, err)
+
+	err = backup.Create(app)
+	assert.NoError(t, err)
+	backups, err := backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.True(t, backups[0].Encrypted)
+
+	err = os.Remove(commonFile)
+	assert.NoError(t, err)
+
+	userConfig.encryption.Passphrase = "wrong"
+	err = backup.Restore(backups[0].File)
+	assert.Error(t, err)
+
+	userConfig.encryption.Passphrase = "secret"
+	err = backup.Restore(backups[0].File)
+	assert.NoError(t, err)
+	commonFileContent, err := os.ReadFile(commonFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "common", string(commonFileContent))
+}
+
+func TestBackup_IncrementalEncrypted_Rejected(t *testing.T) {
+	userConfig := &UserConfigStub{incremental: true}
+	snapService := &SnapServiceStub{}
+	backup := New(t.TempDir(), t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, snapService, &SnapInfoStub{},
+		userConfig, &TargetConfigStub{}, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+
+	err := backup.SetEncryption(Encryption{Passphrase: "secret"})
+	assert.ErrorIs(t, err, ErrIncrementalEncrypted)
+	assert.Empty(t, userConfig.encryption.Passphrase)
+
+	userConfig.encryption.Passphrase = "secret"
+	assert.ErrorIs(t, backup.ValidateMode("test-app"), ErrIncrementalEncrypted)
+	assert.NoError(t, backup.ValidateMode(PlatformApp))
+	err = backup.Create("test-app")
+	assert.ErrorIs(t, err, ErrIncrementalEncrypted)
+	assert.Equal(t, 0, snapService.stopped)
+}
+
+func TestBackup_IncrementalRemote_Rejected(t *testing.T) {
+	userConfig := &UserConfigStub{incremental: true}
+	targets := &TargetConfigStub{}
+	backup := New(t.TempDir(), t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{},
+		userConfig, targets, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+
+	err := backup.AddTarget(config.BackupTarget{Name: "usb", Type: TargetLocal, Path: t.TempDir()})
+	assert.ErrorIs(t, err, ErrIncrementalRemote)
+	assert.Empty(t, targets.targets)
+
+	targets.targets = []config.BackupTarget{{Name: "usb", Type: TargetLocal, Path: t.TempDir()}}
+	assert.ErrorIs(t, backup.ValidateMode("test-app"), ErrIncrementalRemote)
+	userConfig.incremental = false
+	assert.NoError(t, backup.ValidateMode("test-app"))
+}
+
+func TestBackup_SetMode(t *testing.T) {
+	userConfig := &UserConfigStub{}
+	targets := &TargetConfigStub{}
+	backup := New(t.TempDir(), t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{},
+		userConfig, targets, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+
+	assert.NoError(t, backup.SetMode(Mode{Incremental: true}))
+	assert.Equal(t, Mode{Incremental: true}, backup.Mode())
+	assert.NoError(t, backup.SetMode(Mode{Streaming: true}))
+	assert.False(t, userConfig.incremental)
+	assert.True(t, userConfig.streaming)
+
+	userConfig.encryption.Passphrase = "secret"
+	assert.ErrorIs(t, backup.SetMode(Mode{Incremental: true}), ErrIncrementalEncrypted)
+	assert.False(t, userConfig.incremental)
+	assert.NoError(t, backup.SetMode(Mode{Streaming: true}))
+
+	userConfig.encryption.Passphrase = ""
+	targets.targets = []config.BackupTarget{{Name: "usb", Type: TargetLocal, Path: t.TempDir()}}
+	assert.ErrorIs(t, backup.SetMode(Mode{Incremental: true}), ErrIncrementalRemote)
+	assert.False(t, userConfig.incremental)
+}
+
+func TestBackup_RemoteTarget(t *testing.T) {
+	backupDir := t.TempDir()
+	remoteDir := t.TempDir()
+	varDir := t.TempDir()
+	app := "test-app"
+	appDir := filepath.Join(varDir, app)
+	versionDir := filepath.Join(appDir, "x1")
+	_ = os.MkdirAll(versionDir, 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	commonDir := filepath.Join(appDir, "common")
+	_ = os.Mkdir(commonDir, 0750)
+	commonFile := filepath.Join(commonDir, "common.file")
+	err := os.WriteFile(commonFile, []byte("common"), 0666)
+	assert.NoError(t, err)
+
+	err = linux.CreateUser(app)
+	assert.NoError(t, err)
+
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{versionDir: versionDir},
+		&SnapInfoStub{},
+		&UserConfigStub{streaming: true},
+		&TargetConfigStub{targets: []config.BackupTarget{{Name: "usb", Type: TargetLocal, Path: remoteDir}}},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	err = backup.Create(app)
+	assert.NoError(t, err)
+	backups, err := backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.Empty(t, backups[0].Target)
+	_, err = os.Stat(filepath.Join(remoteDir, backups[0].File))
+	assert.NoError(t, err)
+
+	err = os.Remove(filepath.Join(backupDir, backups[0].File))
+	assert.NoError(t, err)
+	backups, err = backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.Equal(t, "usb", backups[0].Target)
+
+	err = os.Remove(commonFile)
+	assert.NoError(t, err)
+	err = backup.Restore(backups[0].File)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(commonFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "common", string(content))
+	_, err = os.Stat(filepath.Join(backupDir, backups[0].File))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	err = backup.Remove(backups[0].File, false)
+	assert.Error(t, err)
+	err = backup.Remove(backups[0].File, true)
+	assert.NoError(t, err)
+	backups, err = backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(backups))
+}
+
+func TestBackup_ApplyRetention(t *testing.T) {
+	backupDir := t.TempDir()
+	for _, name := range []string{
+		"app1-2001-0201-040506.tar.gz",
+		"app1-2001-0202-040506.tar.gz",
+		"app1-2001-0203-040506.tar.gz",
+		"app2-2001-0201-040506.tar.gz",
+		"app2-2001-0202-040506.tar.gz",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(backupDir, name), []byte{}, 0644))
+	}
+	userConfig := &UserConfigStub{}
+	backup := New(
+		backupDir,
+		t.TempDir(),
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{},
+		&SnapInfoStub{},
+		userConfig,
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+	assert.NoError(t, backup.SetRetention(AppRetention{BackupRetention: config.BackupRetention{KeepLast: 1}}))
+	assert.NoError(t, backup.SetRetention(AppRetention{App: "app2", BackupRetention: config.BackupRetention{KeepLast: 2}}))
+	assert.Error(t, backup.SetRetention(AppRetention{BackupRetention: config.BackupRetention{KeepDaily: -1}}))
+
+	plan, err := backup.PlanRetention("")
+	assert.NoError(t, err)
+	assert.Len(t, plan.Keep, 3)
+	assert.Len(t, plan.Remove, 2)
+	files, err := backup.List()
+	assert.NoError(t, err)
+	assert.Len(t, files, 5)
+
+	_, err = backup.ApplyRetention("app1")
+	assert.NoError(t, err)
+	files, err = backup.List()
+	assert.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.File)
+	}
+	assert.ElementsMatch(t, []string{"app1-2001-0203-040506.tar.gz", "app2-2001-0201-040506.tar.gz", "app2-2001-0202-040506.tar.gz"}, names)
+}
+
+func TestBackup_ApplyRetention_KeepsRemoteCopies(t *testing.T) {
+	backupDir := t.TempDir()
+	remoteDir := t.TempDir()
+	for _, name := range []string{
+		"app1-2001-0201-040506.tar.gz",
+		"app1-2001-0202-040506.tar.gz",
+		"app1-2001-0203-040506.tar.gz",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(backupDir, name), []byte{}, 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(remoteDir, name), []byte{}, 0644))
+	}
+	userConfig := &UserConfigStub{}
+	backup := New(backupDir, t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{}, userConfig,
+		&TargetConfigStub{targets: []config.BackupTarget{{Name: "usb", Type: TargetLocal, Path: remoteDir}}},
+		&PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+	assert.NoError(t, backup.SetRetention(AppRetention{BackupRetention: config.BackupRetention{KeepLast: 1}}))
+
+	plan, err := backup.ApplyRetention("")
+	assert.NoError(t, err)
+	assert.Len(t, plan.Remove, 2)
+	local, err := os.ReadDir(backupDir)
+	assert.NoError(t, err)
+	assert.Len(t, local, 1)
+	remote, err := os.ReadDir(remoteDir)
+	assert.NoError(t, err)
+	assert.Len(t, remote, 3)
+}
+
+func TestBackup_Restore_VerificationFailed(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	appDir := filepath.Join(varDir, "test-app")
+	_ = os.MkdirAll(filepath.Join(appDir, "x1"), 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	_ = os.Mkdir(filepath.Join(appDir, "common"), 0750)
+	file := "test-app-2001-0203-040506.tar.gz"
+	writeTestArchive(t, filepath.Join(backupDir, file), "corrupted", &Manifest{App: "test-app", Files: []ManifestFile{
+		{Path: "common/data.file", Size: 9, Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+	}})
+
+	snapService := &SnapServiceStub{}
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		snapService,
+		&SnapInfoStub{},
+		&UserConfigStub{},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	_, err := backup.Verify(file)
+	assert.Error(t, err)
+	err = backup.Restore(file)
+	assert.ErrorContains(t, err, "verification failed")
+	assert.Equal(t, 0, snapService.stopped)
+}
+
+func TestBackup_Restore_Rollback(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	app := "test-app"
+	appDir := filepath.Join(varDir, app)
+	versionDir := filepath.Join(appDir, "x1")
+	_ = os.MkdirAll(versionDir, 0750)
+	_ = os.Symlink("x1", filepath.Join(appDir, "current"))
+	commonDir := filepath.Join(appDir, "common")
+	_ = os.Mkdir(commonDir, 0750)
+	commonFile := filepath.Join(commonDir, "common.file")
+	err := os.WriteFile(commonFile, []byte("backup"), 0666)
+	assert.NoError(t, err)
+
+	err = linux.CreateUser(app)
+	assert.NoError(t, err)
+
+	snapService := &SnapServiceStub{versionDir: versionDir}
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		snapService,
+		&SnapInfoStub{},
+		&UserConfigStub{streaming: true},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+	err = backup.Create(app)
+	assert.NoError(t, err)
+	backups, err := backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+
+	err = os.WriteFile(commonFile, []byte("live"), 0666)
+	assert.NoError(t, err)
+	snapService.failCmd = RestorePostStart
+	snapService.started = 0
+
+	err = backup.Restore(backups[0].File)
+	assert.ErrorContains(t, err, RestorePostStart)
+
+	content, err := os.ReadFile(commonFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "live", string(content))
+	entries, err := os.ReadDir(appDir)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"common", "current", "x1"}, names)
+	assert.Equal(t, 2, snapService.started)
+}
+
+func TestBackup_Upload(t *testing.T) {
+	backupDir := t.TempDir()
+	uploadDir := t.TempDir()
+	file := "test-app-2001-0203-040506.tar.gz"
+	writeTestArchive(t, filepath.Join(uploadDir, file), "data", &Manifest{App: "test-app", Version: "1", Files: []ManifestFile{
+		{Path: "common/data.file", Size: 4, Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+	}})
+	corrupted := "test-app-2001-0204-040506.tar.gz"
+	err := os.WriteFile(filepath.Join(uploadDir, corrupted), []byte("corrupted"), 0644)
+	assert.NoError(t, err)
+
+	backup := New(
+		backupDir,
+		t.TempDir(),
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{},
+		&SnapInfoStub{},
+		&UserConfigStub{},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	upload := func(name string, source string) (Verification, error) {
+		f, err := os.Open(filepath.Join(uploadDir, source))
+		assert.NoError(t, err)
+		defer f.Close()
+		return backup.Upload(name, f)
+	}
+
+	verification, err := upload(file, file)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", verification.Version)
+	backups, err := backup.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.Equal(t, file, backups[0].File)
+
+	_, err = upload(file, file)
+	assert.ErrorContains(t, err, "already exists")
+	_, err = upload("../"+file, file)
+	assert.ErrorContains(t, err, "invalid backup file name")
+	_, err = upload("test-app-2001-0203-040506.snapshot", file)
+	assert.Error(t, err)
+	_, err = upload(corrupted, corrupted)
+	assert.ErrorContains(t, err, "verification failed")
+
+	entries, err := os.ReadDir(backupDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestBackup_Upload_Encrypted(t *testing.T) {
+	sourceDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data.file"), []byte("data"), 0644))
+	uploadDir := t.TempDir()
+	file := "test-app-2001-0203-040506.tar.gz" + EncryptedExt
+	encryption := Encryption{Passphrase: "secret"}
+	err := createTarGz(filepath.Join(uploadDir, file), []Source{{Name: "common", Dir: sourceDir}}, encryption, Manifest{App: "test-app", Version: "1"}, io.Discard)
+	assert.NoError(t, err)
+
+	backupDir := t.TempDir()
+	backup := New(backupDir, t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{},
+		&UserConfigStub{encryption: encryption}, &TargetConfigStub{}, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+
+	f, err := os.Open(filepath.Join(uploadDir, file))
+	assert.NoError(t, err)
+	defer f.Close()
+	verification, err := backup.Upload(file, f)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", verification.Version)
+	assert.Equal(t, 1, verification.Files)
+	_, err = os.Stat(filepath.Join(backupDir, file))
+	assert.NoError(t, err)
+}
+
+func TestBackup_Restore_Migrate(t *testing.T) {
+	backupDir := t.TempDir()
+	varDir := t.TempDir()
+	app := "test-app"
+	appDir := filepath.Join(varDir, app)
+	_ = os.MkdirAll(filepath.Join(appDir, "x2"), 0750)
+	_ = os.Symlink("x2", filepath.Join(appDir, "current"))
+	commonDir := filepath.Join(appDir, "common")
+	_ = os.Mkdir(commonDir, 0750)
+	file := "test-app-2001-0203-040506.tar.gz"
+	writeTestArchive(t, filepath.Join(backupDir, file), "data", &Manifest{App: app, Version: "1", Files: []ManifestFile{
+		{Path: "common/data.file", Size: 4, Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+	}})
+
+	err := linux.CreateUser(app)
+	assert.NoError(t, err)
+
+	snapService := &SnapServiceStub{}
+	progress := &ProgressStub{}
+	backup := New(
+		backupDir,
+		varDir,
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		snapService,
+		&SnapInfoStub{snap: model.Snap{Name: app, Version: "2", Apps: []model.App{{Name: RestoreMigrate, Snap: app}}}},
+		&UserConfigStub{},
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		progress,
+		log.Default())
+
+	err = backup.Restore(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{RestorePreStop, RestorePostStop, RestoreMigrate + " 1 2", RestorePreStart, RestorePostStart}, snapService.cmds)
+	assert.Equal(t, []string{PhaseVerify, PhaseExtract, PhasePreStop, PhaseStop, PhaseSwap, PhaseMigrate, PhaseStart}, progress.phases)
+	content, err := os.ReadFile(filepath.Join(commonDir, "data.file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+}
+
+func TestBackup_AppAuto(t *testing.T) {
+	userConfig := &UserConfigStub{auto: "backup", hour: 3}
+	backup := New(
+		t.TempDir(),
+		t.TempDir(),
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		&SnapServiceStub{},
+		&SnapInfoStub{},
+		userConfig,
+		&TargetConfigStub{},
+		&PlatformStateStub{},
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	assert.NoError(t, backup.SetAppAuto(AppAuto{App: "files", BackupAppAuto: config.BackupAppAuto{Enabled: true, Mode: AutoRestore, Schedule: "0 2 * * *"}}))
+	assert.NoError(t, backup.SetAppAuto(AppAuto{App: "syncthing"}))
+	assert.Error(t, backup.SetAppAuto(AppAuto{BackupAppAuto: config.BackupAppAuto{Enabled: true}}))
+	assert.Error(t, backup.SetAppAuto(AppAuto{App: "files", BackupAppAuto: config.BackupAppAuto{Mode: "no"}}))
+	assert.Error(t, backup.SetAppAuto(AppAuto{App: "files", BackupAppAuto: config.BackupAppAuto{Schedule: "0 25 * * *"}}))
+
+	auto := backup.Auto()
+	assert.Equal(t, "backup", auto.Auto)
+	assert.Equal(t, 3, auto.Hour)
+	assert.Len(t, auto.Apps, 2)
+	assert.Equal(t, "0 2 * * *", auto.Apps["files"].Schedule)
+	assert.False(t, auto.Apps["syncthing"].Enabled)
+
+	backup.RemoveAppAuto("files")
+	assert.Len(t, backup.Auto().Apps, 1)
+}
@@ -0,0 +1,122 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	ChunksDir = ".chunks"
+	ChunkSize = 4 * 1024 * 1024
+	chunkTmp  = ".tmp"
+)
+
+// ChunkStore keeps chunks shared by snapshots, writers hold the read lock until their snapshot
+// manifest is written so that garbage collection never sees their chunks as unreferenced.
+type ChunkStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func NewChunkStore(dir string) *ChunkStore {
+	return &ChunkStore{dir: dir}
+}
+
+func (s *ChunkStore) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+func (s *ChunkStore) Has(hash string) bool {
+	_, err := os.Stat(s.path(hash))
+	return err == nil
+}
+
+func (s *ChunkStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if s.Has(hash) {
+		return hash, nil
+	}
+	target := s.path(hash)
+	err := os.MkdirAll(filepath.Dir(target), 0700)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), hash+chunkTmp)
+	if err != nil {
+		return "", err
+	}
+	gw := gzip.NewWriter(tmp)
+	_, err = gw.Write(data)
+	if err == nil {
+		err = gw.Close()
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return hash, os.Rename(tmp.Name(), target)
+}
+
+func (s *ChunkStore) Get(hash string) ([]byte, error) {
+	f, err := os.Open(s.path(hash))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, gr)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("chunk %s is corrupted", hash)
+	}
+	return buf.Bytes(), nil
+}
+
+// Collect removes every chunk not present in referenced and returns the number of removed chunks,
+// chunks still being written are skipped.
+func (s *ChunkStore) Collect(referenced map[string]bool) (int, error) {
+	removed := 0
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if referenced[info.Name()] || strings.Contains(info.Name(), chunkTmp) {
+			return nil
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const EncryptedExt = ".age"
+
+// ErrIncrementalEncrypted is returned as chunks of incremental backups are stored unencrypted.
+var ErrIncrementalEncrypted = errors.New("incremental backups cannot be encrypted, disable incremental backups or encryption")
+
+type Encryption struct {
+	Passphrase string   `json:"passphrase,omitempty"`
+	Recipients []string `json:"recipients,omitempty"`
+	Identities []string `json:"identities,omitempty"`
+}
+
+type EncryptionInfo struct {
+	Enabled    bool     `json:"enabled"`
+	Passphrase bool     `json:"passphrase"`
+	Recipients []string `json:"recipients"`
+	Identities int      `json:"identities"`
+}
+
+func IsEncrypted(fileName string) bool {
+	return strings.HasSuffix(fileName, EncryptedExt)
+}
+
+func (e Encryption) Enabled() bool {
+	return e.Passphrase != "" || len(e.Recipients) > 0
+}
+
+func (e Encryption) Info() EncryptionInfo {
+	return EncryptionInfo{
+		Enabled:    e.Enabled(),
+		Passphrase: e.Passphrase != "",
+		Recipients: e.Recipients,
+		Identities: len(e.Identities),
+	}
+}
+
+func (e Encryption) Validate() error {
+	if e.Passphrase != "" && len(e.Recipients) > 0 {
+		return fmt.Errorf("use either a passphrase or recipients, not both")
+	}
+	_, err := e.recipients()
+	if err != nil {
+		return err
+	}
+	_, err = e.identities()
+	return err
+}
+
+func (e Encryption) recipients() ([]age.Recipient, error) {
+	if e.Passphrase != "" {
+		recipient, err := age.NewScryptRecipient(e.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{recipient}, nil
+	}
+	var recipients []age.Recipient
+	for _, value := range e.Recipients {
+		recipient, err := age.ParseX25519Recipient(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %s: %w", value, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+func (e Encryption) identities() ([]age.Identity, error) {
+	var identities []age.Identity
+	if e.Passphrase != "" {
+		identity, err := age.NewScryptIdentity(e.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	for _, value := range e.Identities {
+		identity, err := age.ParseX25519Identity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+func (e Encryption) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	recipients, err := e.recipients()
+	if err != nil {
+		return nil, err
+	}
+	return age.Encrypt(w, recipients...)
+}
+
+func (e Encryption) Decrypt(r io.Reader) (io.Reader, error) {
+	identities, err := e.identities()
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("backup is encrypted but no passphrase or identity is configured")
+	}
+	return age.Decrypt(r, identities...)
+}
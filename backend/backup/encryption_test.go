@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encrypt(t *testing.T, encryption Encryption, data string) []byte {
+	var buf bytes.Buffer
+	w, err := encryption.Encrypt(&buf)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestEncryption_Recipients(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypted := encrypt(t, Encryption{Recipients: []string{identity.Recipient().String()}}, "data")
+
+	r, err := Encryption{Identities: []string{identity.String()}}.Decrypt(bytes.NewReader(encrypted))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestEncryption_Recipients_WrongIdentity(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypted := encrypt(t, Encryption{Recipients: []string{identity.Recipient().String()}}, "data")
+
+	_, err = Encryption{Identities: []string{other.String()}}.Decrypt(bytes.NewReader(encrypted))
+	assert.Error(t, err)
+}
+
+func TestEncryption_NoIdentity(t *testing.T) {
+	_, err := Encryption{}.Decrypt(bytes.NewReader([]byte("data")))
+	assert.Error(t, err)
+}
+
+func TestEncryption_Validate(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	assert.NoError(t, Encryption{Recipients: []string{identity.Recipient().String()}}.Validate())
+	assert.NoError(t, Encryption{Passphrase: "secret"}.Validate())
+	assert.Error(t, Encryption{Recipients: []string{"invalid"}}.Validate())
+	assert.Error(t, Encryption{Identities: []string{"invalid"}}.Validate())
+	assert.Error(t, Encryption{Passphrase: "secret", Recipients: []string{identity.Recipient().String()}}.Validate())
+}
+
+func TestEncryption_Info(t *testing.T) {
+	info := Encryption{Passphrase: "secret", Identities: []string{"key"}}.Info()
+	assert.True(t, info.Enabled)
+	assert.True(t, info.Passphrase)
+	assert.Equal(t, 1, info.Identities)
+	assert.False(t, Encryption{}.Info().Enabled)
+}
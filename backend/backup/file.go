@@ -6,10 +6,12 @@ import (
 )
 
 type File struct {
-	Path     string `json:"path"`
-	File     string `json:"file"`
-	App      string `json:"app"`
-	FullName string `json:"-"`
+	Path      string `json:"path"`
+	File      string `json:"file"`
+	App       string `json:"app"`
+	Encrypted bool   `json:"encrypted"`
+	Target    string `json:"target,omitempty"`
+	FullName  string `json:"-"`
 }
 
 func Parse(path string, fileName string) (File, error) {
@@ -24,9 +26,10 @@ func Parse(path string, fileName string) (File, error) {
 	}
 	app := matches[1]
 	return File{
-		Path:     path,
-		File:     fileName,
-		App:      app,
-		FullName: fmt.Sprintf("%s/%s", path, fileName),
+		Path:      path,
+		File:      fileName,
+		App:       app,
+		Encrypted: IsEncrypted(fileName),
+		FullName:  fmt.Sprintf("%s/%s", path, fileName),
 	}, nil
 }
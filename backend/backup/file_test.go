@@ -27,3 +27,10 @@ func TestParse_Wrong(t *testing.T) {
 	_, err := Parse("/data", "app-name-2001_020304-050607.tar.gz")
 	assert.NotNil(t, err)
 }
+
+func TestParse_Encrypted(t *testing.T) {
+	file, err := Parse("/data", "app-2001-020304-050607.tar.gz.age")
+	assert.Nil(t, err)
+	assert.Equal(t, file.App, "app")
+	assert.True(t, file.Encrypted)
+}
@@ -0,0 +1,51 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a directory stored in a backup under Name.
+type Source struct {
+	Name string
+	Dir  string
+}
+
+// Layout maps top level backup entries (current, common) to directories on disk.
+type Layout map[string]string
+
+// entryLayout is only used to check entry names, it does not point to real directories.
+var entryLayout = Layout{"current": "", "common": "", PlatformApp: ""}
+
+func TempLayout(dir string) Layout {
+	return Layout{
+		"current": filepath.Join(dir, "current"),
+		"common":  filepath.Join(dir, "common"),
+	}
+}
+
+func entryName(name string) string {
+	return filepath.Clean(strings.TrimPrefix(name, "./"))
+}
+
+func (l Layout) Target(name string) (string, error) {
+	clean := entryName(name)
+	if clean == "." || filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("invalid backup entry: %s", name)
+	}
+	parts := strings.SplitN(clean, string(os.PathSeparator), 2)
+	dir, ok := l[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("unexpected backup entry: %s", name)
+	}
+	if len(parts) == 1 {
+		return dir, nil
+	}
+	return filepath.Join(dir, parts[1]), nil
+}
+
+func partFile(file string) string {
+	return filepath.Join(filepath.Dir(file), fmt.Sprintf(".%s.part", filepath.Base(file)))
+}
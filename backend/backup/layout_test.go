@@ -0,0 +1,39 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayout_Target(t *testing.T) {
+	layout := Layout{"current": "/var/snap/app/x1", "common": "/var/snap/app/common"}
+
+	target, err := layout.Target("./current")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/snap/app/x1", target)
+
+	target, err = layout.Target("./common/dir/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/snap/app/common/dir/file", target)
+
+	target, err = layout.Target("current/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/snap/app/x1/file", target)
+}
+
+func TestLayout_Target_Invalid(t *testing.T) {
+	layout := TempLayout("/tmp/restore")
+
+	_, err := layout.Target("./current/../../etc/passwd")
+	assert.Error(t, err)
+
+	_, err = layout.Target("/etc/passwd")
+	assert.Error(t, err)
+
+	_, err = layout.Target("./other/file")
+	assert.Error(t, err)
+
+	_, err = layout.Target("./")
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"time"
+)
+
+// ManifestName is the archive entry written after all data entries.
+const ManifestName = "manifest.json"
+
+type Manifest struct {
+	App     string         `json:"app"`
+	Version string         `json:"version"`
+	Created time.Time      `json:"created"`
+	Files   []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+type Verification struct {
+	File     string    `json:"file"`
+	App      string    `json:"app"`
+	Version  string    `json:"version,omitempty"`
+	Created  time.Time `json:"created"`
+	Files    int       `json:"files"`
+	Size     uint64    `json:"size"`
+	Manifest bool      `json:"manifest"`
+}
+
+// Verify compares files found in an archive with the manifest.
+func (m Manifest) Verify(found map[string]ManifestFile) error {
+	if len(found) != len(m.Files) {
+		return fmt.Errorf("manifest lists %d files, archive has %d", len(m.Files), len(found))
+	}
+	for _, expected := range m.Files {
+		actual, ok := found[expected.Path]
+		if !ok {
+			return fmt.Errorf("%s is missing", expected.Path)
+		}
+		if actual.Size != expected.Size || actual.Sha256 != expected.Sha256 {
+			return fmt.Errorf("%s checksum mismatch", expected.Path)
+		}
+	}
+	return nil
+}
+
+func (m Manifest) Size() uint64 {
+	var size uint64
+	for _, file := range m.Files {
+		size += uint64(file.Size)
+	}
+	return size
+}
+
+func (m Manifest) Verification(file File) Verification {
+	return Verification{
+		File:     file.File,
+		App:      m.App,
+		Version:  m.Version,
+		Created:  m.Created,
+		Files:    len(m.Files),
+		Size:     m.Size(),
+		Manifest: true,
+	}
+}
+
+type hashWriter struct {
+	hash hash.Hash
+	size int64
+}
+
+func newHashWriter() *hashWriter {
+	return &hashWriter{hash: sha256.New()}
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return w.hash.Write(p)
+}
+
+func (w *hashWriter) File(path string) ManifestFile {
+	return ManifestFile{Path: path, Size: w.size, Sha256: hex.EncodeToString(w.hash.Sum(nil))}
+}
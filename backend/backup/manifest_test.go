@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestArchive(t *testing.T, file string, content string, manifest *Manifest) {
+	f, err := os.Create(file)
+	require.NoError(t, err)
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./common/data.file", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+	_, err = tw.Write([]byte(content))
+	require.NoError(t, err)
+	if manifest != nil {
+		require.NoError(t, writeManifest(tw, *manifest))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+}
+
+func TestCreateTarGz_Manifest(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "data.file"), []byte("data"), 0644))
+	file := filepath.Join(t.TempDir(), "app-2001-0203-040506.tar.gz")
+	created := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	err := createTarGz(file, []Source{{Name: "common", Dir: sourceDir}}, Encryption{}, Manifest{App: "app", Version: "42", Created: created}, io.Discard)
+	require.NoError(t, err)
+
+	manifest, found, err := verifyTarGz(file, false, Encryption{}, io.Discard)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "app", manifest.App)
+	assert.Equal(t, "42", manifest.Version)
+	assert.True(t, created.Equal(manifest.Created))
+	assert.Equal(t, []ManifestFile{{
+		Path:   "common/data.file",
+		Size:   4,
+		Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+	}}, manifest.Files)
+}
+
+func TestVerifyTarGz_ChecksumMismatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app-2001-0203-040506.tar.gz")
+	writeTestArchive(t, file, "corrupted", &Manifest{App: "app", Files: []ManifestFile{{
+		Path:   "common/data.file",
+		Size:   9,
+		Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+	}}})
+
+	_, _, err := verifyTarGz(file, false, Encryption{}, io.Discard)
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestVerifyTarGz_MissingFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app-2001-0203-040506.tar.gz")
+	writeTestArchive(t, file, "data", &Manifest{App: "app", Files: []ManifestFile{
+		{Path: "common/data.file", Size: 4, Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+		{Path: "common/other.file", Size: 4, Sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+	}})
+
+	_, _, err := verifyTarGz(file, false, Encryption{}, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestVerifyTarGz_NoManifest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app-2001-0203-040506.tar.gz")
+	writeTestArchive(t, file, "data", nil)
+
+	_, found, err := verifyTarGz(file, false, Encryption{}, io.Discard)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,8 @@
+package backup
+
+// Mode is how app backups are written, incremental takes precedence over streaming.
+// Streaming archives the app data in place while the app is stopped instead of copying it to a temp dir first.
+type Mode struct {
+	Incremental bool `json:"incremental"`
+	Streaming   bool `json:"streaming"`
+}
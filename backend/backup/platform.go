@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// PlatformApp is the pseudo app name of the device state which does not belong to any installed app.
+const PlatformApp = "platform"
+
+const (
+	platformDbFile       = "platform.db"
+	platformLdifFile     = "ldap.ldif"
+	platformAutheliaDir  = "authelia"
+	platformCertificates = "certificates"
+)
+
+// ErrPlatformUnencrypted is returned as the platform backup holds the config with its credentials, password hashes and private keys.
+var ErrPlatformUnencrypted = errors.New("platform backup contains credentials and keys, configure backup encryption first")
+
+type PlatformDb interface {
+	Snapshot(file string) error
+	Restore(file string) error
+}
+
+type Migrator interface {
+	Migrate() error
+}
+
+type LdapExport interface {
+	Export(file string) error
+}
+
+type LdapImport interface {
+	Import(file string) error
+}
+
+type AuthStorage interface {
+	Export(dir string) error
+	Import(dir string) error
+}
+
+type CertificateConfig interface {
+	SslCertificateFile() string
+	SslKeyFile() string
+	SslCaCertificateFile() string
+	SslCaKeyFile() string
+}
+
+type WebServer interface {
+	InitConfig() error
+	ReloadPublic() error
+}
+
+// Platform exports and imports config, users, OIDC clients, TOTP secrets and certificates.
+type Platform struct {
+	db           PlatformDb
+	migrator     Migrator
+	ldapExport   LdapExport
+	ldapImport   LdapImport
+	auth         AuthStorage
+	web          WebServer
+	certificates CertificateConfig
+	logger       *zap.Logger
+}
+
+func NewPlatform(
+	db PlatformDb,
+	migrator Migrator,
+	ldapExport LdapExport,
+	ldapImport LdapImport,
+	auth AuthStorage,
+	web WebServer,
+	certificates CertificateConfig,
+	logger *zap.Logger,
+) *Platform {
+	return &Platform{
+		db:           db,
+		migrator:     migrator,
+		ldapExport:   ldapExport,
+		ldapImport:   ldapImport,
+		auth:         auth,
+		web:          web,
+		certificates: certificates,
+		logger:       logger,
+	}
+}
+
+func (p *Platform) Export(dir string) error {
+	p.logger.Info("exporting platform config")
+	err := p.db.Snapshot(filepath.Join(dir, platformDbFile))
+	if err != nil {
+		return fmt.Errorf("config export: %w", err)
+	}
+	err = p.ldapExport.Export(filepath.Join(dir, platformLdifFile))
+	if err != nil {
+		return err
+	}
+	autheliaDir := filepath.Join(dir, platformAutheliaDir)
+	err = os.Mkdir(autheliaDir, 0700)
+	if err != nil {
+		return err
+	}
+	err = p.auth.Export(autheliaDir)
+	if err != nil {
+		return fmt.Errorf("authelia export: %w", err)
+	}
+	certificatesDir := filepath.Join(dir, platformCertificates)
+	err = os.Mkdir(certificatesDir, 0700)
+	if err != nil {
+		return err
+	}
+	for _, file := range p.certificateFiles() {
+		_, err = os.Stat(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		err = copyFileMode(file, filepath.Join(certificatesDir, filepath.Base(file)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import applies an export, the config goes first as the other parts are generated from it.
+func (p *Platform) Import(dir string) error {
+	p.logger.Info("importing platform config")
+	err := p.db.Restore(filepath.Join(dir, platformDbFile))
+	if err != nil {
+		return fmt.Errorf("config import: %w", err)
+	}
+	err = p.migrator.Migrate()
+	if err != nil {
+		return fmt.Errorf("config migration: %w", err)
+	}
+	for _, file := range p.certificateFiles() {
+		exported := filepath.Join(dir, platformCertificates, filepath.Base(file))
+		_, err = os.Stat(exported)
+		if os.IsNotExist(err) {
+			continue
+		}
+		err = copyFileMode(exported, file)
+		if err != nil {
+			return err
+		}
+	}
+	err = p.ldapImport.Import(filepath.Join(dir, platformLdifFile))
+	if err != nil {
+		return fmt.Errorf("ldap import: %w", err)
+	}
+	err = p.auth.Import(filepath.Join(dir, platformAutheliaDir))
+	if err != nil {
+		return fmt.Errorf("authelia import: %w", err)
+	}
+	err = p.web.InitConfig()
+	if err != nil {
+		return err
+	}
+	return p.web.ReloadPublic()
+}
+
+func (p *Platform) certificateFiles() []string {
+	return []string{
+		p.certificates.SslCertificateFile(),
+		p.certificates.SslKeyFile(),
+		p.certificates.SslCaCertificateFile(),
+		p.certificates.SslCaKeyFile(),
+	}
+}
+
+func copyFileMode(from string, to string) error {
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	err = copyFile(from, to)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(to, info.Mode().Perm())
+}
+
+func (b *Backup) createPlatform() error {
+	if !b.encryption().Enabled() {
+		return ErrPlatformUnencrypted
+	}
+	now := b.timeProvider.Now()
+	file := b.fileName(PlatformApp, now, b.archiveExt())
+	b.logger.Info("Running platform backup create", zap.String("file", file))
+	version := ""
+	snap, err := b.snapServer.FindInstalled(PlatformApp)
+	if err != nil {
+		return err
+	}
+	if snap != nil {
+		version = snap.Version
+	}
+	tempDir, err := os.MkdirTemp("", "platform-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	b.phase(PhaseExport)
+	err = b.platform.Export(tempDir)
+	if err != nil {
+		return err
+	}
+	manifest := Manifest{App: PlatformApp, Version: version, Created: now}
+	err = createTarGz(file, []Source{{Name: PlatformApp, Dir: tempDir}}, b.encryption(), manifest, b.counter(PhaseCompress, b.size(tempDir)))
+	if err != nil {
+		return err
+	}
+	return b.upload(file)
+}
+
+func (b *Backup) restorePlatform(file File, keys Encryption) error {
+	tempDir, err := os.MkdirTemp("", "platform-restore")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	size, err := b.archiveSize(file)
+	if err != nil {
+		return err
+	}
+	err = b.extract(file, Layout{PlatformApp: tempDir}, keys, b.counter(PhaseExtract, size))
+	if err != nil {
+		return err
+	}
+	b.phase(PhaseImport)
+	return b.platform.Import(tempDir)
+}
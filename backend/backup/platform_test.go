@@ -0,0 +1,207 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/cli"
+	"github.com/syncloud/platform/log"
+)
+
+type PlatformDbStub struct {
+	restored string
+}
+
+func (d *PlatformDbStub) Snapshot(file string) error {
+	return os.WriteFile(file, []byte("db"), 0600)
+}
+
+func (d *PlatformDbStub) Restore(file string) error {
+	content, err := os.ReadFile(file)
+	d.restored = string(content)
+	return err
+}
+
+type MigratorStub struct {
+	migrated bool
+}
+
+func (m *MigratorStub) Migrate() error {
+	m.migrated = true
+	return nil
+}
+
+type LdapStub struct {
+	imported string
+}
+
+func (l *LdapStub) Export(file string) error {
+	return os.WriteFile(file, []byte("dn: dc=syncloud,dc=org"), 0600)
+}
+
+func (l *LdapStub) Import(file string) error {
+	content, err := os.ReadFile(file)
+	l.imported = string(content)
+	return err
+}
+
+type AuthStorageStub struct {
+	imported string
+}
+
+func (a *AuthStorageStub) Export(dir string) error {
+	return os.WriteFile(filepath.Join(dir, "key"), []byte("key"), 0600)
+}
+
+func (a *AuthStorageStub) Import(dir string) error {
+	content, err := os.ReadFile(filepath.Join(dir, "key"))
+	a.imported = string(content)
+	return err
+}
+
+type WebServerStub struct {
+	reloaded bool
+}
+
+func (w *WebServerStub) InitConfig() error {
+	return nil
+}
+
+func (w *WebServerStub) ReloadPublic() error {
+	w.reloaded = true
+	return nil
+}
+
+type CertificateConfigStub struct {
+	dir string
+}
+
+func (c *CertificateConfigStub) SslCertificateFile() string {
+	return filepath.Join(c.dir, "syncloud.crt")
+}
+
+func (c *CertificateConfigStub) SslKeyFile() string {
+	return filepath.Join(c.dir, "syncloud.key")
+}
+
+func (c *CertificateConfigStub) SslCaCertificateFile() string {
+	return filepath.Join(c.dir, "syncloud.ca.crt")
+}
+
+func (c *CertificateConfigStub) SslCaKeyFile() string {
+	return filepath.Join(c.dir, "syncloud.ca.key")
+}
+
+func TestPlatform_ExportImport(t *testing.T) {
+	certificates := &CertificateConfigStub{dir: t.TempDir()}
+	certFile := certificates.SslCertificateFile()
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0644))
+	missingCertFile := certificates.SslKeyFile()
+	db := &PlatformDbStub{}
+	migrator := &MigratorStub{}
+	ldap := &LdapStub{}
+	auth := &AuthStorageStub{}
+	web := &WebServerStub{}
+	platform := NewPlatform(db, migrator, ldap, ldap, auth, web, certificates, log.Default())
+
+	dir := t.TempDir()
+	err := platform.Export(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, []byte("new"), 0600))
+
+	err = platform.Import(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "db", db.restored)
+	assert.True(t, migrator.migrated)
+	assert.Equal(t, "dn: dc=syncloud,dc=org", ldap.imported)
+	assert.Equal(t, "key", auth.imported)
+	assert.True(t, web.reloaded)
+	content, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, "cert", string(content))
+	info, err := os.Stat(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	assert.NoFileExists(t, missingCertFile)
+}
+
+func TestBackup_Platform(t *testing.T) {
+	backupDir := t.TempDir()
+	platform := &PlatformStateStub{}
+	snapService := &SnapServiceStub{}
+	backup := New(
+		backupDir,
+		t.TempDir(),
+		cli.New(log.Default()),
+		&DiskUsageStub{100},
+		snapService,
+		&SnapInfoStub{},
+		&UserConfigStub{encryption: Encryption{Passphrase: "secret"}},
+		&TargetConfigStub{},
+		platform,
+		&ProviderStub{},
+		&ProgressStub{},
+		log.Default())
+
+	err := backup.Create(PlatformApp)
+	require.NoError(t, err)
+	backups, err := backup.List()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(backups))
+	assert.Equal(t, PlatformApp, backups[0].App)
+
+	verification, err := backup.Verify(backups[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, 1, verification.Files)
+
+	err = backup.Restore(backups[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, "config", platform.imported)
+	assert.Equal(t, 0, snapService.stopped)
+}
+
+func TestBackup_Platform_Unencrypted(t *testing.T) {
+	backupDir := t.TempDir()
+	backup := New(backupDir, t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{},
+		&UserConfigStub{}, &TargetConfigStub{}, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+
+	err := backup.Create(PlatformApp)
+	assert.ErrorIs(t, err, ErrPlatformUnencrypted)
+	backups, err := backup.List()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestBackup_Platform_RestoreWithKeys(t *testing.T) {
+	backupDir := t.TempDir()
+	userConfig := &UserConfigStub{encryption: Encryption{Passphrase: "secret"}}
+	source := New(backupDir, t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{},
+		userConfig, &TargetConfigStub{}, &PlatformStateStub{}, &ProviderStub{}, &ProgressStub{}, log.Default())
+	require.NoError(t, source.Create(PlatformApp))
+	backups, err := source.List()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(backups))
+	archive, err := os.Open(filepath.Join(backupDir, backups[0].File))
+	require.NoError(t, err)
+	defer archive.Close()
+
+	platform := &PlatformStateStub{}
+	fresh := New(t.TempDir(), t.TempDir(), cli.New(log.Default()), &DiskUsageStub{100}, &SnapServiceStub{}, &SnapInfoStub{},
+		&UserConfigStub{}, &TargetConfigStub{}, platform, &ProviderStub{}, &ProgressStub{}, log.Default())
+	_, err = fresh.UploadWithKeys(backups[0].File, archive, Encryption{})
+	assert.Error(t, err)
+	_, err = archive.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	_, err = fresh.UploadWithKeys(backups[0].File, archive, Encryption{Passphrase: "secret"})
+	require.NoError(t, err)
+	err = fresh.RestoreWithKeys(backups[0].File, Encryption{Passphrase: "wrong"})
+	assert.Error(t, err)
+	err = fresh.RestoreWithKeys(backups[0].File, Encryption{Passphrase: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "config", platform.imported)
+}
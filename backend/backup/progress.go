@@ -0,0 +1,35 @@
+package backup
+
+const (
+	PhaseDownload = "download"
+	PhaseVerify   = "verify"
+	PhasePreStop  = "pre-stop"
+	PhaseStop     = "stop"
+	PhaseCopy     = "copy"
+	PhaseCompress = "compress"
+	PhaseExtract  = "extract"
+	PhaseSwap     = "swap"
+	PhaseMigrate  = "migrate"
+	PhaseExport   = "export"
+	PhaseImport   = "import"
+	PhaseStart    = "start"
+	PhaseUpload   = "upload"
+)
+
+type Progress interface {
+	Phase(phase string)
+	Bytes(done int64, total int64)
+}
+
+// progressCounter reports bytes written to it as progress of the current phase.
+type progressCounter struct {
+	progress Progress
+	done     int64
+	total    int64
+}
+
+func (c *progressCounter) Write(p []byte) (int, error) {
+	c.done += int64(len(p))
+	c.progress.Bytes(c.done, c.total)
+	return len(p), nil
+}
@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"errors"
+	"os"
+)
+
+const (
+	StagingSuffix  = ".restore"
+	PreviousSuffix = ".previous"
+)
+
+// DirSwap replaces live dirs with staged copies, the previous data is kept next to them until Commit.
+type DirSwap struct {
+	dirs    []string
+	swapped []string
+}
+
+func NewDirSwap(dirs ...string) *DirSwap {
+	return &DirSwap{dirs: dirs}
+}
+
+func (s *DirSwap) Staging(dir string) string {
+	return dir + StagingSuffix
+}
+
+func (s *DirSwap) previous(dir string) string {
+	return dir + PreviousSuffix
+}
+
+// Prepare recovers dirs left by an interrupted swap and creates empty staging dirs.
+func (s *DirSwap) Prepare() error {
+	for _, dir := range s.dirs {
+		previous := s.previous(dir)
+		if _, err := os.Lstat(previous); err == nil {
+			if _, err := os.Lstat(dir); os.IsNotExist(err) {
+				err = os.Rename(previous, dir)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, leftover := range []string{previous, s.Staging(dir)} {
+			err := os.RemoveAll(leftover)
+			if err != nil {
+				return err
+			}
+		}
+		err := os.Mkdir(s.Staging(dir), 0755)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DirSwap) Apply() error {
+	for _, dir := range s.dirs {
+		s.swapped = append(s.swapped, dir)
+		err := os.Rename(dir, s.previous(dir))
+		if err != nil {
+			return err
+		}
+		err = os.Rename(s.Staging(dir), dir)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback puts the previous data back and removes staged data.
+func (s *DirSwap) Rollback() error {
+	var errs []error
+	for i := len(s.swapped) - 1; i >= 0; i-- {
+		dir := s.swapped[i]
+		previous := s.previous(dir)
+		if _, err := os.Lstat(previous); err != nil {
+			continue
+		}
+		err := os.RemoveAll(dir)
+		if err == nil {
+			err = os.Rename(previous, dir)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.swapped = nil
+	return errors.Join(append(errs, s.Cleanup())...)
+}
+
+func (s *DirSwap) Commit() error {
+	var errs []error
+	for _, dir := range s.dirs {
+		errs = append(errs, os.RemoveAll(s.previous(dir)))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *DirSwap) Cleanup() error {
+	var errs []error
+	for _, dir := range s.dirs {
+		errs = append(errs, os.RemoveAll(s.Staging(dir)))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirSwap_ApplyCommit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "common")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte("old"), 0644))
+	swap := NewDirSwap(dir)
+	require.NoError(t, swap.Prepare())
+	require.NoError(t, os.WriteFile(filepath.Join(swap.Staging(dir), "file"), []byte("new"), 0644))
+
+	require.NoError(t, swap.Apply())
+	require.NoError(t, swap.Commit())
+
+	content, err := os.ReadFile(filepath.Join(dir, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+	_, err = os.Stat(dir + PreviousSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDirSwap_Rollback(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "common")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte("old"), 0644))
+	swap := NewDirSwap(dir)
+	require.NoError(t, swap.Prepare())
+	require.NoError(t, os.WriteFile(filepath.Join(swap.Staging(dir), "file"), []byte("new"), 0644))
+	require.NoError(t, swap.Apply())
+
+	require.NoError(t, swap.Rollback())
+
+	content, err := os.ReadFile(filepath.Join(dir, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+	_, err = os.Stat(dir + PreviousSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDirSwap_Prepare_RecoversInterruptedSwap(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "common")
+	require.NoError(t, os.Mkdir(dir+PreviousSuffix, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir+PreviousSuffix, "file"), []byte("old"), 0644))
+	require.NoError(t, os.Mkdir(dir+StagingSuffix, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir+StagingSuffix, "file"), []byte("partial"), 0644))
+
+	require.NoError(t, NewDirSwap(dir).Prepare())
+
+	content, err := os.ReadFile(filepath.Join(dir, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+	entries, err := os.ReadDir(dir + StagingSuffix)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/syncloud/platform/config"
+)
+
+const fileTimeFormat = "2006-0102-150405"
+
+type RetentionConfig struct {
+	Global config.BackupRetention            `json:"global"`
+	Apps   map[string]config.BackupRetention `json:"apps"`
+}
+
+type AppRetention struct {
+	App string `json:"app,omitempty"`
+	config.BackupRetention
+}
+
+type RetentionPlan struct {
+	Keep   []File `json:"keep"`
+	Remove []File `json:"remove"`
+}
+
+func ValidateRetention(retention config.BackupRetention) error {
+	if retention.KeepLast < 0 || retention.KeepDaily < 0 || retention.KeepWeekly < 0 || retention.KeepMonthly < 0 {
+		return fmt.Errorf("retention values should not be negative")
+	}
+	return nil
+}
+
+func retentionEnabled(retention config.BackupRetention) bool {
+	return retention.KeepLast > 0 || retention.KeepDaily > 0 || retention.KeepWeekly > 0 || retention.KeepMonthly > 0
+}
+
+// FileTime returns the creation time encoded in a backup file name.
+func FileTime(file File) (time.Time, error) {
+	start := len(file.App) + 1
+	end := start + len(fileTimeFormat)
+	if len(file.File) < end {
+		return time.Time{}, fmt.Errorf("no time in backup file name: %s", file.File)
+	}
+	return time.Parse(fileTimeFormat, file.File[start:end])
+}
+
+// PlanRetention splits backups of a single app into the ones to keep and to remove.
+// A backup is kept when any of the rules selects it, backups without a parsable time are always kept.
+func PlanRetention(retention config.BackupRetention, files []File) RetentionPlan {
+	plan := RetentionPlan{Keep: []File{}, Remove: []File{}}
+	if !retentionEnabled(retention) {
+		plan.Keep = append(plan.Keep, files...)
+		return plan
+	}
+
+	type dated struct {
+		file File
+		time time.Time
+	}
+	var sorted []dated
+	for _, file := range files {
+		created, err := FileTime(file)
+		if err != nil {
+			plan.Keep = append(plan.Keep, file)
+			continue
+		}
+		sorted = append(sorted, dated{file: file, time: created})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].time.After(sorted[j].time)
+	})
+
+	keep := make([]bool, len(sorted))
+	for i := 0; i < len(sorted) && i < retention.KeepLast; i++ {
+		keep[i] = true
+	}
+	buckets := []struct {
+		count int
+		key   func(t time.Time) string
+	}{
+		{retention.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{retention.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{retention.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, bucket := range buckets {
+		last := ""
+		kept := 0
+		for i, entry := range sorted {
+			if kept >= bucket.count {
+				break
+			}
+			key := bucket.key(entry.time)
+			if key == last {
+				continue
+			}
+			last = key
+			keep[i] = true
+			kept++
+		}
+	}
+
+	for i, entry := range sorted {
+		if keep[i] {
+			plan.Keep = append(plan.Keep, entry.file)
+		} else {
+			plan.Remove = append(plan.Remove, entry.file)
+		}
+	}
+	return plan
+}
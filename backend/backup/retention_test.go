@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/config"
+)
+
+func files(t *testing.T, names ...string) []File {
+	var result []File
+	for _, name := range names {
+		file, err := Parse("/data", name)
+		assert.NoError(t, err)
+		result = append(result, file)
+	}
+	return result
+}
+
+func names(files []File) []string {
+	result := []string{}
+	for _, file := range files {
+		result = append(result, file.File)
+	}
+	return result
+}
+
+func TestFileTime(t *testing.T) {
+	created, err := FileTime(files(t, "my-app-2001-0203-040506.tar.gz.age")[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "2001-02-03 04:05:06", created.Format("2006-01-02 15:04:05"))
+}
+
+func TestPlanRetention_Disabled(t *testing.T) {
+	plan := PlanRetention(config.BackupRetention{}, files(t, "app-2001-0203-040506.tar.gz", "app-2001-0204-040506.tar.gz"))
+	assert.Len(t, plan.Keep, 2)
+	assert.Empty(t, plan.Remove)
+}
+
+func TestPlanRetention_KeepLast(t *testing.T) {
+	plan := PlanRetention(config.BackupRetention{KeepLast: 2}, files(t,
+		"app-2001-0203-040506.tar.gz",
+		"app-2001-0205-040506.tar.gz",
+		"app-2001-0204-040506.tar.gz",
+	))
+	assert.Equal(t, []string{"app-2001-0205-040506.tar.gz", "app-2001-0204-040506.tar.gz"}, names(plan.Keep))
+	assert.Equal(t, []string{"app-2001-0203-040506.tar.gz"}, names(plan.Remove))
+}
+
+func TestPlanRetention_Daily(t *testing.T) {
+	plan := PlanRetention(config.BackupRetention{KeepDaily: 2}, files(t,
+		"app-2001-0203-010000.tar.gz",
+		"app-2001-0203-020000.tar.gz",
+		"app-2001-0204-010000.tar.gz",
+		"app-2001-0204-020000.tar.gz",
+		"app-2001-0205-010000.tar.gz",
+	))
+	assert.Equal(t, []string{"app-2001-0205-010000.tar.gz", "app-2001-0204-020000.tar.gz"}, names(plan.Keep))
+	assert.Len(t, plan.Remove, 3)
+}
+
+func TestPlanRetention_WeeklyMonthly(t *testing.T) {
+	plan := PlanRetention(config.BackupRetention{KeepWeekly: 2, KeepMonthly: 3}, files(t,
+		"app-2001-0101-010000.tar.gz",
+		"app-2001-0201-010000.tar.gz",
+		"app-2001-0205-010000.tar.gz",
+		"app-2001-0301-010000.tar.gz",
+		"app-2001-0312-010000.tar.gz",
+		"app-2001-0313-010000.tar.gz",
+		"app-2001-0314-010000.tar.gz",
+	))
+	assert.Equal(t, []string{
+		"app-2001-0314-010000.tar.gz",
+		"app-2001-0301-010000.tar.gz",
+		"app-2001-0205-010000.tar.gz",
+		"app-2001-0101-010000.tar.gz",
+	}, names(plan.Keep))
+	assert.Equal(t, []string{
+		"app-2001-0313-010000.tar.gz",
+		"app-2001-0312-010000.tar.gz",
+		"app-2001-0201-010000.tar.gz",
+	}, names(plan.Remove))
+}
+
+func TestPlanRetention_KeepsUnparsable(t *testing.T) {
+	plan := PlanRetention(config.BackupRetention{KeepLast: 1}, []File{{File: "app-x.tar.gz", App: "app"}})
+	assert.Equal(t, []string{"app-x.tar.gz"}, names(plan.Keep))
+}
@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const SnapshotExt = ".snapshot"
+
+type Snapshot struct {
+	App     string          `json:"app"`
+	Version string          `json:"version,omitempty"`
+	Created time.Time       `json:"created"`
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+type SnapshotEntry struct {
+	Path   string      `json:"path"`
+	Mode   os.FileMode `json:"mode"`
+	Uid    int         `json:"uid"`
+	Gid    int         `json:"gid"`
+	Size   int64       `json:"size,omitempty"`
+	Link   string      `json:"link,omitempty"`
+	Chunks []string    `json:"chunks,omitempty"`
+	Sha256 string      `json:"sha256,omitempty"`
+}
+
+func (s Snapshot) Size() uint64 {
+	var size uint64
+	for _, entry := range s.Entries {
+		size += uint64(entry.Size)
+	}
+	return size
+}
+
+func (s Snapshot) Chunks() []string {
+	var chunks []string
+	for _, entry := range s.Entries {
+		chunks = append(chunks, entry.Chunks...)
+	}
+	return chunks
+}
+
+func (s Snapshot) Verification(file File) Verification {
+	files := 0
+	for _, entry := range s.Entries {
+		if entry.Mode.IsRegular() {
+			files++
+		}
+	}
+	return Verification{
+		File:     file.File,
+		App:      s.App,
+		Version:  s.Version,
+		Created:  s.Created,
+		Files:    files,
+		Size:     s.Size(),
+		Manifest: true,
+	}
+}
+
+func IsSnapshot(fileName string) bool {
+	return strings.HasSuffix(fileName, SnapshotExt)
+}
+
+func (s *ChunkStore) WriteSnapshot(outputFile string, manifest Manifest, sources []Source, progress io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := Snapshot{App: manifest.App, Version: manifest.Version, Created: manifest.Created}
+	for _, source := range sources {
+		entries, err := s.storeDir(source, progress)
+		if err != nil {
+			return err
+		}
+		snapshot.Entries = append(snapshot.Entries, entries...)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	part := partFile(outputFile)
+	err = os.WriteFile(part, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(part, outputFile)
+}
+
+func (s *ChunkStore) storeDir(source Source, progress io.Writer) ([]SnapshotEntry, error) {
+	var entries []SnapshotEntry
+	err := filepath.Walk(source.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSocket != 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(source.Dir, path)
+		if err != nil {
+			return err
+		}
+		entry := SnapshotEntry{
+			Path: filepath.Join(source.Name, rel),
+			Mode: info.Mode(),
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			entry.Uid = int(stat.Uid)
+			entry.Gid = int(stat.Gid)
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			entry.Link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			entry.Size = info.Size()
+			entry.Chunks, entry.Sha256, err = s.storeFile(path, progress)
+			if err != nil {
+				return err
+			}
+		case !info.IsDir():
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (s *ChunkStore) storeFile(path string, progress io.Writer) ([]string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	var chunks []string
+	fileHash := newHashWriter()
+	buf := make([]byte, ChunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			hash, putErr := s.Put(buf[:n])
+			if putErr != nil {
+				return nil, "", putErr
+			}
+			chunks = append(chunks, hash)
+			_, _ = fileHash.Write(buf[:n])
+			_, _ = progress.Write(buf[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return chunks, fileHash.File(path).Sha256, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+func ReadSnapshot(file string) (Snapshot, error) {
+	var snapshot Snapshot
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot %s: %w", file, err)
+	}
+	return snapshot, nil
+}
+
+// VerifySnapshot reads every chunk of a snapshot and compares file checksums.
+func (s *ChunkStore) VerifySnapshot(file string, progress io.Writer) (Snapshot, error) {
+	snapshot, err := ReadSnapshot(file)
+	if err != nil {
+		return snapshot, err
+	}
+	for _, entry := range snapshot.Entries {
+		_, err = entryLayout.Target(entry.Path)
+		if err != nil {
+			return snapshot, err
+		}
+		if !entry.Mode.IsRegular() {
+			continue
+		}
+		fileHash := newHashWriter()
+		for _, hash := range entry.Chunks {
+			data, err := s.Get(hash)
+			if err != nil {
+				return snapshot, fmt.Errorf("%s: %w", entry.Path, err)
+			}
+			_, _ = fileHash.Write(data)
+			_, _ = progress.Write(data)
+		}
+		actual := fileHash.File(entry.Path)
+		if actual.Size != entry.Size || entry.Sha256 != "" && actual.Sha256 != entry.Sha256 {
+			return snapshot, fmt.Errorf("%s checksum mismatch", entry.Path)
+		}
+	}
+	return snapshot, nil
+}
+
+func (s *ChunkStore) ExtractSnapshot(file string, layout Layout, progress io.Writer) error {
+	snapshot, err := ReadSnapshot(file)
+	if err != nil {
+		return err
+	}
+	for _, entry := range snapshot.Entries {
+		target, err := layout.Target(entry.Path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.Mode.IsDir():
+			err = os.MkdirAll(target, entry.Mode.Perm())
+		case entry.Mode&os.ModeSymlink != 0:
+			err = os.Symlink(entry.Link, target)
+		default:
+			err = s.extractFile(target, entry, progress)
+		}
+		if err != nil {
+			return err
+		}
+		_ = os.Lchown(target, entry.Uid, entry.Gid)
+	}
+	return nil
+}
+
+func (s *ChunkStore) extractFile(target string, entry SnapshotEntry, progress io.Writer) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, hash := range entry.Chunks {
+		data, err := s.Get(hash)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(data)
+		if err != nil {
+			return err
+		}
+		_, _ = progress.Write(data)
+	}
+	return nil
+}
+
+// Prune removes chunks which are not referenced by any snapshot in dir.
+func (s *ChunkStore) Prune(dir string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() || !IsSnapshot(file.Name()) {
+			continue
+		}
+		snapshot, err := ReadSnapshot(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return 0, err
+		}
+		for _, hash := range snapshot.Chunks() {
+			referenced[hash] = true
+		}
+	}
+	return s.Collect(referenced)
+}
@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countChunks(t *testing.T, dir string) int {
+	count := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func TestChunkStore_Snapshot_Deduplicates(t *testing.T) {
+	backupDir := t.TempDir()
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.file"), []byte("same"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "b.file"), []byte("same"), 0644))
+	store := NewChunkStore(filepath.Join(backupDir, ChunksDir))
+	sources := []Source{{Name: "common", Dir: sourceDir}}
+
+	err := store.WriteSnapshot(filepath.Join(backupDir, "app-2001-0203-040506"+SnapshotExt), Manifest{App: "app", Created: time.Now()}, sources, io.Discard)
+	require.NoError(t, err)
+	err = store.WriteSnapshot(filepath.Join(backupDir, "app-2001-0204-040506"+SnapshotExt), Manifest{App: "app", Created: time.Now()}, sources, io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, countChunks(t, filepath.Join(backupDir, ChunksDir)))
+}
+
+func TestChunkStore_Snapshot_Extract(t *testing.T) {
+	backupDir := t.TempDir()
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "sub"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "sub", "data.file"), []byte("data"), 0640))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "empty.file"), []byte{}, 0644))
+	require.NoError(t, os.Symlink("sub/data.file", filepath.Join(sourceDir, "link")))
+	store := NewChunkStore(filepath.Join(backupDir, ChunksDir))
+	file := filepath.Join(backupDir, "app-2001-0203-040506"+SnapshotExt)
+	err := store.WriteSnapshot(file, Manifest{App: "app", Created: time.Now()}, []Source{{Name: "current", Dir: sourceDir}}, io.Discard)
+	require.NoError(t, err)
+
+	destDir := t.TempDir()
+	err = store.ExtractSnapshot(file, TempLayout(destDir), io.Discard)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(destDir, "current", "sub", "data.file"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+	info, err := os.Stat(filepath.Join(destDir, "current", "sub", "data.file"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	content, err = os.ReadFile(filepath.Join(destDir, "current", "empty.file"))
+	require.NoError(t, err)
+	assert.Empty(t, content)
+	link, err := os.Readlink(filepath.Join(destDir, "current", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/data.file", link)
+}
+
+func TestChunkStore_Prune(t *testing.T) {
+	backupDir := t.TempDir()
+	sourceDir := t.TempDir()
+	store := NewChunkStore(filepath.Join(backupDir, ChunksDir))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.file"), []byte("v1"), 0644))
+	first := filepath.Join(backupDir, "app-2001-0203-040506"+SnapshotExt)
+	require.NoError(t, store.WriteSnapshot(first, Manifest{App: "app", Created: time.Now()}, []Source{{Name: "common", Dir: sourceDir}}, io.Discard))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.file"), []byte("v2"), 0644))
+	second := filepath.Join(backupDir, "app-2001-0204-040506"+SnapshotExt)
+	require.NoError(t, store.WriteSnapshot(second, Manifest{App: "app", Created: time.Now()}, []Source{{Name: "common", Dir: sourceDir}}, io.Discard))
+	assert.Equal(t, 2, countChunks(t, filepath.Join(backupDir, ChunksDir)))
+
+	require.NoError(t, os.Remove(first))
+	removed, err := store.Prune(backupDir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	destDir := t.TempDir()
+	require.NoError(t, store.ExtractSnapshot(second, TempLayout(destDir), io.Discard))
+	content, err := os.ReadFile(filepath.Join(destDir, "common", "a.file"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+}
+
+func TestChunkStore_Prune_KeepsChunksInProgress(t *testing.T) {
+	backupDir := t.TempDir()
+	store := NewChunkStore(filepath.Join(backupDir, ChunksDir))
+	hash, err := store.Put([]byte("unreferenced"))
+	require.NoError(t, err)
+	tmp := filepath.Join(filepath.Dir(store.path(hash)), hash+chunkTmp+"123")
+	require.NoError(t, os.WriteFile(tmp, []byte("partial"), 0600))
+
+	store.mu.RLock()
+	pruned := make(chan int)
+	go func() {
+		removed, _ := store.Prune(backupDir)
+		pruned <- removed
+	}()
+	select {
+	case <-pruned:
+		t.Fatal("prune ran while a snapshot was being written")
+	case <-time.After(100 * time.Millisecond):
+	}
+	store.mu.RUnlock()
+
+	assert.Equal(t, 1, <-pruned)
+	assert.FileExists(t, tmp)
+	assert.False(t, store.Has(hash))
+}
+
+func TestChunkStore_VerifySnapshot(t *testing.T) {
+	backupDir := t.TempDir()
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.file"), []byte("data"), 0644))
+	store := NewChunkStore(filepath.Join(backupDir, ChunksDir))
+	file := filepath.Join(backupDir, "app-2001-0203-040506"+SnapshotExt)
+	require.NoError(t, store.WriteSnapshot(file, Manifest{App: "app", Version: "1"}, []Source{{Name: "common", Dir: sourceDir}}, io.Discard))
+
+	snapshot, err := store.VerifySnapshot(file, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, "1", snapshot.Version)
+	assert.Equal(t, "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", snapshot.Entries[1].Sha256)
+
+	require.NoError(t, os.Remove(store.path(snapshot.Entries[1].Chunks[0])))
+	_, err = store.VerifySnapshot(file, io.Discard)
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/syncloud/platform/config"
+)
+
+const (
+	TargetLocal  = "local"
+	TargetS3     = "s3"
+	TargetSftp   = "sftp"
+	TargetWebDav = "webdav"
+)
+
+// ErrIncrementalRemote is returned as incremental backups are kept in the local chunk store only.
+var ErrIncrementalRemote = errors.New("incremental backups cannot be uploaded to remote targets, disable incremental backups or remove the targets")
+
+// Target is a place where backup archives are stored next to the local backup dir.
+type Target interface {
+	Name() string
+	Upload(localFile string, name string) error
+	List() ([]string, error)
+	Download(name string, localFile string) error
+	Delete(name string) error
+}
+
+type TargetConfig interface {
+	Add(target config.BackupTarget) error
+	Remove(name string) error
+	List() ([]config.BackupTarget, error)
+}
+
+func NewTarget(target config.BackupTarget) (Target, error) {
+	switch target.Type {
+	case TargetLocal:
+		return NewLocalTarget(target.Name, target.Path), nil
+	case TargetS3:
+		return NewS3Target(target)
+	case TargetSftp:
+		return NewSftpTarget(target)
+	case TargetWebDav:
+		return NewWebDavTarget(target)
+	default:
+		return nil, fmt.Errorf("unsupported backup target type: %s", target.Type)
+	}
+}
+
+func ValidateTarget(target config.BackupTarget) error {
+	if target.Name == "" {
+		return fmt.Errorf("target name is required")
+	}
+	_, err := NewTarget(target)
+	return err
+}
+
+// download fetches a remote file into localFile through a hidden part file.
+func download(target Target, name string, localFile string) error {
+	part := partFile(localFile)
+	err := target.Download(name, part)
+	if err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	return os.Rename(part, localFile)
+}
+
+func writeFile(file string, body io.Reader) error {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, body)
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+type httpStatusError struct {
+	status  int
+	message string
+}
+
+func (e *httpStatusError) Error() string {
+	return e.message
+}
+
+func newHttpStatusError(protocol string, req *http.Request, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &httpStatusError{
+		status:  resp.StatusCode,
+		message: strings.TrimSpace(fmt.Sprintf("%s %s %s: %s %s", protocol, req.Method, req.URL.Path, resp.Status, body)),
+	}
+}
+
+func isHttpStatus(err error, status int) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.status == status
+}
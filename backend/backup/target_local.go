@@ -0,0 +1,70 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LocalTarget struct {
+	name string
+	dir  string
+}
+
+func NewLocalTarget(name string, dir string) *LocalTarget {
+	return &LocalTarget{name: name, dir: dir}
+}
+
+func (t *LocalTarget) Name() string {
+	return t.name
+}
+
+func (t *LocalTarget) Upload(localFile string, name string) error {
+	err := os.MkdirAll(t.dir, 0700)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(t.dir, filepath.Base(name))
+	part := partFile(target)
+	err = copyFile(localFile, part)
+	if err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	return os.Rename(part, target)
+}
+
+func (t *LocalTarget) List() ([]string, error) {
+	files, err := os.ReadDir(t.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	return names, nil
+}
+
+func (t *LocalTarget) Download(name string, localFile string) error {
+	return copyFile(filepath.Join(t.dir, filepath.Base(name)), localFile)
+}
+
+func (t *LocalTarget) Delete(name string) error {
+	return os.Remove(filepath.Join(t.dir, filepath.Base(name)))
+}
+
+func copyFile(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	return writeFile(to, source)
+}
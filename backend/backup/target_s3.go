@@ -0,0 +1,265 @@
+package backup
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syncloud/platform/config"
+)
+
+const (
+	s3DefaultRegion   = "us-east-1"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Target stores backups in an S3 compatible bucket using path style requests.
+// Path is "bucket/prefix", username and password are the access and secret keys.
+type S3Target struct {
+	name      string
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func NewS3Target(target config.BackupTarget) (*S3Target, error) {
+	endpoint, err := url.Parse(target.Url)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", target.Url)
+	}
+	bucket, prefix, _ := strings.Cut(strings.Trim(target.Path, "/"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	region := target.Region
+	if region == "" {
+		region = s3DefaultRegion
+	}
+	return &S3Target{
+		name:      target.Name,
+		endpoint:  endpoint,
+		bucket:    bucket,
+		prefix:    prefix,
+		region:    region,
+		accessKey: target.Username,
+		secretKey: target.Password,
+		client:    http.DefaultClient,
+		now:       time.Now,
+	}, nil
+}
+
+func (t *S3Target) Name() string {
+	return t.name
+}
+
+func (t *S3Target) key(name string) string {
+	return path.Join(t.prefix, path.Base(name))
+}
+
+func (t *S3Target) Upload(localFile string, name string) error {
+	file, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := t.request(http.MethodPut, t.key(name), nil, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	_, err = t.do(req, nil)
+	return err
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (t *S3Target) List() ([]string, error) {
+	prefix := ""
+	if t.prefix != "" {
+		prefix = t.prefix + "/"
+	}
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := t.request(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		_, err = t.do(req, func(body io.Reader) error {
+			return xml.NewDecoder(body).Decode(&result)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			name := strings.TrimPrefix(content.Key, prefix)
+			if name == "" || strings.HasPrefix(name, ".") {
+				continue
+			}
+			names = append(names, name)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (t *S3Target) Download(name string, localFile string) error {
+	req, err := t.request(http.MethodGet, t.key(name), nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = t.do(req, func(body io.Reader) error {
+		return writeFile(localFile, body)
+	})
+	return err
+}
+
+func (t *S3Target) Delete(name string) error {
+	req, err := t.request(http.MethodDelete, t.key(name), nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = t.do(req, nil)
+	return err
+}
+
+func (t *S3Target) request(method string, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *t.endpoint
+	u.Path = path.Join("/", t.endpoint.Path, t.bucket, key)
+	u.RawPath = canonicalPath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	t.sign(req, t.now().UTC())
+	return req, nil
+}
+
+func (t *S3Target) do(req *http.Request, handle func(body io.Reader) error) (*http.Response, error) {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, newHttpStatusError("s3", req, resp)
+	}
+	if handle != nil {
+		return resp, handle(resp.Body)
+	}
+	return resp, nil
+}
+
+// sign adds AWS signature version 4 headers, the payload is not signed so that uploads can be streamed.
+func (t *S3Target) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + s3UnsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+	scope := strings.Join([]string{date, t.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSha256(canonicalRequest)}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+t.secretKey), date)
+	key = hmacSha256(key, t.region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query url.Values) string {
+	var keys []string
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func canonicalPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func uriEncode(value string) string {
+	var builder strings.Builder
+	for _, b := range []byte(value) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || b == '-' || b == '_' || b == '.' || b == '~' {
+			builder.WriteByte(b)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+func hexSha256(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSha256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
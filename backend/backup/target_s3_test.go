@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/config"
+)
+
+// fakeS3 is a path style S3 server which returns one key per list page.
+type fakeS3 struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") || r.Header.Get("x-amz-date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "bucket" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		s.list(w, r)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+	case r.Method == http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key > r.URL.Query().Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var result s3ListResult
+	if len(keys) > 0 {
+		result.Contents = append(result.Contents, struct {
+			Key string `xml:"Key"`
+		}{Key: keys[0]})
+	}
+	if len(keys) > 1 {
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[0]
+	}
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func TestS3Target(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{"other/app-2001-0203-040506.tar.gz": {}}})
+	defer server.Close()
+	target, err := NewS3Target(config.BackupTarget{Name: "s3", Type: TargetS3, Url: server.URL, Path: "bucket/device", Username: "key", Password: "secret"})
+	assert.NoError(t, err)
+	testTarget(t, target)
+}
+
+func TestUriEncode(t *testing.T) {
+	assert.Equal(t, "app-2001_0203.tar.gz~", uriEncode("app-2001_0203.tar.gz~"))
+	assert.Equal(t, "a%20b%2Fc%3D", uriEncode("a b/c="))
+}
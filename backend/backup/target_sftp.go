@@ -0,0 +1,148 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"github.com/syncloud/platform/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// SftpTarget stores backups in a directory of an ssh server.
+// Url is "sftp://host[:port]", HostKey is the server public key in authorized_keys format.
+type SftpTarget struct {
+	name    string
+	dir     string
+	connect func() (*sftp.Client, io.Closer, error)
+}
+
+func NewSftpTarget(target config.BackupTarget) (*SftpTarget, error) {
+	address, err := url.Parse(target.Url)
+	if err != nil {
+		return nil, err
+	}
+	if address.Scheme != "sftp" || address.Hostname() == "" {
+		return nil, fmt.Errorf("invalid sftp url: %s", target.Url)
+	}
+	if target.HostKey == "" {
+		return nil, fmt.Errorf("sftp host key is required")
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(target.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sftp host key: %w", err)
+	}
+	host := address.Host
+	if address.Port() == "" {
+		host = net.JoinHostPort(address.Hostname(), "22")
+	}
+	sshConfig := &ssh.ClientConfig{
+		User:            target.Username,
+		Auth:            []ssh.AuthMethod{ssh.Password(target.Password)},
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+	}
+	connect := func() (*sftp.Client, io.Closer, error) {
+		conn, err := ssh.Dial("tcp", host, sshConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := sftp.NewClient(conn)
+		if err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+		return client, conn, nil
+	}
+	return NewSftpTargetWith(target.Name, target.Path, connect), nil
+}
+
+func NewSftpTargetWith(name string, dir string, connect func() (*sftp.Client, io.Closer, error)) *SftpTarget {
+	return &SftpTarget{name: name, dir: dir, connect: connect}
+}
+
+func (t *SftpTarget) Name() string {
+	return t.name
+}
+
+func (t *SftpTarget) session(action func(client *sftp.Client) error) error {
+	client, conn, err := t.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer client.Close()
+	return action(client)
+}
+
+func (t *SftpTarget) Upload(localFile string, name string) error {
+	return t.session(func(client *sftp.Client) error {
+		err := client.MkdirAll(t.dir)
+		if err != nil {
+			return err
+		}
+		source, err := os.Open(localFile)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		target := path.Join(t.dir, path.Base(name))
+		part := path.Join(t.dir, fmt.Sprintf(".%s.part", path.Base(name)))
+		dest, err := client.Create(part)
+		if err != nil {
+			return err
+		}
+		_, err = dest.ReadFrom(source)
+		closeErr := dest.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = client.Remove(part)
+			return err
+		}
+		return client.PosixRename(part, target)
+	})
+}
+
+func (t *SftpTarget) List() ([]string, error) {
+	var names []string
+	err := t.session(func(client *sftp.Client) error {
+		files, err := client.ReadDir(t.dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, file := range files {
+			if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+				continue
+			}
+			names = append(names, file.Name())
+		}
+		return nil
+	})
+	return names, err
+}
+
+func (t *SftpTarget) Download(name string, localFile string) error {
+	return t.session(func(client *sftp.Client) error {
+		source, err := client.Open(path.Join(t.dir, path.Base(name)))
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		return writeFile(localFile, source)
+	})
+}
+
+func (t *SftpTarget) Delete(name string) error {
+	return t.session(func(client *sftp.Client) error {
+		return client.Remove(path.Join(t.dir, path.Base(name)))
+	})
+}
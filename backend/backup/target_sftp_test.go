@@ -0,0 +1,39 @@
+package backup
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/config"
+)
+
+func TestSftpTarget(t *testing.T) {
+	connect := func() (*sftp.Client, io.Closer, error) {
+		serverConn, clientConn := net.Pipe()
+		server, err := sftp.NewServer(serverConn)
+		if err != nil {
+			return nil, nil, err
+		}
+		go func() { _ = server.Serve() }()
+		client, err := sftp.NewClientPipe(clientConn, clientConn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, server, nil
+	}
+	testTarget(t, NewSftpTargetWith("nas", filepath.Join(t.TempDir(), "backup"), connect))
+}
+
+func TestNewSftpTarget_HostKey(t *testing.T) {
+	target := config.BackupTarget{Name: "nas", Type: TargetSftp, Url: "sftp://nas", Path: "/backup"}
+	_, err := NewSftpTarget(target)
+	assert.Error(t, err)
+
+	target.HostKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	_, err = NewSftpTarget(target)
+	assert.NoError(t, err)
+}
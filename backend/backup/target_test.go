@@ -0,0 +1,51 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/config"
+)
+
+// testTarget runs upload, list, download and delete against a target.
+func testTarget(t *testing.T, target Target) {
+	localFile := filepath.Join(t.TempDir(), "app-2001-0203-040506.tar.gz")
+	require.NoError(t, os.WriteFile(localFile, []byte("archive"), 0600))
+
+	names, err := target.List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, target.Upload(localFile, "app-2001-0203-040506.tar.gz"))
+	require.NoError(t, target.Upload(localFile, "app-2001-0204-040506.tar.gz"))
+	names, err = target.List()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"app-2001-0203-040506.tar.gz", "app-2001-0204-040506.tar.gz"}, names)
+
+	downloaded := filepath.Join(t.TempDir(), "downloaded")
+	require.NoError(t, download(target, "app-2001-0203-040506.tar.gz", downloaded))
+	content, err := os.ReadFile(downloaded)
+	require.NoError(t, err)
+	assert.Equal(t, "archive", string(content))
+
+	require.NoError(t, target.Delete("app-2001-0203-040506.tar.gz"))
+	names, err = target.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app-2001-0204-040506.tar.gz"}, names)
+}
+
+func TestLocalTarget(t *testing.T) {
+	testTarget(t, NewLocalTarget("usb", filepath.Join(t.TempDir(), "backup")))
+}
+
+func TestValidateTarget(t *testing.T) {
+	assert.NoError(t, ValidateTarget(config.BackupTarget{Name: "usb", Type: TargetLocal, Path: "/mnt/usb"}))
+	assert.Error(t, ValidateTarget(config.BackupTarget{Type: TargetLocal, Path: "/mnt/usb"}))
+	assert.Error(t, ValidateTarget(config.BackupTarget{Name: "ftp", Type: "ftp"}))
+	assert.Error(t, ValidateTarget(config.BackupTarget{Name: "s3", Type: TargetS3, Url: "https://s3.example.com"}))
+	assert.Error(t, ValidateTarget(config.BackupTarget{Name: "nas", Type: TargetSftp, Url: "sftp://nas"}))
+	assert.Error(t, ValidateTarget(config.BackupTarget{Name: "dav", Type: TargetWebDav, Url: "dav.example.com"}))
+}
@@ -0,0 +1,189 @@
+package backup
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/syncloud/platform/config"
+)
+
+// WebDavTarget stores backups in a collection of a WebDAV server (Nextcloud, ownCloud, Apache mod_dav).
+type WebDavTarget struct {
+	name     string
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+func NewWebDavTarget(target config.BackupTarget) (*WebDavTarget, error) {
+	base, err := url.Parse(target.Url)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("invalid webdav url: %s", target.Url)
+	}
+	base.Path = path.Join("/", base.Path, target.Path) + "/"
+	return &WebDavTarget{
+		name:     target.Name,
+		base:     base,
+		username: target.Username,
+		password: target.Password,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+func (t *WebDavTarget) Name() string {
+	return t.name
+}
+
+func (t *WebDavTarget) url(name string) string {
+	u := *t.base
+	u.Path = path.Join(t.base.Path, path.Base(name))
+	return u.String()
+}
+
+func (t *WebDavTarget) Upload(localFile string, name string) error {
+	err := t.mkcol()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := t.request(http.MethodPut, t.url(name), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	return t.do(req, nil)
+}
+
+// mkcol creates the target collection and its missing parents.
+func (t *WebDavTarget) mkcol() error {
+	return t.mkcolPath(t.base.Path)
+}
+
+func (t *WebDavTarget) mkcolPath(p string) error {
+	u := *t.base
+	u.Path = p
+	req, err := t.request("MKCOL", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	err = t.do(req, nil)
+	if err == nil || isHttpStatus(err, http.StatusMethodNotAllowed) {
+		return nil
+	}
+	parent := path.Dir(strings.TrimSuffix(p, "/")) + "/"
+	if !isHttpStatus(err, http.StatusConflict) || parent == "//" || parent == p {
+		return err
+	}
+	err = t.mkcolPath(parent)
+	if err != nil {
+		return err
+	}
+	return t.do(req, nil)
+}
+
+type webDavMultistatus struct {
+	Responses []struct {
+		Href       string `xml:"href"`
+		Collection *struct {
+		} `xml:"propstat>prop>resourcetype>collection"`
+	} `xml:"response"`
+}
+
+const webDavPropfind = `<?xml version="1.0" encoding="utf-8"?><d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`
+
+func (t *WebDavTarget) List() ([]string, error) {
+	req, err := t.request("PROPFIND", t.base.String(), strings.NewReader(webDavPropfind))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml")
+	var result webDavMultistatus
+	err = t.do(req, func(body io.Reader) error {
+		return xml.NewDecoder(body).Decode(&result)
+	})
+	if err != nil {
+		if isHttpStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, response := range result.Responses {
+		if response.Collection != nil {
+			continue
+		}
+		href, err := url.PathUnescape(response.Href)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(href)
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (t *WebDavTarget) Download(name string, localFile string) error {
+	req, err := t.request(http.MethodGet, t.url(name), nil)
+	if err != nil {
+		return err
+	}
+	return t.do(req, func(body io.Reader) error {
+		return writeFile(localFile, body)
+	})
+}
+
+func (t *WebDavTarget) Delete(name string) error {
+	req, err := t.request(http.MethodDelete, t.url(name), nil)
+	if err != nil {
+		return err
+	}
+	return t.do(req, nil)
+}
+
+func (t *WebDavTarget) request(method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if t.username != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	return req, nil
+}
+
+func (t *WebDavTarget) do(req *http.Request, handle func(body io.Reader) error) error {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newHttpStatusError("webdav", req, resp)
+	}
+	if handle != nil {
+		return handle(resp.Body)
+	}
+	return nil
+}
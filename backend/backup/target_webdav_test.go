@@ -0,0 +1,30 @@
+package backup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/config"
+	"golang.org/x/net/webdav"
+)
+
+func TestWebDavTarget(t *testing.T) {
+	handler := &webdav.Handler{
+		FileSystem: webdav.Dir(t.TempDir()),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	target, err := NewWebDavTarget(config.BackupTarget{Name: "dav", Type: TargetWebDav, Url: server.URL, Path: "device/backup", Username: "user", Password: "secret"})
+	assert.NoError(t, err)
+	testTarget(t, target)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/syncloud/platform/backup"
+	"github.com/syncloud/platform/config"
 )
 
 func backupCmd(userConfig *string, systemConfig *string) *cobra.Command {
@@ -44,6 +45,30 @@ func backupCmd(userConfig *string, systemConfig *string) *cobra.Command {
 		},
 	})
 
+	cmd.AddCommand(&cobra.Command{
+		Use:   "verify [file]",
+		Short: "Verify backup checksums",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				verification, err := backup.Verify(args[0])
+				if err != nil {
+					return err
+				}
+				s, err := json.MarshalIndent(verification, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	})
+
 	cmd.AddCommand(&cobra.Command{
 		Use:   "list",
 		Short: "List backups",
@@ -66,5 +91,366 @@ func backupCmd(userConfig *string, systemConfig *string) *cobra.Command {
 			})
 		},
 	})
+	cmd.AddCommand(backupModeCmd(userConfig, systemConfig))
+	cmd.AddCommand(backupEncryptionCmd(userConfig, systemConfig))
+	cmd.AddCommand(backupTargetCmd(userConfig, systemConfig))
+	cmd.AddCommand(backupRetentionCmd(userConfig, systemConfig))
+	cmd.AddCommand(backupAutoCmd(userConfig, systemConfig))
+	return cmd
+}
+
+func backupModeCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "mode",
+		Short: "Show how app backups are written",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				s, err := json.MarshalIndent(backup.Mode(), "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	}
+
+	var cmdSet = &cobra.Command{
+		Use:   "set",
+		Short: "Set how app backups are written",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mode := backup.Mode{}
+			mode.Incremental, _ = cmd.Flags().GetBool("incremental")
+			mode.Streaming, _ = cmd.Flags().GetBool("streaming")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(b *backup.Backup) error {
+				return b.SetMode(mode)
+			})
+		},
+	}
+	cmdSet.Flags().Bool("incremental", false, "deduplicated snapshots in the local chunk store, not available with encryption or remote targets")
+	cmdSet.Flags().Bool("streaming", false, "archive app data in place without a temp copy")
+	cmd.AddCommand(cmdSet)
+	return cmd
+}
+
+func backupEncryptionCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "encryption",
+		Short: "Show backup encryption settings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				s, err := json.MarshalIndent(backup.Encryption(), "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	}
+
+	var cmdSet = &cobra.Command{
+		Use:   "set",
+		Short: "Encrypt new backups with a passphrase or age recipients",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			passphrase, _ := cmd.Flags().GetString("passphrase")
+			recipients, _ := cmd.Flags().GetStringSlice("recipient")
+			identities, _ := cmd.Flags().GetStringSlice("identity")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(b *backup.Backup) error {
+				return b.SetEncryption(backup.Encryption{
+					Passphrase: passphrase,
+					Recipients: recipients,
+					Identities: identities,
+				})
+			})
+		},
+	}
+	cmdSet.Flags().String("passphrase", "", "passphrase")
+	cmdSet.Flags().StringSlice("recipient", nil, "age public key (age1...), can be repeated")
+	cmdSet.Flags().StringSlice("identity", nil, "age private key (AGE-SECRET-KEY-1...) used to restore, can be repeated")
+	cmd.AddCommand(cmdSet)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "disable",
+		Short: "Stop encrypting new backups",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(b *backup.Backup) error {
+				return b.SetEncryption(backup.Encryption{})
+			})
+		},
+	})
+	return cmd
+}
+
+func backupTargetCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "target",
+		Short: "Remote backup targets",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List remote backup targets",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				targets, err := backup.Targets()
+				if err != nil {
+					return err
+				}
+				s, err := json.MarshalIndent(targets, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	})
+
+	var cmdAdd = &cobra.Command{
+		Use:   "add [name] [local|s3|sftp|webdav]",
+		Short: "Add or replace a remote backup target",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := config.BackupTarget{Name: args[0], Type: args[1]}
+			target.Url, _ = cmd.Flags().GetString("url")
+			target.Path, _ = cmd.Flags().GetString("path")
+			target.Region, _ = cmd.Flags().GetString("region")
+			target.Username, _ = cmd.Flags().GetString("username")
+			target.Password, _ = cmd.Flags().GetString("password")
+			target.HostKey, _ = cmd.Flags().GetString("host-key")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				return backup.AddTarget(target)
+			})
+		},
+	}
+	cmdAdd.Flags().String("url", "", "endpoint: https://s3.example.com, sftp://host:22, https://dav.example.com/remote.php/dav/files/user")
+	cmdAdd.Flags().String("path", "", "directory, for s3: bucket/prefix")
+	cmdAdd.Flags().String("region", "", "s3 region")
+	cmdAdd.Flags().String("username", "", "username or s3 access key")
+	cmdAdd.Flags().String("password", "", "password or s3 secret key")
+	cmdAdd.Flags().String("host-key", "", "sftp server public key in authorized_keys format")
+	cmd.AddCommand(cmdAdd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a remote backup target, backups stored there are kept",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				return backup.RemoveTarget(args[0])
+			})
+		},
+	})
+	return cmd
+}
+
+func backupAutoCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "auto",
+		Short: "Show auto backup settings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				s, err := json.MarshalIndent(backup.Auto(), "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	}
+
+	var cmdSet = &cobra.Command{
+		Use:   "set [no|backup|restore]",
+		Short: "Set the global auto mode, day (0 is every day, 1-7 is Monday-Sunday) and hour",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auto := backup.Auto{Auto: args[0]}
+			auto.Day, _ = cmd.Flags().GetInt("day")
+			auto.Hour, _ = cmd.Flags().GetInt("hour")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				backup.SetAuto(auto)
+				return nil
+			})
+		},
+	}
+	cmdSet.Flags().Int("day", 0, "day of week, 0 is every day")
+	cmdSet.Flags().Int("hour", 0, "hour")
+	cmd.AddCommand(cmdSet)
+
+	var cmdApp = &cobra.Command{
+		Use:   "app [app]",
+		Short: "Override the global auto settings for an app",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auto := backup.AppAuto{App: args[0]}
+			disable, _ := cmd.Flags().GetBool("disable")
+			auto.Enabled = !disable
+			auto.Mode, _ = cmd.Flags().GetString("mode")
+			auto.Schedule, _ = cmd.Flags().GetString("schedule")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				return backup.SetAppAuto(auto)
+			})
+		},
+	}
+	cmdApp.Flags().Bool("disable", false, "exclude the app from auto backup and restore")
+	cmdApp.Flags().String("mode", "", "backup or restore, the global mode is used if empty")
+	cmdApp.Flags().String("schedule", "", "cron expression, the global schedule is used if empty")
+	cmd.AddCommand(cmdApp)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove [app]",
+		Short: "Remove the auto settings of an app so that the global ones apply",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				backup.RemoveAppAuto(args[0])
+				return nil
+			})
+		},
+	})
+	return cmd
+}
+
+func backupRetentionCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "retention",
+		Short: "Show backup retention policies",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				s, err := json.MarshalIndent(backup.Retention(), "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	}
+
+	var cmdSet = &cobra.Command{
+		Use:   "set [app]",
+		Short: "Set the retention policy of an app, without an app sets the global policy",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			retention := backup.AppRetention{}
+			if len(args) > 0 {
+				retention.App = args[0]
+			}
+			retention.KeepLast, _ = cmd.Flags().GetInt("keep-last")
+			retention.KeepDaily, _ = cmd.Flags().GetInt("keep-daily")
+			retention.KeepWeekly, _ = cmd.Flags().GetInt("keep-weekly")
+			retention.KeepMonthly, _ = cmd.Flags().GetInt("keep-monthly")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				return backup.SetRetention(retention)
+			})
+		},
+	}
+	cmdSet.Flags().Int("keep-last", 0, "keep the last n backups")
+	cmdSet.Flags().Int("keep-daily", 0, "keep the last backup of each of the last n days")
+	cmdSet.Flags().Int("keep-weekly", 0, "keep the last backup of each of the last n weeks")
+	cmdSet.Flags().Int("keep-monthly", 0, "keep the last backup of each of the last n months")
+	cmd.AddCommand(cmdSet)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove [app]",
+		Short: "Remove the retention policy of an app so that the global one applies",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				backup.RemoveRetention(args[0])
+				return nil
+			})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "preview [app]",
+		Short: "Show which backups the retention policies would remove",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := ""
+			if len(args) > 0 {
+				app = args[0]
+			}
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(backup *backup.Backup) error {
+				plan, err := backup.PlanRetention(app)
+				if err != nil {
+					return err
+				}
+				s, err := json.MarshalIndent(plan, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	})
 	return cmd
 }
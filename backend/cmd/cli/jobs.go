@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/syncloud/platform/config"
+)
+
+func jobsCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "jobs",
+		Short: "Jobs",
+	}
+
+	var cmdHistory = &cobra.Command{
+		Use:   "history",
+		Short: "Show finished jobs, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(history *config.JobHistory) error {
+				records, err := history.List(limit)
+				if err != nil {
+					return err
+				}
+				s, err := json.MarshalIndent(records, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", s)
+				return nil
+			})
+		},
+	}
+	cmdHistory.Flags().Int("limit", 20, "number of jobs to show")
+	cmd.AddCommand(cmdHistory)
+
+	return cmd
+}
@@ -25,6 +25,8 @@ func main() {
 		loginCmd(userConfig, systemConfig),
 		proxyCmd(userConfig, systemConfig),
 		userCmd(userConfig, systemConfig),
+		jobsCmd(userConfig, systemConfig),
+		metricsCmd(userConfig, systemConfig),
 	)
 
 	err := rootCmd.Execute()
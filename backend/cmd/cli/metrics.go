@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/syncloud/platform/health"
+)
+
+func metricsCmd(userConfig *string, systemConfig *string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "metrics",
+		Short: "Manage the metrics listener",
+	}
+
+	var cmdListen = &cobra.Command{
+		Use:   "listen",
+		Short: "Show the metrics listen address",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(server *health.MetricsServer) {
+				fmt.Println(server.Listen())
+			})
+		},
+	}
+
+	var cmdListenSet = &cobra.Command{
+		Use:   "set [address]",
+		Short: "Set a loopback host:port for the metrics listener, no address disables it",
+		Long:  "Set a loopback host:port for the metrics listener, no address disables it, the backend applies it on restart",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address := ""
+			if len(args) > 0 {
+				address = args[0]
+			}
+			c, err := Init(*userConfig, *systemConfig)
+			if err != nil {
+				return err
+			}
+			return c.Call(func(server *health.MetricsServer) error {
+				return server.SetListen(address)
+			})
+		},
+	}
+	cmdListen.AddCommand(cmdListenSet)
+	cmd.AddCommand(cmdListen)
+	return cmd
+}
@@ -4,9 +4,12 @@ import (
 	"path"
 	"syscall"
 
+	"github.com/syncloud/platform/cli"
+	"github.com/syncloud/platform/config"
 	"github.com/syncloud/platform/hook"
 	"github.com/syncloud/platform/log"
 	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/systemd"
 )
 
 func main() {
@@ -15,15 +18,23 @@ func main() {
 	eventsPath := path.Join(hook.DataDir, "stability-events.jsonl")
 	stability.MigrateEventLog(path.Join(hook.CommonDir, "stability-events.jsonl"), eventsPath, logger)
 	events := stability.NewEventLog(eventsPath)
+	policyFile := stability.NewPolicyFile(path.Join(hook.DataDir, stability.PolicyFileName))
+	policy, err := policyFile.Load()
+	if err != nil {
+		logger.Sugar().Warnf("stability: policy is not valid, using the defaults: %v", err)
+	}
 	zram := stability.NewZram(mem, events, logger)
-	if err := zram.EnsureConfigured(); err != nil {
+	if err := zram.EnsureConfigured(policy.Zram); err != nil {
 		logger.Sugar().Warnf("stability: zram setup failed (continuing): %v", err)
 	}
 
 	scanner := stability.NewProcScanner("/proc")
+	systemConfig := config.NewSystemConfig(config.DefaultSystemConfig)
+	systemConfig.Load()
+	restarter := stability.NewRestarter(systemd.New(cli.New(logger), systemConfig, logger), events, logger)
 	watcher := stability.NewWatcher(mem, scanner, func(pid int, sig syscall.Signal) error {
 		return syscall.Kill(pid, sig)
-	}, events, logger)
+	}, events, policyFile, stability.NewLimits("/sys/fs/cgroup", events, logger), restarter, logger)
 
 	watcher.Run()
 }
@@ -0,0 +1,86 @@
+package config
+
+type AlertRule struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold"`
+	Event     string  `json:"event,omitempty"`
+	Enabled   bool    `json:"enabled"`
+}
+
+type AlertChannel struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Url     string `json:"url,omitempty"`
+	Token   string `json:"token,omitempty"`
+	To      string `json:"to,omitempty"`
+	Enabled bool   `json:"enabled"`
+}
+
+type Alerts struct {
+	db *Db
+}
+
+func NewAlerts(db *Db) *Alerts {
+	return &Alerts{db: db}
+}
+
+func (c *Alerts) AddRule(rule AlertRule) error {
+	_, err := c.db.Exec("INSERT OR REPLACE INTO alert_rule VALUES (?, ?, ?, ?, ?)",
+		rule.Name, rule.Type, rule.Threshold, rule.Event, rule.Enabled)
+	return err
+}
+
+func (c *Alerts) RemoveRule(name string) error {
+	_, err := c.db.Exec("DELETE FROM alert_rule WHERE name = ?", name)
+	return err
+}
+
+func (c *Alerts) Rules() ([]AlertRule, error) {
+	db := c.db.Open()
+	defer db.Close()
+	rows, err := db.Query("select name, type, threshold, event, enabled from alert_rule order by name")
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]AlertRule, 0)
+	defer rows.Close()
+	for rows.Next() {
+		var rule AlertRule
+		if err := rows.Scan(&rule.Name, &rule.Type, &rule.Threshold, &rule.Event, &rule.Enabled); err != nil {
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (c *Alerts) AddChannel(channel AlertChannel) error {
+	_, err := c.db.Exec("INSERT OR REPLACE INTO alert_channel VALUES (?, ?, ?, ?, ?, ?)",
+		channel.Name, channel.Type, channel.Url, channel.Token, channel.To, channel.Enabled)
+	return err
+}
+
+func (c *Alerts) RemoveChannel(name string) error {
+	_, err := c.db.Exec("DELETE FROM alert_channel WHERE name = ?", name)
+	return err
+}
+
+func (c *Alerts) Channels() ([]AlertChannel, error) {
+	db := c.db.Open()
+	defer db.Close()
+	rows, err := db.Query("select name, type, url, token, recipient, enabled from alert_channel order by name")
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]AlertChannel, 0)
+	defer rows.Close()
+	for rows.Next() {
+		var channel AlertChannel
+		if err := rows.Scan(&channel.Name, &channel.Type, &channel.Url, &channel.Token, &channel.To, &channel.Enabled); err != nil {
+			return channels, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
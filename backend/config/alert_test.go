@@ -0,0 +1,51 @@
+package config
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+)
+
+func newTestAlerts(t *testing.T) *Alerts {
+	db := NewDb(path.Join(t.TempDir(), "db"), log.Default())
+	assert.NoError(t, NewMigrator(db).Migrate())
+	return NewAlerts(db)
+}
+
+func TestAlerts_DefaultRules(t *testing.T) {
+	alerts := newTestAlerts(t)
+	rules, err := alerts.Rules()
+	assert.NoError(t, err)
+	assert.Len(t, rules, 5)
+	assert.Equal(t, AlertRule{Name: "app_killed", Type: "stability_event", Threshold: 60, Event: "victim_sigkill", Enabled: true}, rules[0])
+}
+
+func TestAlerts_Rules(t *testing.T) {
+	alerts := newTestAlerts(t)
+	assert.NoError(t, alerts.AddRule(AlertRule{Name: "disk_full", Type: "disk_usage", Threshold: 80, Enabled: false}))
+	assert.NoError(t, alerts.RemoveRule("memory_full"))
+
+	rules, err := alerts.Rules()
+	assert.NoError(t, err)
+	assert.Len(t, rules, 4)
+	assert.Equal(t, AlertRule{Name: "disk_full", Type: "disk_usage", Threshold: 80}, rules[3])
+}
+
+func TestAlerts_Channels(t *testing.T) {
+	alerts := newTestAlerts(t)
+	assert.NoError(t, alerts.AddChannel(AlertChannel{Name: "phone", Type: "ntfy", Url: "https://ntfy.sh/topic", Token: "secret", Enabled: true}))
+	assert.NoError(t, alerts.AddChannel(AlertChannel{Name: "admin", Type: "email", To: "admin@example.com", Enabled: true}))
+
+	channels, err := alerts.Channels()
+	assert.NoError(t, err)
+	assert.Len(t, channels, 2)
+	assert.Equal(t, "admin@example.com", channels[0].To)
+	assert.Equal(t, "secret", channels[1].Token)
+
+	assert.NoError(t, alerts.RemoveChannel("admin"))
+	channels, err = alerts.Channels()
+	assert.NoError(t, err)
+	assert.Len(t, channels, 1)
+}
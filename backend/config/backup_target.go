@@ -0,0 +1,50 @@
+package config
+
+type BackupTarget struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Url      string `json:"url"`
+	Path     string `json:"path"`
+	Region   string `json:"region,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	HostKey  string `json:"host_key,omitempty"`
+}
+
+type BackupTargets struct {
+	db *Db
+}
+
+func NewBackupTargets(db *Db) *BackupTargets {
+	return &BackupTargets{db: db}
+}
+
+func (c *BackupTargets) Add(target BackupTarget) error {
+	_, err := c.db.Exec("INSERT OR REPLACE INTO backup_target VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		target.Name, target.Type, target.Url, target.Path, target.Region, target.Username, target.Password, target.HostKey)
+	return err
+}
+
+func (c *BackupTargets) Remove(name string) error {
+	_, err := c.db.Exec("DELETE FROM backup_target WHERE name = ?", name)
+	return err
+}
+
+func (c *BackupTargets) List() ([]BackupTarget, error) {
+	db := c.db.Open()
+	defer db.Close()
+	rows, err := db.Query("select name, type, url, path, region, username, password, host_key from backup_target order by name")
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]BackupTarget, 0)
+	defer rows.Close()
+	for rows.Next() {
+		var target BackupTarget
+		if err := rows.Scan(&target.Name, &target.Type, &target.Url, &target.Path, &target.Region, &target.Username, &target.Password, &target.HostKey); err != nil {
+			return targets, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+	"path"
+	"testing"
+)
+
+func newTestBackupTargets(t *testing.T) *BackupTargets {
+	db := NewDb(path.Join(t.TempDir(), "db"), log.Default())
+	assert.NoError(t, NewMigrator(db).Migrate())
+	return NewBackupTargets(db)
+}
+
+func TestBackupTargets_AddListRemove(t *testing.T) {
+	targets := newTestBackupTargets(t)
+
+	err := targets.Add(BackupTarget{Name: "s3", Type: "s3", Url: "https://s3.example.com", Path: "bucket/backup", Region: "eu", Username: "key", Password: "secret"})
+	assert.NoError(t, err)
+	err = targets.Add(BackupTarget{Name: "nas", Type: "sftp", Url: "sftp://nas:22", Path: "/backup"})
+	assert.NoError(t, err)
+
+	list, err := targets.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "nas", list[0].Name)
+	assert.Equal(t, "s3", list[1].Name)
+	assert.Equal(t, "secret", list[1].Password)
+
+	assert.NoError(t, targets.Remove("nas"))
+	list, err = targets.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestBackupTargets_Add_Replaces(t *testing.T) {
+	targets := newTestBackupTargets(t)
+
+	assert.NoError(t, targets.Add(BackupTarget{Name: "nas", Type: "sftp", Url: "sftp://old"}))
+	assert.NoError(t, targets.Add(BackupTarget{Name: "nas", Type: "sftp", Url: "sftp://new"}))
+
+	list, err := targets.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "sftp://new", list[0].Url)
+}
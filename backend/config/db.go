@@ -6,6 +6,7 @@ import (
 	_ "modernc.org/sqlite"
 	"go.uber.org/zap"
 	"log"
+	"os"
 	"strconv"
 )
 
@@ -53,6 +54,34 @@ func (c *Db) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return db.Exec(query, args...)
 }
 
+// Snapshot writes a consistent copy of the database into file.
+func (c *Db) Snapshot(file string) error {
+	db := c.Open()
+	defer db.Close()
+	_, err := db.Exec("VACUUM INTO ?", file)
+	return err
+}
+
+// Restore replaces the database with a snapshot.
+func (c *Db) Restore(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	tmp := c.file + ".restore"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		err = os.Remove(c.file + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(tmp, c.file)
+}
+
 func (c *Db) Upsert(key string, value string) {
 	db := c.Open()
 	defer db.Close()
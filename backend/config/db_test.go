@@ -101,3 +101,16 @@ func TestDb_Exec_AllowsArbitrarySchema(t *testing.T) {
 	_, err = db.Exec("insert into extra values ('x')")
 	assert.NoError(t, err)
 }
+
+func TestDb_Snapshot_Restore(t *testing.T) {
+	db := newTestDb(t)
+	db.Upsert("k", "backup")
+	snapshot := path.Join(t.TempDir(), "snapshot.db")
+	assert.NoError(t, db.Snapshot(snapshot))
+	db.Upsert("k", "live")
+	db.Upsert("other", "live")
+
+	assert.NoError(t, db.Restore(snapshot))
+	assert.Equal(t, "backup", db.Get("k", ""))
+	assert.Nil(t, db.GetOrNilString("other"))
+}
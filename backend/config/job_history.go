@@ -0,0 +1,65 @@
+package config
+
+import "time"
+
+// KeepJobHistory is the number of finished jobs kept in the history.
+const KeepJobHistory = 1000
+
+type JobRecord struct {
+	Id       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Source   string    `json:"source"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration float64   `json:"duration"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type JobHistory struct {
+	db *Db
+}
+
+func NewJobHistory(db *Db) *JobHistory {
+	return &JobHistory{db: db}
+}
+
+// Add records a finished job and drops the oldest records over KeepJobHistory.
+func (h *JobHistory) Add(record JobRecord) error {
+	db := h.db.Open()
+	defer db.Close()
+	_, err := db.Exec("INSERT INTO job_history (name, source, started, finished, duration, result, error) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		record.Name, record.Source, record.Started.UTC().Format(time.RFC3339Nano), record.Finished.UTC().Format(time.RFC3339Nano),
+		record.Finished.Sub(record.Started).Seconds(), record.Result, record.Error)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM job_history WHERE id NOT IN (SELECT id FROM job_history ORDER BY id DESC LIMIT ?)", KeepJobHistory)
+	return err
+}
+
+// List returns up to limit records, newest first.
+func (h *JobHistory) List(limit int) ([]JobRecord, error) {
+	db := h.db.Open()
+	defer db.Close()
+	if limit <= 0 || limit > KeepJobHistory {
+		limit = KeepJobHistory
+	}
+	rows, err := db.Query("select id, name, source, started, finished, duration, result, error from job_history order by id desc limit ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]JobRecord, 0)
+	defer rows.Close()
+	for rows.Next() {
+		var record JobRecord
+		var started, finished string
+		if err := rows.Scan(&record.Id, &record.Name, &record.Source, &started, &finished, &record.Duration, &record.Result, &record.Error); err != nil {
+			return records, err
+		}
+		record.Started, _ = time.Parse(time.RFC3339Nano, started)
+		record.Finished, _ = time.Parse(time.RFC3339Nano, finished)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
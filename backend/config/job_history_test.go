@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+	"path"
+	"testing"
+	"time"
+)
+
+func newTestJobHistory(t *testing.T) *JobHistory {
+	db := NewDb(path.Join(t.TempDir(), "db"), log.Default())
+	assert.NoError(t, NewMigrator(db).Migrate())
+	return NewJobHistory(db)
+}
+
+func TestJobHistory_AddList(t *testing.T) {
+	history := newTestJobHistory(t)
+	started := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	err := history.Add(JobRecord{Name: "backup.create", Source: "cron", Started: started, Finished: started.Add(90 * time.Second), Result: "succeeded"})
+	assert.NoError(t, err)
+	err = history.Add(JobRecord{Name: "backup.restore", Source: "user", Started: started, Finished: started.Add(time.Second), Result: "failed", Error: "no space"})
+	assert.NoError(t, err)
+
+	list, err := history.List(10)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "backup.restore", list[0].Name)
+	assert.Equal(t, "no space", list[0].Error)
+	assert.Equal(t, "backup.create", list[1].Name)
+	assert.Equal(t, "cron", list[1].Source)
+	assert.Equal(t, float64(90), list[1].Duration)
+	assert.True(t, started.Equal(list[1].Started))
+
+	list, err = history.List(1)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestJobHistory_Retention(t *testing.T) {
+	history := newTestJobHistory(t)
+	now := time.Now()
+	for i := 0; i < KeepJobHistory+5; i++ {
+		assert.NoError(t, history.Add(JobRecord{Name: fmt.Sprintf("job%d", i), Started: now, Finished: now, Result: "succeeded"}))
+	}
+
+	list, err := history.List(0)
+	assert.NoError(t, err)
+	assert.Len(t, list, KeepJobHistory)
+	assert.Equal(t, fmt.Sprintf("job%d", KeepJobHistory+4), list[0].Name)
+	assert.Equal(t, "job5", list[len(list)-1].Name)
+}
@@ -24,6 +24,10 @@ func migrations() []*goose.Migration {
 		goose.NewGoMigration(4, &goose.GoFunc{RunTx: addCustomProxyHttps}, nil),
 		goose.NewGoMigration(5, &goose.GoFunc{RunTx: addCustomProxyAuthelia}, nil),
 		goose.NewGoMigration(6, &goose.GoFunc{RunTx: normalizeOidcRedirectUris}, nil),
+		goose.NewGoMigration(7, &goose.GoFunc{RunTx: createBackupTargetTable}, nil),
+		goose.NewGoMigration(8, &goose.GoFunc{RunTx: createJobHistoryTable}, nil),
+		goose.NewGoMigration(9, &goose.GoFunc{RunTx: excludeSyncthingFromAutoBackup}, nil),
+		goose.NewGoMigration(10, &goose.GoFunc{RunTx: createAlertTables}, nil),
 	}
 }
 
@@ -101,6 +105,46 @@ func normalizeOidcRedirectUris(ctx context.Context, tx *sql.Tx) error {
 	return err
 }
 
+func createBackupTargetTable(_ context.Context, tx *sql.Tx) error {
+	_, err := tx.Exec(`create table if not exists backup_target
+		(name varchar primary key, type varchar, url varchar, path varchar, region varchar, username varchar, password varchar, host_key varchar)`)
+	return err
+}
+
+func createJobHistoryTable(_ context.Context, tx *sql.Tx) error {
+	_, err := tx.Exec(`create table if not exists job_history
+		(id integer primary key autoincrement, name varchar, source varchar, started varchar, finished varchar, duration real, result varchar, error varchar)`)
+	return err
+}
+
+// excludeSyncthingFromAutoBackup keeps syncthing, which is used as a backup transport, out of auto backups
+// now that the exclusion is a per app setting which can be changed.
+func excludeSyncthingFromAutoBackup(_ context.Context, tx *sql.Tx) error {
+	_, err := tx.Exec("INSERT OR IGNORE INTO config (key, value) VALUES ('platform.backup_auto.syncthing', '{\"enabled\":false}')")
+	return err
+}
+
+// createAlertTables adds the alert rules with the default ones enabled, channels are added by the user.
+func createAlertTables(_ context.Context, tx *sql.Tx) error {
+	_, err := tx.Exec(`create table if not exists alert_rule
+		(name varchar primary key, type varchar, threshold real, event varchar, enabled boolean)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`create table if not exists alert_channel
+		(name varchar primary key, type varchar, url varchar, token varchar, recipient varchar, enabled boolean)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT OR IGNORE INTO alert_rule VALUES
+		('disk_full', 'disk_usage', 95, '', true),
+		('memory_full', 'memory_usage', 95, '', true),
+		('btrfs_errors', 'btrfs_errors', 0, '', true),
+		('certificate_expiry', 'certificate_expiry', 5, '', true),
+		('app_killed', 'stability_event', 60, 'victim_sigkill', true)`)
+	return err
+}
+
 func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
 	exists, err := columnExists(ctx, tx, table, column)
 	if err != nil {
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -207,6 +210,172 @@ func (c *UserConfig) SetBackupAppTime(app string, mode string, time time.Time) {
 	c.db.Upsert(fmt.Sprintf("platform.backup.%s.%s", app, mode), strconv.FormatInt(time.Unix(), 10))
 }
 
+// GetBackupRestoredFile is the archive which was last restored to the app by auto restore.
+func (c *UserConfig) GetBackupRestoredFile(app string) string {
+	return c.db.Get(fmt.Sprintf("platform.backup_restored.%s", app), "")
+}
+
+func (c *UserConfig) SetBackupRestoredFile(app string, file string) {
+	c.db.Upsert(fmt.Sprintf("platform.backup_restored.%s", app), file)
+}
+
+func (c *UserConfig) IsBackupIncremental() bool {
+	return c.db.GetBool("platform.backup_incremental", false)
+}
+
+func (c *UserConfig) SetBackupIncremental(enabled bool) {
+	c.db.UpsertBool("platform.backup_incremental", enabled)
+}
+
+func (c *UserConfig) IsBackupStreaming() bool {
+	return c.db.GetBool("platform.backup_streaming", false)
+}
+
+func (c *UserConfig) SetBackupStreaming(enabled bool) {
+	c.db.UpsertBool("platform.backup_streaming", enabled)
+}
+
+func (c *UserConfig) GetBackupPassphrase() string {
+	return c.db.Get("platform.backup_passphrase", "")
+}
+
+func (c *UserConfig) SetBackupPassphrase(passphrase string) {
+	c.setOrDelete("platform.backup_passphrase", passphrase)
+}
+
+func (c *UserConfig) GetBackupRecipients() []string {
+	return c.getList("platform.backup_recipients")
+}
+
+func (c *UserConfig) SetBackupRecipients(recipients []string) {
+	c.setOrDelete("platform.backup_recipients", strings.Join(recipients, "\n"))
+}
+
+func (c *UserConfig) GetBackupIdentities() []string {
+	return c.getList("platform.backup_identities")
+}
+
+func (c *UserConfig) SetBackupIdentities(identities []string) {
+	c.setOrDelete("platform.backup_identities", strings.Join(identities, "\n"))
+}
+
+type BackupRetention struct {
+	KeepLast    int `json:"keep_last"`
+	KeepDaily   int `json:"keep_daily"`
+	KeepWeekly  int `json:"keep_weekly"`
+	KeepMonthly int `json:"keep_monthly"`
+}
+
+func backupRetentionKey(app string) string {
+	if app == "" {
+		return "platform.backup_retention"
+	}
+	return fmt.Sprintf("platform.backup_retention.%s", app)
+}
+
+// GetBackupRetention returns the retention policy of an app or the global one for an empty app.
+func (c *UserConfig) GetBackupRetention(app string) (BackupRetention, bool) {
+	var retention BackupRetention
+	value := c.db.GetOrNilString(backupRetentionKey(app))
+	if value == nil {
+		return retention, false
+	}
+	err := json.Unmarshal([]byte(*value), &retention)
+	if err != nil {
+		c.logger.Error("invalid backup retention", zap.String("app", app), zap.Error(err))
+		return retention, false
+	}
+	return retention, true
+}
+
+func (c *UserConfig) SetBackupRetention(app string, retention BackupRetention) {
+	value, err := json.Marshal(retention)
+	if err != nil {
+		c.logger.Error("backup retention", zap.Error(err))
+		return
+	}
+	c.db.Upsert(backupRetentionKey(app), string(value))
+}
+
+func (c *UserConfig) RemoveBackupRetention(app string) {
+	c.db.Delete(backupRetentionKey(app))
+}
+
+func (c *UserConfig) ListBackupRetentionApps() []string {
+	return c.listApps(backupRetentionKey(""))
+}
+
+// BackupAppAuto overrides the global auto backup settings for one app,
+// an empty mode or schedule falls back to the global one.
+type BackupAppAuto struct {
+	Enabled  bool   `json:"enabled"`
+	Mode     string `json:"mode,omitempty"`
+	Schedule string `json:"schedule,omitempty"`
+}
+
+func backupAppAutoKey(app string) string {
+	return fmt.Sprintf("platform.backup_auto.%s", app)
+}
+
+func (c *UserConfig) GetBackupAppAuto(app string) (BackupAppAuto, bool) {
+	var auto BackupAppAuto
+	value := c.db.GetOrNilString(backupAppAutoKey(app))
+	if value == nil {
+		return auto, false
+	}
+	err := json.Unmarshal([]byte(*value), &auto)
+	if err != nil {
+		c.logger.Error("invalid backup auto", zap.String("app", app), zap.Error(err))
+		return auto, false
+	}
+	return auto, true
+}
+
+func (c *UserConfig) SetBackupAppAuto(app string, auto BackupAppAuto) {
+	value, err := json.Marshal(auto)
+	if err != nil {
+		c.logger.Error("backup auto", zap.Error(err))
+		return
+	}
+	c.db.Upsert(backupAppAutoKey(app), string(value))
+}
+
+func (c *UserConfig) RemoveBackupAppAuto(app string) {
+	c.db.Delete(backupAppAutoKey(app))
+}
+
+func (c *UserConfig) ListBackupAutoApps() []string {
+	return c.listApps("platform.backup_auto")
+}
+
+func (c *UserConfig) listApps(key string) []string {
+	prefix := key + "."
+	var apps []string
+	for key := range c.db.List() {
+		if strings.HasPrefix(key, prefix) {
+			apps = append(apps, strings.TrimPrefix(key, prefix))
+		}
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+func (c *UserConfig) getList(key string) []string {
+	value := c.db.Get(key, "")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+func (c *UserConfig) setOrDelete(key string, value string) {
+	if value == "" {
+		c.db.Delete(key)
+	} else {
+		c.db.Upsert(key, value)
+	}
+}
+
 func (c *UserConfig) SetCustomDomain(domain string) {
 	c.db.Upsert("platform.custom_domain", domain)
 }
@@ -261,8 +430,24 @@ func (c *UserConfig) SetTimezone(timezone string) {
 	c.db.Upsert("platform.timezone", timezone)
 }
 
+func (c *UserConfig) GetMetricsListen() string {
+	return c.db.Get("platform.metrics_listen", "")
+}
+
+func (c *UserConfig) SetMetricsListen(address string) {
+	c.setOrDelete("platform.metrics_listen", address)
+}
+
 func (c *UserConfig) Url(app string) string {
 	port := c.GetPublicPort()
 	domain := c.GetDeviceDomain()
 	return ConstructUrl(port, fmt.Sprintf("%s.%s", app, domain))
 }
+
+func (c *UserConfig) GetCronSchedule(job string) *string {
+	return c.db.GetOrNilString(fmt.Sprintf("platform.cron.%s", job))
+}
+
+func (c *UserConfig) SetCronSchedule(job string, expression string) {
+	c.setOrDelete(fmt.Sprintf("platform.cron.%s", job), expression)
+}
@@ -56,6 +56,64 @@ func TestBackupAppTime(t *testing.T) {
 	assert.Equal(t, time.Unix(timesatamp.Unix(), 0), config.GetBackupAppTime("app1", "backup"))
 }
 
+func TestBackupEncryption(t *testing.T) {
+	config, _ := newTestUserConfig(t)
+	assert.Equal(t, "", config.GetBackupPassphrase())
+	assert.Empty(t, config.GetBackupRecipients())
+
+	config.SetBackupPassphrase("secret")
+	config.SetBackupRecipients([]string{"age1a", "age1b"})
+	assert.Equal(t, "secret", config.GetBackupPassphrase())
+	assert.Equal(t, []string{"age1a", "age1b"}, config.GetBackupRecipients())
+
+	config.SetBackupPassphrase("")
+	config.SetBackupRecipients(nil)
+	assert.Equal(t, "", config.GetBackupPassphrase())
+	assert.Empty(t, config.GetBackupRecipients())
+}
+
+func TestBackupRetention(t *testing.T) {
+	config, _ := newTestUserConfig(t)
+	_, ok := config.GetBackupRetention("")
+	assert.False(t, ok)
+
+	config.SetBackupRetention("", BackupRetention{KeepLast: 3, KeepDaily: 7})
+	config.SetBackupRetention("files", BackupRetention{KeepMonthly: 12})
+	retention, ok := config.GetBackupRetention("")
+	assert.True(t, ok)
+	assert.Equal(t, BackupRetention{KeepLast: 3, KeepDaily: 7}, retention)
+	retention, ok = config.GetBackupRetention("files")
+	assert.True(t, ok)
+	assert.Equal(t, 12, retention.KeepMonthly)
+	assert.Equal(t, []string{"files"}, config.ListBackupRetentionApps())
+
+	config.RemoveBackupRetention("files")
+	_, ok = config.GetBackupRetention("files")
+	assert.False(t, ok)
+	assert.Empty(t, config.ListBackupRetentionApps())
+}
+
+func TestBackupAppAuto(t *testing.T) {
+	config, _ := newTestUserConfig(t)
+	config.SetBackupAuto("backup")
+	assert.Equal(t, []string{"syncthing"}, config.ListBackupAutoApps())
+	auto, ok := config.GetBackupAppAuto("syncthing")
+	assert.True(t, ok)
+	assert.False(t, auto.Enabled)
+
+	config.SetBackupAppAuto("files", BackupAppAuto{Enabled: true, Mode: "restore", Schedule: "0 2 * * *"})
+	auto, ok = config.GetBackupAppAuto("files")
+	assert.True(t, ok)
+	assert.Equal(t, BackupAppAuto{Enabled: true, Mode: "restore", Schedule: "0 2 * * *"}, auto)
+	assert.Equal(t, []string{"files", "syncthing"}, config.ListBackupAutoApps())
+	assert.Equal(t, "backup", config.GetBackupAuto())
+
+	config.RemoveBackupAppAuto("syncthing")
+	_, ok = config.GetBackupAppAuto("syncthing")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"files"}, config.ListBackupAutoApps())
+}
+
 func TestDeviceUrl(t *testing.T) {
 	config, _ := newTestUserConfig(t)
 	config.SetCustomDomain("domain.tld")
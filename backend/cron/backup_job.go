@@ -3,17 +3,20 @@ package cron
 import (
 	"fmt"
 	"github.com/syncloud/platform/backup"
+	"github.com/syncloud/platform/config"
 	"github.com/syncloud/platform/date"
+	"github.com/syncloud/platform/job"
 	"github.com/syncloud/platform/snap/model"
+	"github.com/syncloud/platform/stability"
 	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
 	"time"
 )
 
 const (
-	AutoNo      = "no"
-	AutoBackup  = "backup"
-	AutoRestore = "restore"
+	AutoNo      = backup.AutoNo
+	AutoBackup  = backup.AutoBackup
+	AutoRestore = backup.AutoRestore
 )
 
 type BackupJob struct {
@@ -22,6 +25,9 @@ type BackupJob struct {
 	backup    Backup
 	provider  date.Provider
 	scheduler Scheduler
+	history   History
+	verifier  Verifier
+	events    Events
 	logger    *zap.Logger
 }
 
@@ -31,29 +37,47 @@ type Snapd interface {
 
 type UserConfig interface {
 	GetBackupAuto() string
-	GetBackupAutoDay() int
-	GetBackupAutoHour() int
+	GetBackupAppAuto(app string) (config.BackupAppAuto, bool)
 	GetBackupAppTime(string, string) time.Time
 	SetBackupAppTime(string, string, time.Time)
+	GetBackupRestoredFile(app string) string
+	SetBackupRestoredFile(app string, file string)
 }
 
 type Backup interface {
 	Create(app string) error
 	Restore(fileName string) error
 	List() ([]backup.File, error)
+	ApplyRetention(app string) (backup.RetentionPlan, error)
+}
+
+type History interface {
+	Add(record config.JobRecord) error
+}
+
+type Verifier interface {
+	Verify(app string) error
+}
+
+type Events interface {
+	Append(e stability.Event) error
 }
 
 type Scheduler interface {
-	ShouldRun(day int, hour int, now time.Time, last time.Time) bool
+	ShouldRun(job string, now time.Time, last time.Time) bool
+	ShouldRunExpression(spec string, now time.Time, last time.Time) bool
 }
 
-func NewBackupJob(snapd Snapd, config UserConfig, backup Backup, provider date.Provider, scheduler Scheduler, logger *zap.Logger) *BackupJob {
+func NewBackupJob(snapd Snapd, config UserConfig, backup Backup, provider date.Provider, scheduler Scheduler, history History, verifier Verifier, events Events, logger *zap.Logger) *BackupJob {
 	return &BackupJob{
 		snapd:     snapd,
 		config:    config,
 		backup:    backup,
 		provider:  provider,
 		scheduler: scheduler,
+		history:   history,
+		verifier:  verifier,
+		events:    events,
 		logger:    logger,
 	}
 }
@@ -64,51 +88,125 @@ func (j *BackupJob) Run() error {
 		return err
 	}
 	auto := j.config.GetBackupAuto()
-	if auto == AutoNo {
-		j.logger.Info("auto backup is disabled")
-		return nil
-	}
-	day := j.config.GetBackupAutoDay()
-	hour := j.config.GetBackupAutoHour()
 	now := j.provider.Now()
 	for _, app := range apps {
-		if app.Id == "syncthing" {
-			j.logger.Info("syncthing app is excluded as it is used as a backup network transport")
+		mode, schedule := j.appAuto(app.Id, auto)
+		if mode == AutoNo {
 			continue
 		}
-		last := j.config.GetBackupAppTime(app.Id, auto)
-		if j.scheduler.ShouldRun(day, hour, now, last) {
-			if auto == AutoBackup {
-				j.runBackup(app, now)
-			} else {
-				j.runRestore(app, now)
-			}
+		last := j.config.GetBackupAppTime(app.Id, mode)
+		if !j.shouldRun(schedule, now, last) {
+			continue
+		}
+		if mode == AutoBackup {
+			j.runBackup(app, now)
+		} else {
+			j.runRestore(app, now)
 		}
 	}
 	return nil
 }
 
+// appAuto applies the app settings over the global mode, an empty schedule means the global one.
+func (j *BackupJob) appAuto(app string, auto string) (string, string) {
+	appAuto, ok := j.config.GetBackupAppAuto(app)
+	if !ok {
+		return auto, ""
+	}
+	if !appAuto.Enabled {
+		return AutoNo, ""
+	}
+	if appAuto.Mode != "" {
+		auto = appAuto.Mode
+	}
+	return auto, appAuto.Schedule
+}
+
+func (j *BackupJob) shouldRun(schedule string, now time.Time, last time.Time) bool {
+	if schedule == "" {
+		return j.scheduler.ShouldRun(JobBackup, now, last)
+	}
+	return j.scheduler.ShouldRunExpression(schedule, now, last)
+}
+
+// runRestore keeps a standby device up to date: a new archive is restored and verified,
+// an archive which is already restored is skipped.
 func (j *BackupJob) runRestore(app model.SyncloudApp, now time.Time) {
 	latestBackup, err := j.LatestBackup(app.Id)
 	if err != nil {
 		j.logger.Info("no backups to restore yet", zap.String("app", app.Id))
 		return
 	}
+	if latestBackup == j.config.GetBackupRestoredFile(app.Id) {
+		j.logger.Info("latest backup is already restored", zap.String("app", app.Id), zap.String("file", latestBackup))
+		j.config.SetBackupAppTime(app.Id, AutoRestore, now)
+		return
+	}
+	started := j.provider.Now()
 	err = j.backup.Restore(latestBackup)
+	j.record("backup.restore", started, err)
 	if err != nil {
 		j.logger.Error("failed", zap.String("app", app.Id), zap.Error(err))
+		j.event(stability.EventKindRestoreFailed, app.Id, latestBackup, err)
 		return
 	}
+	j.config.SetBackupRestoredFile(app.Id, latestBackup)
 	j.config.SetBackupAppTime(app.Id, AutoRestore, now)
+	err = j.verifier.Verify(app.Id)
+	if err != nil {
+		j.logger.Error("restored app verification failed", zap.String("app", app.Id), zap.Error(err))
+		j.event(stability.EventKindRestoreBroken, app.Id, latestBackup, err)
+		return
+	}
+	j.event(stability.EventKindRestoreOk, app.Id, latestBackup, nil)
+}
+
+func (j *BackupJob) event(kind stability.EventKind, app string, file string, result error) {
+	event := stability.Event{Kind: kind, App: app, Path: file}
+	if result != nil {
+		event.Message = result.Error()
+	}
+	err := j.events.Append(event)
+	if err != nil {
+		j.logger.Warn("cannot record event", zap.Error(err))
+	}
 }
 
 func (j *BackupJob) runBackup(app model.SyncloudApp, now time.Time) {
+	started := j.provider.Now()
 	err := j.backup.Create(app.Id)
+	j.record("backup.create", started, err)
 	if err != nil {
 		j.logger.Error("failed", zap.String("app", app.Id), zap.Error(err))
 		return
 	}
 	j.config.SetBackupAppTime(app.Id, AutoBackup, now)
+	plan, err := j.backup.ApplyRetention(app.Id)
+	if err != nil {
+		j.logger.Error("retention failed", zap.String("app", app.Id), zap.Error(err))
+		return
+	}
+	if len(plan.Remove) > 0 {
+		j.logger.Info("retention removed backups", zap.String("app", app.Id), zap.Int("count", len(plan.Remove)))
+	}
+}
+
+func (j *BackupJob) record(name string, started time.Time, result error) {
+	record := config.JobRecord{
+		Name:     name,
+		Source:   job.SourceCron,
+		Started:  started,
+		Finished: j.provider.Now(),
+		Result:   job.Succeeded,
+	}
+	if result != nil {
+		record.Result = job.Failed
+		record.Error = result.Error()
+	}
+	err := j.history.Add(record)
+	if err != nil {
+		j.logger.Warn("cannot record job history", zap.Error(err))
+	}
 }
 
 func (j *BackupJob) LatestBackup(app string) (string, error) {
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/syncloud/platform/backup"
+	"github.com/syncloud/platform/config"
 	"github.com/syncloud/platform/log"
 	"github.com/syncloud/platform/snap/model"
+	"github.com/syncloud/platform/stability"
 	"testing"
 	"time"
 )
@@ -13,9 +15,13 @@ import (
 type SnapdStub struct {
 	appName string
 	appID   string
+	apps    []model.SyncloudApp
 }
 
 func (s *SnapdStub) InstalledUserApps() ([]model.SyncloudApp, error) {
+	if s.apps != nil {
+		return s.apps, nil
+	}
 	return []model.SyncloudApp{{Name: s.appName, Id: s.appID}}, nil
 }
 
@@ -26,6 +32,24 @@ type UserConfigStub struct {
 	lastTime time.Time
 	lastMode string
 	lastApp  string
+	apps     map[string]config.BackupAppAuto
+	restored map[string]string
+}
+
+func (c *UserConfigStub) GetBackupRestoredFile(app string) string {
+	return c.restored[app]
+}
+
+func (c *UserConfigStub) SetBackupRestoredFile(app string, file string) {
+	if c.restored == nil {
+		c.restored = make(map[string]string)
+	}
+	c.restored[app] = file
+}
+
+func (c *UserConfigStub) GetBackupAppAuto(app string) (config.BackupAppAuto, bool) {
+	auto, ok := c.apps[app]
+	return auto, ok
 }
 
 func (c *UserConfigStub) GetBackupAuto() string {
@@ -55,6 +79,7 @@ type BackupStub struct {
 	err      error
 	created  bool
 	restored bool
+	pruned   string
 	list     []backup.File
 }
 
@@ -78,6 +103,39 @@ func (b *BackupStub) List() ([]backup.File, error) {
 	return b.list, nil
 }
 
+func (b *BackupStub) ApplyRetention(app string) (backup.RetentionPlan, error) {
+	b.pruned = app
+	return backup.RetentionPlan{}, nil
+}
+
+type HistoryStub struct {
+	records []config.JobRecord
+}
+
+func (h *HistoryStub) Add(record config.JobRecord) error {
+	h.records = append(h.records, record)
+	return nil
+}
+
+type VerifierStub struct {
+	err      error
+	verified []string
+}
+
+func (v *VerifierStub) Verify(app string) error {
+	v.verified = append(v.verified, app)
+	return v.err
+}
+
+type EventsStub struct {
+	events []stability.Event
+}
+
+func (e *EventsStub) Append(event stability.Event) error {
+	e.events = append(e.events, event)
+	return nil
+}
+
 type ProviderStub struct {
 	now time.Time
 }
@@ -87,10 +145,16 @@ func (p ProviderStub) Now() time.Time {
 }
 
 type SchedulerStub struct {
-	run bool
+	run   bool
+	specs []string
+}
+
+func (s *SchedulerStub) ShouldRun(_ string, _ time.Time, _ time.Time) bool {
+	return s.run
 }
 
-func (s *SchedulerStub) ShouldRun(_ int, _ int, _ time.Time, _ time.Time) bool {
+func (s *SchedulerStub) ShouldRunExpression(spec string, _ time.Time, _ time.Time) bool {
+	s.specs = append(s.specs, spec)
 	return s.run
 }
 
@@ -100,7 +164,7 @@ func TestRun_Disabled(t *testing.T) {
 	backuper := &BackupStub{}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
 	err := job.Run()
 
 	assert.Nil(t, err)
@@ -114,7 +178,7 @@ func TestRun_Backup(t *testing.T) {
 	backuper := &BackupStub{}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{run: true}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
 	err := job.Run()
 
 	assert.Nil(t, err)
@@ -122,6 +186,7 @@ func TestRun_Backup(t *testing.T) {
 	assert.False(t, backuper.restored)
 	assert.Equal(t, "backup", config.lastMode)
 	assert.Equal(t, "app1", config.lastApp)
+	assert.Equal(t, "app1", backuper.pruned)
 }
 
 func TestRun_Backup_Failed(t *testing.T) {
@@ -130,7 +195,8 @@ func TestRun_Backup_Failed(t *testing.T) {
 	backuper := &BackupStub{err: fmt.Errorf("expected error")}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{run: true}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	history := &HistoryStub{}
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, history, &VerifierStub{}, &EventsStub{}, log.Default())
 	err := job.Run()
 
 	assert.Nil(t, err)
@@ -138,6 +204,12 @@ func TestRun_Backup_Failed(t *testing.T) {
 	assert.False(t, backuper.restored)
 	assert.Equal(t, "", config.lastMode)
 	assert.Equal(t, "", config.lastApp)
+	assert.Equal(t, "", backuper.pruned)
+	assert.Len(t, history.records, 1)
+	assert.Equal(t, "backup.create", history.records[0].Name)
+	assert.Equal(t, "cron", history.records[0].Source)
+	assert.Equal(t, "failed", history.records[0].Result)
+	assert.Equal(t, "expected error", history.records[0].Error)
 }
 
 func TestRun_Restore(t *testing.T) {
@@ -150,7 +222,7 @@ func TestRun_Restore(t *testing.T) {
 	}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{run: true}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
 	err := job.Run()
 
 	assert.Nil(t, err)
@@ -160,13 +232,46 @@ func TestRun_Restore(t *testing.T) {
 	assert.Equal(t, "app1", config.lastApp)
 }
 
+func TestRun_Restore_Verify(t *testing.T) {
+	snapd := &SnapdStub{appID: "app1", appName: "App 1"}
+	config := &UserConfigStub{auto: "restore"}
+	backuper := &BackupStub{
+		list: []backup.File{{Path: "/data/platform/backup", File: "app1-2020-0514-061314.tar.gz", App: "app1"}},
+	}
+	verifier := &VerifierStub{}
+	events := &EventsStub{}
+	job := NewBackupJob(snapd, config, backuper, &ProviderStub{}, &SchedulerStub{run: true}, &HistoryStub{}, verifier, events, log.Default())
+
+	assert.Nil(t, job.Run())
+	assert.True(t, backuper.restored)
+	assert.Equal(t, []string{"app1"}, verifier.verified)
+	assert.Equal(t, "app1-2020-0514-061314.tar.gz", config.restored["app1"])
+	assert.Len(t, events.events, 1)
+	assert.Equal(t, stability.EventKindRestoreOk, events.events[0].Kind)
+	assert.Equal(t, "app1", events.events[0].App)
+
+	backuper.restored = false
+	assert.Nil(t, job.Run())
+	assert.False(t, backuper.restored)
+	assert.Len(t, verifier.verified, 1)
+
+	backuper.list = append(backuper.list, backup.File{Path: "/data/platform/backup", File: "app1-2020-0515-061314.tar.gz", App: "app1"})
+	verifier.err = fmt.Errorf("status 502")
+	assert.Nil(t, job.Run())
+	assert.True(t, backuper.restored)
+	assert.Equal(t, "app1-2020-0515-061314.tar.gz", config.restored["app1"])
+	assert.Len(t, events.events, 2)
+	assert.Equal(t, stability.EventKindRestoreBroken, events.events[1].Kind)
+	assert.Equal(t, "status 502", events.events[1].Message)
+}
+
 func TestRun_Restore_Failed(t *testing.T) {
 	snapd := &SnapdStub{appID: "app1", appName: "App 1"}
 	config := &UserConfigStub{auto: "restore"}
 	backuper := &BackupStub{err: fmt.Errorf("expected error")}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{run: true}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
 	err := job.Run()
 
 	assert.Nil(t, err)
@@ -176,6 +281,39 @@ func TestRun_Restore_Failed(t *testing.T) {
 	assert.Equal(t, "", config.lastApp)
 }
 
+func TestRun_AppModeAndSchedule(t *testing.T) {
+	snapd := &SnapdStub{apps: []model.SyncloudApp{{Id: "app1"}, {Id: "app2"}}}
+	config := &UserConfigStub{auto: "no", apps: map[string]config.BackupAppAuto{
+		"app2": {Enabled: true, Mode: "backup", Schedule: "0 2 * * *"},
+	}}
+	backuper := &BackupStub{}
+	scheduler := &SchedulerStub{run: true}
+	job := NewBackupJob(snapd, config, backuper, &ProviderStub{}, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
+	err := job.Run()
+
+	assert.Nil(t, err)
+	assert.True(t, backuper.created)
+	assert.Equal(t, "app2", config.lastApp)
+	assert.Equal(t, "backup", config.lastMode)
+	assert.Equal(t, []string{"0 2 * * *"}, scheduler.specs)
+}
+
+func TestRun_AppMode_OverridesGlobal(t *testing.T) {
+	snapd := &SnapdStub{appID: "app1", appName: "App 1"}
+	config := &UserConfigStub{auto: "backup", apps: map[string]config.BackupAppAuto{"app1": {Enabled: true, Mode: "restore"}}}
+	backuper := &BackupStub{
+		list: []backup.File{{Path: "/data/platform/backup", File: "app1-2020-0514-061314.tar.gz", App: "app1"}},
+	}
+	scheduler := &SchedulerStub{run: true}
+	job := NewBackupJob(snapd, config, backuper, &ProviderStub{}, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
+	err := job.Run()
+
+	assert.Nil(t, err)
+	assert.False(t, backuper.created)
+	assert.True(t, backuper.restored)
+	assert.Empty(t, scheduler.specs)
+}
+
 func TestBackupJob_LatestBackup(t *testing.T) {
 	snapd := &SnapdStub{appID: "app1", appName: "App 1"}
 	config := &UserConfigStub{auto: "restore"}
@@ -195,7 +333,7 @@ func TestBackupJob_LatestBackup(t *testing.T) {
 	}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{run: true}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
 	latest, err := job.LatestBackup("rocketchat")
 	assert.Nil(t, err)
 	assert.Equal(t, "rocketchat-2022-0129-231530.tar.gz", latest)
@@ -203,11 +341,11 @@ func TestBackupJob_LatestBackup(t *testing.T) {
 
 func TestRun_Backup_Syncthing_exclude(t *testing.T) {
 	snapd := &SnapdStub{appID: "syncthing", appName: "App 1"}
-	config := &UserConfigStub{auto: "backup"}
+	config := &UserConfigStub{auto: "backup", apps: map[string]config.BackupAppAuto{"syncthing": {Enabled: false}}}
 	backuper := &BackupStub{}
 	timeProvider := &ProviderStub{}
 	scheduler := &SchedulerStub{run: true}
-	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, log.Default())
+	job := NewBackupJob(snapd, config, backuper, timeProvider, scheduler, &HistoryStub{}, &VerifierStub{}, &EventsStub{}, log.Default())
 	err := job.Run()
 
 	assert.Nil(t, err)
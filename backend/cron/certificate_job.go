@@ -1,19 +1,36 @@
 package cron
 
 import (
+	"time"
+
 	"github.com/syncloud/platform/cert"
+	"github.com/syncloud/platform/date"
 )
 
 type CertificateJob struct {
 	certGenerator cert.Generator
+	scheduler     Scheduler
+	provider      date.Provider
+	lastRun       time.Time
 }
 
-func NewCertificateJob(certGenerator cert.Generator) *CertificateJob {
+func NewCertificateJob(certGenerator cert.Generator, scheduler Scheduler, provider date.Provider) *CertificateJob {
 	return &CertificateJob{
 		certGenerator: certGenerator,
+		scheduler:     scheduler,
+		provider:      provider,
 	}
 }
 
 func (j *CertificateJob) Run() error {
-	return j.certGenerator.Generate()
+	now := j.provider.Now()
+	if !j.scheduler.ShouldRun(JobCertificate, now, j.lastRun) {
+		return nil
+	}
+	err := j.certGenerator.Generate()
+	if err != nil {
+		return err
+	}
+	j.lastRun = now
+	return nil
 }
@@ -0,0 +1,163 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a standard 5 field cron expression: minute, hour, day of month, month and day of week.
+// Fields accept *, lists, ranges, steps and month or weekday names, Sunday is 0 or 7.
+type Expression struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDom bool
+	anyDow bool
+	spec   string
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+func Parse(spec string) (*Expression, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression should have 5 fields: %s", spec)
+	}
+	e := &Expression{spec: strings.Join(fields, " ")}
+	var err error
+	if e.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if e.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if e.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if e.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if e.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if e.dow&(1<<7) != 0 {
+		e.dow |= 1
+	}
+	e.anyDom = strings.HasPrefix(fields[2], "*")
+	e.anyDow = strings.HasPrefix(fields[4], "*")
+	return e, nil
+}
+
+func (e *Expression) String() string {
+	return e.spec
+}
+
+func (f field) parse(value string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		from, to, step := f.min, f.max, 1
+		rangePart := part
+		if i := strings.Index(part, "/"); i >= 0 {
+			parsed, err := strconv.Atoi(part[i+1:])
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("invalid %s step: %s", f.name, part)
+			}
+			step = parsed
+			rangePart = part[:i]
+		}
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			from, err = f.value(bounds[0])
+			if err != nil {
+				return 0, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				to, err = f.value(bounds[1])
+				if err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				to = f.max
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid %s range: %s", f.name, part)
+			}
+		}
+		for i := from; i <= to; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(value string) (int, error) {
+	if number, ok := f.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < f.min || number > f.max {
+		return 0, fmt.Errorf("invalid %s: %s", f.name, value)
+	}
+	return number, nil
+}
+
+// Next returns the first matching minute after the given time, in the location of that time.
+// A zero time is returned when nothing matches within five years (e.g. 30th of February).
+func (e *Expression) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !e.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay follows the classic cron rule: when both day fields are restricted either of them matches.
+func (e *Expression) matchDay(t time.Time) bool {
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.anyDom || e.anyDow {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
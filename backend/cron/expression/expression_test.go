@@ -0,0 +1,52 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func next(t *testing.T, spec string, after time.Time) time.Time {
+	expression, err := Parse(spec)
+	assert.NoError(t, err)
+	return expression.Next(after)
+}
+
+func TestExpression_Next(t *testing.T) {
+	from := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 31, 10, 18, 0, 0, time.UTC), next(t, "* * * * *", from))
+	assert.Equal(t, time.Date(2024, 1, 31, 10, 20, 0, 0, time.UTC), next(t, "*/5 * * * *", from))
+	assert.Equal(t, time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC), next(t, "0 3 * * *", from))
+	assert.Equal(t, time.Date(2024, 2, 4, 3, 30, 0, 0, time.UTC), next(t, "30 3 * * 0", from))
+	assert.Equal(t, time.Date(2024, 2, 4, 3, 30, 0, 0, time.UTC), next(t, "30 3 * * 7", from))
+	assert.Equal(t, time.Date(2024, 2, 3, 3, 30, 0, 0, time.UTC), next(t, "30 3 * * Sat", from))
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), next(t, "0 0 29 feb *", from))
+	assert.Equal(t, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), next(t, "0 8-18/4 * * *", from))
+	assert.Equal(t, time.Date(2024, 1, 31, 22, 0, 0, 0, time.UTC), next(t, "0 10,22 * * *", from))
+}
+
+func TestExpression_Next_DayOfMonthOrWeek(t *testing.T) {
+	from := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), next(t, "0 0 1 * 1", from))
+	assert.Equal(t, time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), next(t, "0 0 */2 * 1", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestExpression_Next_Location(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	from := time.Date(2024, 1, 31, 10, 0, 0, 0, location)
+	result := next(t, "0 3 * * *", from)
+	assert.Equal(t, time.Date(2024, 2, 1, 2, 0, 0, 0, time.UTC), result.UTC())
+}
+
+func TestExpression_Next_NeverMatches(t *testing.T) {
+	assert.True(t, next(t, "0 0 30 2 *", time.Now()).IsZero())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
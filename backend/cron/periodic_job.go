@@ -0,0 +1,32 @@
+package cron
+
+import (
+	"time"
+
+	"github.com/syncloud/platform/date"
+)
+
+// PeriodicJob runs a job without a schedule at a fixed interval, the cron loop itself ticks every minute.
+type PeriodicJob struct {
+	job      Job
+	interval time.Duration
+	provider date.Provider
+	lastRun  time.Time
+}
+
+func NewPeriodicJob(job Job, interval time.Duration, provider date.Provider) *PeriodicJob {
+	return &PeriodicJob{
+		job:      job,
+		interval: interval,
+		provider: provider,
+	}
+}
+
+func (j *PeriodicJob) Run() error {
+	now := j.provider.Now()
+	if now.Sub(j.lastRun) < j.interval {
+		return nil
+	}
+	j.lastRun = now
+	return j.job.Run()
+}
@@ -0,0 +1,107 @@
+package cron
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/syncloud/platform/snap/model"
+	"go.uber.org/zap"
+)
+
+const RestoreVerifyCmd = "restore-verify"
+
+type InstalledSnaps interface {
+	FindInstalled(name string) (*model.Snap, error)
+}
+
+type SnapCommands interface {
+	RunCmdIfExists(snap model.Snap, name string, args ...string) error
+}
+
+type AppUrl interface {
+	Url(app string) string
+}
+
+type ProbeClient interface {
+	Get(url string) (*http.Response, error)
+}
+
+// RestoreVerifier checks that a restored app works, with the app restore-verify hook
+// or, for apps without one, with an HTTP request to the app url.
+type RestoreVerifier struct {
+	snaps    InstalledSnaps
+	commands SnapCommands
+	urls     AppUrl
+	client   ProbeClient
+	attempts int
+	delay    time.Duration
+	logger   *zap.Logger
+}
+
+func NewRestoreVerifier(snaps InstalledSnaps, commands SnapCommands, urls AppUrl, logger *zap.Logger) *RestoreVerifier {
+	return &RestoreVerifier{
+		snaps:    snaps,
+		commands: commands,
+		urls:     urls,
+		client:   localClient(),
+		attempts: 24,
+		delay:    5 * time.Second,
+		logger:   logger,
+	}
+}
+
+// localClient sends requests to the local nginx, so the probe does not depend on DNS or NAT loopback,
+// certificates are not checked as a restored standby device may not have a valid one yet.
+func localClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, "127.0.0.1:443")
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: 10 * time.Second,
+	}
+}
+
+func (v *RestoreVerifier) Verify(app string) error {
+	snap, err := v.snaps.FindInstalled(app)
+	if err != nil {
+		return err
+	}
+	if snap != nil && snap.FindCommand(RestoreVerifyCmd) != nil {
+		v.logger.Info("running restore verify hook", zap.String("app", app))
+		return v.commands.RunCmdIfExists(*snap, RestoreVerifyCmd)
+	}
+	return v.probe(v.urls.Url(app))
+}
+
+// probe waits for the app to start answering, any response below 500 (including login redirects) is healthy.
+func (v *RestoreVerifier) probe(url string) error {
+	var lastErr error
+	for i := 0; i < v.attempts; i++ {
+		if i > 0 {
+			time.Sleep(v.delay)
+		}
+		resp, err := v.client.Get(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < http.StatusInternalServerError {
+			v.logger.Info("restored app is healthy", zap.String("url", url), zap.Int("status", resp.StatusCode))
+			return nil
+		}
+		lastErr = fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("health probe %s: %w", url, lastErr)
+}
@@ -0,0 +1,92 @@
+package cron
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/snap/model"
+)
+
+type InstalledSnapsStub struct {
+	snap *model.Snap
+}
+
+func (s *InstalledSnapsStub) FindInstalled(_ string) (*model.Snap, error) {
+	return s.snap, nil
+}
+
+type SnapCommandsStub struct {
+	err error
+	ran []string
+}
+
+func (s *SnapCommandsStub) RunCmdIfExists(snap model.Snap, name string, _ ...string) error {
+	s.ran = append(s.ran, snap.Name+"."+name)
+	return s.err
+}
+
+type AppUrlStub struct{}
+
+func (a *AppUrlStub) Url(app string) string {
+	return fmt.Sprintf("https://%s.example.com", app)
+}
+
+type ProbeClientStub struct {
+	statuses []int
+	urls     []string
+}
+
+func (p *ProbeClientStub) Get(url string) (*http.Response, error) {
+	p.urls = append(p.urls, url)
+	if len(p.statuses) == 0 {
+		return nil, fmt.Errorf("connection refused")
+	}
+	status := p.statuses[0]
+	p.statuses = p.statuses[1:]
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func newTestVerifier(snap *model.Snap, commands *SnapCommandsStub, client *ProbeClientStub) *RestoreVerifier {
+	verifier := NewRestoreVerifier(&InstalledSnapsStub{snap: snap}, commands, &AppUrlStub{}, log.Default())
+	verifier.client = client
+	verifier.attempts = 3
+	verifier.delay = 0
+	return verifier
+}
+
+func TestRestoreVerifier_Hook(t *testing.T) {
+	snap := &model.Snap{Name: "files", Apps: []model.App{{Name: RestoreVerifyCmd}}}
+	commands := &SnapCommandsStub{}
+	client := &ProbeClientStub{}
+	verifier := newTestVerifier(snap, commands, client)
+
+	assert.NoError(t, verifier.Verify("files"))
+	assert.Equal(t, []string{"files.restore-verify"}, commands.ran)
+	assert.Empty(t, client.urls)
+
+	commands.err = fmt.Errorf("database is empty")
+	assert.Error(t, verifier.Verify("files"))
+}
+
+func TestRestoreVerifier_Probe(t *testing.T) {
+	client := &ProbeClientStub{statuses: []int{502, 302}}
+	verifier := newTestVerifier(&model.Snap{Name: "files"}, &SnapCommandsStub{}, client)
+
+	assert.NoError(t, verifier.Verify("files"))
+	assert.Equal(t, []string{"https://files.example.com", "https://files.example.com"}, client.urls)
+}
+
+func TestRestoreVerifier_Probe_Failed(t *testing.T) {
+	client := &ProbeClientStub{statuses: []int{500, 503}}
+	verifier := newTestVerifier(&model.Snap{Name: "files"}, &SnapCommandsStub{}, client)
+
+	err := verifier.Verify("files")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Len(t, client.urls, 3)
+}
@@ -0,0 +1,156 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/syncloud/platform/cron/expression"
+	"github.com/syncloud/platform/date"
+	"go.uber.org/zap"
+)
+
+const (
+	JobBackup       = "backup"
+	JobCertificate  = "certificate"
+	JobSnapdUpgrade = "snapd_upgrade"
+	JobTimeSync     = "time_sync"
+)
+
+// CatchUp is how long a missed run (device was off, job failed) is still started after its time.
+const CatchUp = time.Hour
+
+var defaultSchedules = map[string]string{
+	JobCertificate:  "*/5 * * * *",
+	JobSnapdUpgrade: "0 5 * * *",
+	JobTimeSync:     "0 4 * * *",
+}
+
+var scheduledJobs = []string{JobBackup, JobCertificate, JobSnapdUpgrade, JobTimeSync}
+
+type ScheduleConfig interface {
+	GetTimezone() string
+	GetCronSchedule(job string) *string
+	SetCronSchedule(job string, expression string)
+	GetBackupAutoDay() int
+	GetBackupAutoHour() int
+}
+
+type Schedule struct {
+	Job        string     `json:"job"`
+	Expression string     `json:"expression"`
+	Default    bool       `json:"default"`
+	Timezone   string     `json:"timezone"`
+	Next       *time.Time `json:"next,omitempty"`
+}
+
+// Schedules keeps cron expressions of the platform jobs, evaluated in the device timezone.
+type Schedules struct {
+	config   ScheduleConfig
+	provider date.Provider
+	logger   *zap.Logger
+}
+
+func NewSchedules(config ScheduleConfig, provider date.Provider, logger *zap.Logger) *Schedules {
+	return &Schedules{
+		config:   config,
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+func (s *Schedules) expression(job string) (string, bool) {
+	spec := s.config.GetCronSchedule(job)
+	if spec != nil {
+		return *spec, false
+	}
+	if job == JobBackup {
+		return s.backupDefault(), true
+	}
+	return defaultSchedules[job], true
+}
+
+// backupDefault keeps the older day and hour backup settings working until an expression is set.
+func (s *Schedules) backupDefault() string {
+	day := s.config.GetBackupAutoDay()
+	hour := s.config.GetBackupAutoHour()
+	if day == 0 {
+		return fmt.Sprintf("0 %d * * *", hour)
+	}
+	return fmt.Sprintf("0 %d * * %d", hour, day)
+}
+
+func (s *Schedules) location() *time.Location {
+	timezone := s.config.GetTimezone()
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		s.logger.Warn("unknown timezone, using UTC", zap.String("timezone", timezone), zap.Error(err))
+		return time.UTC
+	}
+	return location
+}
+
+func (s *Schedules) List() []Schedule {
+	var schedules []Schedule
+	location := s.location()
+	now := s.provider.Now().In(location)
+	for _, job := range scheduledJobs {
+		spec, isDefault := s.expression(job)
+		schedule := Schedule{Job: job, Expression: spec, Default: isDefault, Timezone: location.String()}
+		parsed, err := expression.Parse(spec)
+		if err != nil {
+			s.logger.Warn("invalid cron expression", zap.String("job", job), zap.Error(err))
+		} else if next := parsed.Next(now); !next.IsZero() {
+			schedule.Next = &next
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules
+}
+
+// Set changes the expression of a job, an empty expression resets it to the default.
+func (s *Schedules) Set(job string, spec string) error {
+	if !s.known(job) {
+		return fmt.Errorf("unknown job: %s", job)
+	}
+	if spec != "" {
+		parsed, err := expression.Parse(spec)
+		if err != nil {
+			return err
+		}
+		spec = parsed.String()
+	}
+	s.config.SetCronSchedule(job, spec)
+	return nil
+}
+
+func (s *Schedules) known(job string) bool {
+	for _, scheduled := range scheduledJobs {
+		if scheduled == job {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldRun is true when a scheduled time of the job is after last and not later than now,
+// runs missed for longer than CatchUp are skipped.
+func (s *Schedules) ShouldRun(job string, now time.Time, last time.Time) bool {
+	spec, _ := s.expression(job)
+	return s.ShouldRunExpression(spec, now, last)
+}
+
+// ShouldRunExpression is ShouldRun for an expression which is not a platform job schedule (per app backups).
+func (s *Schedules) ShouldRunExpression(spec string, now time.Time, last time.Time) bool {
+	parsed, err := expression.Parse(spec)
+	if err != nil {
+		s.logger.Warn("invalid cron expression", zap.String("expression", spec), zap.Error(err))
+		return false
+	}
+	location := s.location()
+	from := now.Add(-CatchUp)
+	if last.After(from) {
+		from = last
+	}
+	next := parsed.Next(from.In(location))
+	return !next.IsZero() && !next.After(now)
+}
@@ -0,0 +1,107 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+)
+
+type ScheduleConfigStub struct {
+	timezone  string
+	schedules map[string]string
+	day       int
+	hour      int
+}
+
+func (c *ScheduleConfigStub) GetTimezone() string {
+	if c.timezone == "" {
+		return "UTC"
+	}
+	return c.timezone
+}
+
+func (c *ScheduleConfigStub) GetCronSchedule(job string) *string {
+	expression, ok := c.schedules[job]
+	if !ok {
+		return nil
+	}
+	return &expression
+}
+
+func (c *ScheduleConfigStub) SetCronSchedule(job string, expression string) {
+	if c.schedules == nil {
+		c.schedules = map[string]string{}
+	}
+	if expression == "" {
+		delete(c.schedules, job)
+		return
+	}
+	c.schedules[job] = expression
+}
+
+func (c *ScheduleConfigStub) GetBackupAutoDay() int {
+	return c.day
+}
+
+func (c *ScheduleConfigStub) GetBackupAutoHour() int {
+	return c.hour
+}
+
+var monday0am = time.Date(2022, 11, 21, 0, 0, 0, 0, time.UTC)
+
+func TestSchedules_ShouldRun_Backup_FromDayAndHour(t *testing.T) {
+	schedules := NewSchedules(&ScheduleConfigStub{day: 1, hour: 0}, &DateProviderStub{}, log.Default())
+	assert.True(t, schedules.ShouldRun(JobBackup, monday0am, time.Time{}))
+	assert.False(t, schedules.ShouldRun(JobBackup, monday0am, monday0am))
+	assert.False(t, schedules.ShouldRun(JobBackup, monday0am.Add(25*time.Hour), time.Time{}))
+	assert.True(t, schedules.ShouldRun(JobBackup, monday0am.AddDate(0, 0, 7), monday0am))
+}
+
+func TestSchedules_ShouldRun_CatchUp(t *testing.T) {
+	schedules := NewSchedules(&ScheduleConfigStub{}, &DateProviderStub{}, log.Default())
+	at5 := time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC)
+	assert.True(t, schedules.ShouldRun(JobSnapdUpgrade, at5.Add(30*time.Minute), time.Time{}))
+	assert.False(t, schedules.ShouldRun(JobSnapdUpgrade, at5.Add(2*time.Hour), time.Time{}))
+	assert.False(t, schedules.ShouldRun(JobSnapdUpgrade, at5.Add(30*time.Minute), at5))
+}
+
+func TestSchedules_ShouldRun_Timezone(t *testing.T) {
+	config := &ScheduleConfigStub{timezone: "America/New_York"}
+	schedules := NewSchedules(config, &DateProviderStub{}, log.Default())
+	assert.NoError(t, schedules.Set(JobSnapdUpgrade, "0 1 * * *"))
+	assert.True(t, schedules.ShouldRun(JobSnapdUpgrade, time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC), time.Time{}))
+	assert.False(t, schedules.ShouldRun(JobSnapdUpgrade, time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC), time.Time{}))
+}
+
+func TestSchedules_Set(t *testing.T) {
+	config := &ScheduleConfigStub{}
+	schedules := NewSchedules(config, &DateProviderStub{}, log.Default())
+
+	assert.Error(t, schedules.Set("unknown", "* * * * *"))
+	assert.Error(t, schedules.Set(JobCertificate, "* * *"))
+	assert.NoError(t, schedules.Set(JobCertificate, " 0  */6 * * * "))
+	assert.Equal(t, "0 */6 * * *", config.schedules[JobCertificate])
+
+	assert.NoError(t, schedules.Set(JobCertificate, ""))
+	assert.Empty(t, config.schedules)
+}
+
+func TestSchedules_List(t *testing.T) {
+	config := &ScheduleConfigStub{timezone: "Europe/Berlin", hour: 3}
+	provider := &DateProviderStub{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	schedules := NewSchedules(config, provider, log.Default())
+	assert.NoError(t, schedules.Set(JobTimeSync, "30 2 * * *"))
+
+	list := schedules.List()
+	assert.Len(t, list, 4)
+	assert.Equal(t, JobBackup, list[0].Job)
+	assert.Equal(t, "0 3 * * *", list[0].Expression)
+	assert.True(t, list[0].Default)
+	assert.Equal(t, "Europe/Berlin", list[0].Timezone)
+	assert.Equal(t, time.Date(2024, 3, 2, 2, 0, 0, 0, time.UTC), list[0].Next.UTC())
+	assert.Equal(t, JobTimeSync, list[3].Job)
+	assert.False(t, list[3].Default)
+	assert.Equal(t, time.Date(2024, 3, 2, 1, 30, 0, 0, time.UTC), list[3].Next.UTC())
+}
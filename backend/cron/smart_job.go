@@ -0,0 +1,132 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/storage/model"
+	"go.uber.org/zap"
+)
+
+type SmartDisks interface {
+	AllDisks() ([]model.Disk, error)
+}
+
+type SmartChecker interface {
+	Health(device string) (*model.SmartHealth, error)
+}
+
+var smartStatusRank = map[string]int{
+	model.SmartOk:      0,
+	model.SmartWarning: 1,
+	model.SmartFailing: 2,
+}
+
+// SmartJob checks SMART of the disks and records a health event when a disk degrades.
+// The last seen health of every disk is kept in a file, so a restart does not repeat the events.
+type SmartJob struct {
+	disks  SmartDisks
+	smart  SmartChecker
+	events Events
+	file   string
+	logger *zap.Logger
+}
+
+func NewSmartJob(disks SmartDisks, smart SmartChecker, events Events, file string, logger *zap.Logger) *SmartJob {
+	return &SmartJob{
+		disks:  disks,
+		smart:  smart,
+		events: events,
+		file:   file,
+		logger: logger,
+	}
+}
+
+func (j *SmartJob) Run() error {
+	disks, err := j.disks.AllDisks()
+	if err != nil {
+		return err
+	}
+	last := j.load()
+	changed := false
+	for _, disk := range disks {
+		health, err := j.smart.Health(disk.Device)
+		if err != nil {
+			j.logger.Info("smart is not available", zap.String("device", disk.Device), zap.Error(err))
+			continue
+		}
+		key := health.Serial
+		if key == "" {
+			key = disk.Device
+		}
+		previous, known := last[key]
+		reason := degradation(previous, known, *health)
+		if reason != "" {
+			j.logger.Warn("disk degraded", zap.String("device", disk.Device), zap.String("reason", reason))
+			err = j.events.Append(stability.Event{Kind: stability.EventKindDiskDegraded, Path: disk.Device, Message: reason})
+			if err != nil {
+				j.logger.Warn("cannot record event", zap.Error(err))
+			}
+		}
+		if !known || previous != *health {
+			last[key] = *health
+			changed = true
+		}
+	}
+	if changed {
+		j.save(last)
+	}
+	return nil
+}
+
+// degradation describes what got worse since the previous check, a disk seen for the first time
+// is reported when it is not ok already.
+func degradation(previous model.SmartHealth, known bool, current model.SmartHealth) string {
+	var reasons []string
+	if current.Status != model.SmartOk && (!known || smartStatusRank[current.Status] > smartStatusRank[previous.Status]) {
+		reasons = append(reasons, fmt.Sprintf("status %s", current.Status))
+	}
+	if known && current.ReallocatedSectors > previous.ReallocatedSectors {
+		reasons = append(reasons, fmt.Sprintf("reallocated sectors %d -> %d", previous.ReallocatedSectors, current.ReallocatedSectors))
+	}
+	if known && current.PendingSectors > previous.PendingSectors {
+		reasons = append(reasons, fmt.Sprintf("pending sectors %d -> %d", previous.PendingSectors, current.PendingSectors))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func (j *SmartJob) load() map[string]model.SmartHealth {
+	last := make(map[string]model.SmartHealth)
+	data, err := os.ReadFile(j.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			j.logger.Warn("cannot read smart state", zap.Error(err))
+		}
+		return last
+	}
+	err = json.Unmarshal(data, &last)
+	if err != nil {
+		j.logger.Warn("cannot parse smart state", zap.Error(err))
+		return make(map[string]model.SmartHealth)
+	}
+	return last
+}
+
+func (j *SmartJob) save(last map[string]model.SmartHealth) {
+	data, err := json.Marshal(last)
+	if err != nil {
+		j.logger.Warn("cannot save smart state", zap.Error(err))
+		return
+	}
+	tmp := j.file + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err == nil {
+		err = os.Rename(tmp, j.file)
+	}
+	if err != nil {
+		j.logger.Warn("cannot save smart state", zap.Error(err))
+	}
+}
@@ -0,0 +1,74 @@
+package cron
+
+import (
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/storage/model"
+)
+
+type SmartDisksStub struct {
+	disks []model.Disk
+}
+
+func (s *SmartDisksStub) AllDisks() ([]model.Disk, error) {
+	return s.disks, nil
+}
+
+type SmartCheckerStub struct {
+	health map[string]model.SmartHealth
+}
+
+func (s *SmartCheckerStub) Health(device string) (*model.SmartHealth, error) {
+	health, ok := s.health[device]
+	if !ok {
+		return nil, fmt.Errorf("smart is not available")
+	}
+	return &health, nil
+}
+
+func TestSmartJob_RecordsDegradationOnce(t *testing.T) {
+	disks := &SmartDisksStub{disks: []model.Disk{{Device: "/dev/sda"}, {Device: "/dev/mmcblk0"}}}
+	smart := &SmartCheckerStub{health: map[string]model.SmartHealth{
+		"/dev/sda": {Status: model.SmartOk, Serial: "WD1", Passed: true},
+	}}
+	events := &EventsStub{}
+	file := path.Join(t.TempDir(), "smart.json")
+	job := NewSmartJob(disks, smart, events, file, log.Default())
+
+	assert.Nil(t, job.Run())
+	assert.Len(t, events.events, 0)
+
+	smart.health["/dev/sda"] = model.SmartHealth{Status: model.SmartWarning, Serial: "WD1", Passed: true, ReallocatedSectors: 8}
+	assert.Nil(t, job.Run())
+	assert.Len(t, events.events, 1)
+	assert.Equal(t, stability.EventKindDiskDegraded, events.events[0].Kind)
+	assert.Equal(t, "/dev/sda", events.events[0].Path)
+	assert.Equal(t, "status warning, reallocated sectors 0 -> 8", events.events[0].Message)
+
+	job = NewSmartJob(disks, smart, events, file, log.Default())
+	assert.Nil(t, job.Run())
+	assert.Len(t, events.events, 1)
+
+	smart.health["/dev/sda"] = model.SmartHealth{Status: model.SmartWarning, Serial: "WD1", Passed: true, ReallocatedSectors: 8, PendingSectors: 3}
+	assert.Nil(t, job.Run())
+	assert.Len(t, events.events, 2)
+	assert.Equal(t, "pending sectors 0 -> 3", events.events[1].Message)
+}
+
+func TestSmartJob_ReportsNewFailingDisk(t *testing.T) {
+	disks := &SmartDisksStub{disks: []model.Disk{{Device: "/dev/sdb"}}}
+	smart := &SmartCheckerStub{health: map[string]model.SmartHealth{
+		"/dev/sdb": {Status: model.SmartFailing, Serial: "ST1"},
+	}}
+	events := &EventsStub{}
+	job := NewSmartJob(disks, smart, events, path.Join(t.TempDir(), "smart.json"), log.Default())
+
+	assert.Nil(t, job.Run())
+	assert.Len(t, events.events, 1)
+	assert.Equal(t, "status failing", events.events[0].Message)
+}
@@ -10,26 +10,16 @@ import (
 	"go.uber.org/zap"
 )
 
-const (
-	SnapdUpgradeEveryDay = 0
-	SnapdUpgradeHour     = 5
-)
-
 type SnapdInstallerInfo interface {
 	Installer() (*model.InstallerInfo, error)
 }
 
-type SnapdUpgrader interface {
-	Upgrade(version string) error
-}
-
 type JobMaster interface {
-	Offer(name string, j job.Job) error
+	Submit(source string, name string, args interface{}) error
 }
 
 type SnapdUpgradeJob struct {
 	installerInfo SnapdInstallerInfo
-	upgrader      SnapdUpgrader
 	jobMaster     JobMaster
 	scheduler     Scheduler
 	provider      date.Provider
@@ -37,10 +27,9 @@ type SnapdUpgradeJob struct {
 	logger        *zap.Logger
 }
 
-func NewSnapdUpgradeJob(installerInfo SnapdInstallerInfo, upgrader SnapdUpgrader, jobMaster JobMaster, scheduler Scheduler, provider date.Provider, logger *zap.Logger) *SnapdUpgradeJob {
+func NewSnapdUpgradeJob(installerInfo SnapdInstallerInfo, jobMaster JobMaster, scheduler Scheduler, provider date.Provider, logger *zap.Logger) *SnapdUpgradeJob {
 	return &SnapdUpgradeJob{
 		installerInfo: installerInfo,
-		upgrader:      upgrader,
 		jobMaster:     jobMaster,
 		scheduler:     scheduler,
 		provider:      provider,
@@ -50,7 +39,7 @@ func NewSnapdUpgradeJob(installerInfo SnapdInstallerInfo, upgrader SnapdUpgrader
 
 func (j *SnapdUpgradeJob) Run() error {
 	now := j.provider.Now()
-	if !j.scheduler.ShouldRun(SnapdUpgradeEveryDay, SnapdUpgradeHour, now, j.lastRun) {
+	if !j.scheduler.ShouldRun(JobSnapdUpgrade, now, j.lastRun) {
 		return nil
 	}
 	info, err := j.installerInfo.Installer()
@@ -66,7 +55,7 @@ func (j *SnapdUpgradeJob) Run() error {
 		zap.String("from", strings.TrimSpace(info.InstalledVersion)),
 		zap.String("to", strings.TrimSpace(info.StoreVersion)))
 	storeVersion := strings.TrimSpace(info.StoreVersion)
-	err = j.jobMaster.Offer("installer.upgrade", func() error { return j.upgrader.Upgrade(storeVersion) })
+	err = j.jobMaster.Submit(job.SourceCron, "installer.upgrade", storeVersion)
 	if err != nil {
 		return err
 	}
@@ -19,105 +19,98 @@ func (s *InstallerInfoStub) Installer() (*model.InstallerInfo, error) {
 	return s.info, s.err
 }
 
-type UpgraderStub struct {
-	called  int
-	version string
-}
-
-func (u *UpgraderStub) Upgrade(version string) error {
-	u.called++
-	u.version = version
-	return nil
-}
-
 type JobMasterStub struct {
 	offered []string
+	args    []interface{}
 	busy    bool
 }
 
-func (m *JobMasterStub) Offer(name string, j job.Job) error {
+func (m *JobMasterStub) Submit(source string, name string, args interface{}) error {
 	if m.busy {
 		return assert.AnError
 	}
+	if source != job.SourceCron {
+		return assert.AnError
+	}
 	m.offered = append(m.offered, name)
-	return j()
+	m.args = append(m.args, args)
+	return nil
 }
 
+const snapdUpgradeHour = 5
+
 func atHour(hour int) time.Time {
 	return time.Date(2026, 7, 7, hour, 0, 0, 0, time.UTC)
 }
 
-func newJob(store, installed string) (*SnapdUpgradeJob, *UpgraderStub, *JobMasterStub, *DateProviderStub) {
+func newJob(store, installed string) (*SnapdUpgradeJob, *JobMasterStub, *DateProviderStub) {
 	info := &InstallerInfoStub{info: &model.InstallerInfo{StoreVersion: store, InstalledVersion: installed}}
-	upgrader := &UpgraderStub{}
 	master := &JobMasterStub{}
 	provider := &DateProviderStub{}
-	j := NewSnapdUpgradeJob(info, upgrader, master, &SimpleScheduler{}, provider, log.Default())
-	return j, upgrader, master, provider
+	j := NewSnapdUpgradeJob(info, master, NewSchedules(&ScheduleConfigStub{}, provider, log.Default()), provider, log.Default())
+	return j, master, provider
 }
 
 func TestSnapdUpgradeJob_UpgradesWhenVersionsDiffer(t *testing.T) {
-	j, upgrader, master, provider := newJob("2.60.0", "2.59.5")
-	provider.now = atHour(SnapdUpgradeHour)
+	j, master, provider := newJob("2.60.0", "2.59.5")
+	provider.now = atHour(snapdUpgradeHour)
 
 	err := j.Run()
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, upgrader.called)
-	assert.Equal(t, "2.60.0", upgrader.version)
 	assert.Equal(t, []string{"installer.upgrade"}, master.offered)
+	assert.Equal(t, []interface{}{"2.60.0"}, master.args)
 }
 
 func TestSnapdUpgradeJob_SkipsWhenUpToDate(t *testing.T) {
-	j, upgrader, master, provider := newJob("2.59.5\n", "2.59.5")
-	provider.now = atHour(SnapdUpgradeHour)
+	j, master, provider := newJob("2.59.5\n", "2.59.5")
+	provider.now = atHour(snapdUpgradeHour)
 
 	err := j.Run()
 
 	assert.NoError(t, err)
-	assert.Equal(t, 0, upgrader.called)
 	assert.Empty(t, master.offered)
 }
 
 func TestSnapdUpgradeJob_SkipsOutsideWindow(t *testing.T) {
-	j, upgrader, _, provider := newJob("2.60.0", "2.59.5")
+	j, master, provider := newJob("2.60.0", "2.59.5")
 	provider.now = atHour(23)
 
 	err := j.Run()
 
 	assert.NoError(t, err)
-	assert.Equal(t, 0, upgrader.called)
+	assert.Empty(t, master.offered)
 }
 
 func TestSnapdUpgradeJob_RunsOncePerDay(t *testing.T) {
-	j, upgrader, _, provider := newJob("2.60.0", "2.59.5")
+	j, master, provider := newJob("2.60.0", "2.59.5")
 
-	provider.now = atHour(SnapdUpgradeHour)
+	provider.now = atHour(snapdUpgradeHour)
 	_ = j.Run()
-	provider.now = atHour(SnapdUpgradeHour).Add(10 * time.Minute)
+	provider.now = atHour(snapdUpgradeHour).Add(10 * time.Minute)
 	_ = j.Run()
 
-	assert.Equal(t, 1, upgrader.called)
+	assert.Len(t, master.offered, 1)
 
-	provider.now = atHour(SnapdUpgradeHour).AddDate(0, 0, 1)
+	provider.now = atHour(snapdUpgradeHour).AddDate(0, 0, 1)
 	_ = j.Run()
 
-	assert.Equal(t, 2, upgrader.called)
+	assert.Len(t, master.offered, 2)
 }
 
 func TestSnapdUpgradeJob_RetriesWithinHourWhenBusy(t *testing.T) {
-	j, upgrader, master, provider := newJob("2.60.0", "2.59.5")
-	provider.now = atHour(SnapdUpgradeHour)
+	j, master, provider := newJob("2.60.0", "2.59.5")
+	provider.now = atHour(snapdUpgradeHour)
 	master.busy = true
 
 	err := j.Run()
 	assert.Error(t, err)
-	assert.Equal(t, 0, upgrader.called)
+	assert.Empty(t, master.offered)
 
 	master.busy = false
-	provider.now = atHour(SnapdUpgradeHour).Add(5 * time.Minute)
+	provider.now = atHour(snapdUpgradeHour).Add(5 * time.Minute)
 	err = j.Run()
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, upgrader.called)
+	assert.Len(t, master.offered, 1)
 }
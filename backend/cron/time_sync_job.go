@@ -9,22 +9,26 @@ import (
 
 type TimeSyncJob struct {
 	executor     cli.Executor
+	scheduler    Scheduler
 	lastRun      time.Time
 	dateProvider date.Provider
 	logger       *zap.Logger
 }
 
-func NewTimeSyncJob(executor cli.Executor, dateProvider date.Provider, logger *zap.Logger) *TimeSyncJob {
+func NewTimeSyncJob(executor cli.Executor, scheduler Scheduler, dateProvider date.Provider, logger *zap.Logger) *TimeSyncJob {
 	return &TimeSyncJob{
 		executor:     executor,
+		scheduler:    scheduler,
 		dateProvider: dateProvider,
 		logger:       logger,
 	}
 }
 
+// Run syncs the time on the first tick after boot as boards without RTC start with a wrong clock,
+// later runs follow the schedule.
 func (t *TimeSyncJob) Run() error {
 	now := t.dateProvider.Now()
-	if t.lastRun.Add(time.Hour * 24).Before(now) {
+	if t.lastRun.IsZero() || t.scheduler.ShouldRun(JobTimeSync, now, t.lastRun) {
 		output, err := t.executor.CombinedOutput("service", "ntp", "stop")
 		t.logger.Info(string(output))
 		if err != nil {
@@ -24,15 +24,14 @@ func (d *DateProviderStub) Now() time.Time {
 	return d.now
 }
 
-func TestTimeSyncJob_RunOnce_24h(t *testing.T) {
+func TestTimeSyncJob_RunsOnSchedule(t *testing.T) {
 	executor := &ExecutorStub{}
 	date := &DateProviderStub{}
-	job := NewTimeSyncJob(executor, date, log.Default())
-	date.now = time.Now()
+	job := NewTimeSyncJob(executor, NewSchedules(&ScheduleConfigStub{}, date, log.Default()), date, log.Default())
+	date.now = time.Date(2024, 3, 1, 4, 0, 0, 0, time.UTC)
 	err := job.Run()
 	assert.NoError(t, err)
-	date.now = date.now.Add(time.Hour * 24)
-	err = job.Run()
+	date.now = date.now.Add(time.Minute * 10)
 	err = job.Run()
 	assert.NoError(t, err)
 	assert.Equal(t, 3, executor.called)
@@ -41,8 +40,8 @@ func TestTimeSyncJob_RunOnce_24h(t *testing.T) {
 func TestTimeSyncJob_RunTwice_48h(t *testing.T) {
 	executor := &ExecutorStub{}
 	date := &DateProviderStub{}
-	job := NewTimeSyncJob(executor, date, log.Default())
-	date.now = time.Now()
+	job := NewTimeSyncJob(executor, NewSchedules(&ScheduleConfigStub{}, date, log.Default()), date, log.Default())
+	date.now = time.Date(2024, 3, 1, 4, 0, 0, 0, time.UTC)
 	err := job.Run()
 	assert.NoError(t, err)
 	date.now = date.now.Add(time.Hour * 48)
@@ -51,3 +50,25 @@ func TestTimeSyncJob_RunTwice_48h(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 6, executor.called)
 }
+
+func TestTimeSyncJob_FirstTick_RunsOutsideSchedule(t *testing.T) {
+	executor := &ExecutorStub{}
+	date := &DateProviderStub{}
+	job := NewTimeSyncJob(executor, NewSchedules(&ScheduleConfigStub{}, date, log.Default()), date, log.Default())
+	date.now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := job.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, executor.called)
+}
+
+func TestTimeSyncJob_OutsideSchedule_NotRun(t *testing.T) {
+	executor := &ExecutorStub{}
+	date := &DateProviderStub{}
+	job := NewTimeSyncJob(executor, NewSchedules(&ScheduleConfigStub{}, date, log.Default()), date, log.Default())
+	date.now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_ = job.Run()
+	date.now = date.now.Add(time.Hour * 6)
+	err := job.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, executor.called)
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AppUsage is the resource usage of all the systemd units of a snap app, counters are totals since the units started.
+type AppUsage struct {
+	App         string `json:"app"`
+	Units       int    `json:"units"`
+	CPUUsec     uint64 `json:"cpu_usec"`
+	MemoryBytes uint64 `json:"memory_bytes"`
+	RSSBytes    uint64 `json:"rss_bytes"`
+	ReadBytes   uint64 `json:"read_bytes"`
+	WriteBytes  uint64 `json:"write_bytes"`
+	Pids        uint64 `json:"pids"`
+}
+
+// AppCollector reads the cgroup v2 accounting of the snap.<app>.* units.
+type AppCollector struct {
+	cgroupDir string
+}
+
+func NewAppCollector(cgroupDir string) *AppCollector {
+	return &AppCollector{cgroupDir: cgroupDir}
+}
+
+// Apps returns the usage sorted by memory, the biggest first.
+func (c *AppCollector) Apps() ([]AppUsage, error) {
+	entries, err := os.ReadDir(filepath.Join(c.cgroupDir, "system.slice"))
+	if err != nil {
+		return nil, err
+	}
+	apps := make(map[string]*AppUsage)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		app := unitApp(entry.Name())
+		if app == "" {
+			continue
+		}
+		usage, ok := apps[app]
+		if !ok {
+			usage = &AppUsage{App: app}
+			apps[app] = usage
+		}
+		c.addUnit(usage, filepath.Join(c.cgroupDir, "system.slice", entry.Name()))
+	}
+	result := make([]AppUsage, 0, len(apps))
+	for _, usage := range apps {
+		result = append(result, *usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].MemoryBytes != result[j].MemoryBytes {
+			return result[i].MemoryBytes > result[j].MemoryBytes
+		}
+		return result[i].App < result[j].App
+	})
+	return result, nil
+}
+
+func (c *AppCollector) addUnit(usage *AppUsage, dir string) {
+	usage.Units++
+	usage.CPUUsec += readKey(filepath.Join(dir, "cpu.stat"), "usage_usec")
+	usage.MemoryBytes += readValue(filepath.Join(dir, "memory.current"))
+	usage.RSSBytes += readKey(filepath.Join(dir, "memory.stat"), "anon")
+	usage.Pids += readValue(filepath.Join(dir, "pids.current"))
+	read, written := readIOStat(filepath.Join(dir, "io.stat"))
+	usage.ReadBytes += read
+	usage.WriteBytes += written
+}
+
+// unitApp returns the snap name of a snap.<app>.<service>.service or a snap.<app>.hook.<hook>-<id>.scope unit.
+func unitApp(unit string) string {
+	if !strings.HasPrefix(unit, "snap.") {
+		return ""
+	}
+	if !strings.HasSuffix(unit, ".service") && !strings.HasSuffix(unit, ".scope") {
+		return ""
+	}
+	parts := strings.Split(unit, ".")
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[1]
+}
+
+func readValue(file string) uint64 {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	value, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return value
+}
+
+func readKey(file string, key string) uint64 {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, _ := strconv.ParseUint(fields[1], 10, 64)
+			return value
+		}
+	}
+	return 0
+}
+
+// readIOStat sums rbytes and wbytes over the devices, lines look like "8:0 rbytes=1 wbytes=2 rios=3 ...".
+func readIOStat(file string) (uint64, uint64) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	var read, written uint64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		for _, field := range strings.Fields(sc.Text()) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseUint(value, 10, 64)
+			switch key {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				written += n
+			}
+		}
+	}
+	return read, written
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAppCollector(t *testing.T) *AppCollector {
+	dir := t.TempDir()
+	writeProc(t, dir, "system.slice/snap.nextcloud.php-fpm.service/cpu.stat", "usage_usec 3000000\nuser_usec 2000000\nsystem_usec 1000000\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.php-fpm.service/memory.current", "300000000\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.php-fpm.service/memory.stat", "anon 200000000\nfile 100000000\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.php-fpm.service/io.stat", "8:0 rbytes=1000 wbytes=2000 rios=1 wios=2 dbytes=0 dios=0\n179:0 rbytes=10 wbytes=20 rios=1 wios=1 dbytes=0 dios=0\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.php-fpm.service/pids.current", "12\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.mysql.service/cpu.stat", "usage_usec 1000000\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.mysql.service/memory.current", "100000000\n")
+	writeProc(t, dir, "system.slice/snap.nextcloud.mysql.service/pids.current", "30\n")
+	writeProc(t, dir, "system.slice/snap.syncthing.syncthing.service/memory.current", "50000000\n")
+	writeProc(t, dir, "system.slice/snap.mail.hook.configure-8d9e.scope/memory.current", "1000\n")
+	writeProc(t, dir, "system.slice/nginx.service/memory.current", "900000000\n")
+	writeProc(t, dir, "system.slice/snap-core.mount/memory.current", "1\n")
+	return NewAppCollector(dir)
+}
+
+func TestAppCollector_Apps(t *testing.T) {
+	apps, err := newTestAppCollector(t).Apps()
+	require.NoError(t, err)
+	require.Len(t, apps, 3)
+
+	assert.Equal(t, AppUsage{
+		App:         "nextcloud",
+		Units:       2,
+		CPUUsec:     4000000,
+		MemoryBytes: 400000000,
+		RSSBytes:    200000000,
+		ReadBytes:   1010,
+		WriteBytes:  2020,
+		Pids:        42,
+	}, apps[0])
+	assert.Equal(t, "syncthing", apps[1].App)
+	assert.Equal(t, "mail", apps[2].App)
+}
+
+func TestUnitApp(t *testing.T) {
+	assert.Equal(t, "photoprism", unitApp("snap.photoprism.web.service"))
+	assert.Equal(t, "photoprism", unitApp("snap.photoprism.hook.configure-1f2e.scope"))
+	assert.Equal(t, "", unitApp("snap-photoprism-1.mount"))
+	assert.Equal(t, "", unitApp("nginx.service"))
+	assert.Equal(t, "", unitApp("snap.photoprism.service"))
+}
@@ -0,0 +1,268 @@
+package health
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syncloud/platform/cert"
+	"github.com/syncloud/platform/job"
+	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/storage/btrfs"
+	"go.uber.org/zap"
+)
+
+// ContentType is the OpenMetrics text format, Prometheus negotiates it with the Accept header.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// userHz is the unit of the cpu times in /proc/stat.
+const userHz = 100
+
+const sectorBytes = 512
+
+// eventsWindow is the period the stability events are counted over.
+const eventsWindow = 24 * time.Hour
+
+type DeviceErrors interface {
+	DeviceErrors() (*btrfs.DeviceStats, error)
+}
+
+type CertificateInfo interface {
+	ReadCertificateInfo() *cert.Info
+}
+
+type JobList interface {
+	List() []job.Entry
+}
+
+type EventSource interface {
+	Counts(filter stability.EventFilter) ([]stability.EventCount, error)
+}
+
+// Exporter writes the device metrics in the OpenMetrics text format.
+type Exporter struct {
+	collector   *Collector
+	events      EventSource
+	btrfs       DeviceErrors
+	certificate CertificateInfo
+	jobs        JobList
+	logger      *zap.Logger
+}
+
+func NewExporter(collector *Collector, events EventSource, btrfs DeviceErrors, certificate CertificateInfo, jobs JobList, logger *zap.Logger) *Exporter {
+	return &Exporter{
+		collector:   collector,
+		events:      events,
+		btrfs:       btrfs,
+		certificate: certificate,
+		jobs:        jobs,
+		logger:      logger,
+	}
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+type family struct {
+	name    string
+	kind    string
+	help    string
+	samples []sample
+}
+
+func (f *family) add(value float64, labels ...string) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+func (e *Exporter) Write(w io.Writer) error {
+	families, err := e.families()
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(w)
+	for _, f := range families {
+		_, _ = fmt.Fprintf(writer, "# TYPE %s %s\n", f.name, f.kind)
+		_, _ = fmt.Fprintf(writer, "# HELP %s %s\n", f.name, f.help)
+		suffix := ""
+		if f.kind == "counter" {
+			suffix = "_total"
+		}
+		for _, s := range f.samples {
+			_, _ = fmt.Fprintf(writer, "%s%s%s %s\n", f.name, suffix, formatLabels(s.labels), strconv.FormatFloat(s.value, 'f', -1, 64))
+		}
+	}
+	_, _ = writer.WriteString("# EOF\n")
+	return writer.Flush()
+}
+
+func (e *Exporter) families() ([]*family, error) {
+	snapshot, err := e.collector.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	var families []*family
+
+	cpu := &family{name: "syncloud_cpu_seconds", kind: "counter", help: "Time the cpus spent in each mode."}
+	for _, mode := range []struct {
+		name  string
+		value uint64
+	}{
+		{"user", snapshot.CPU.User}, {"nice", snapshot.CPU.Nice}, {"system", snapshot.CPU.System}, {"idle", snapshot.CPU.Idle},
+		{"iowait", snapshot.CPU.IOWait}, {"irq", snapshot.CPU.IRQ}, {"softirq", snapshot.CPU.SoftIRQ}, {"steal", snapshot.CPU.Steal},
+	} {
+		cpu.add(float64(mode.value)/userHz, "mode", mode.name)
+	}
+	families = append(families, cpu)
+
+	memory := &family{name: "syncloud_memory_bytes", kind: "gauge", help: "Memory and swap from /proc/meminfo."}
+	memory.add(kb(snapshot.Memory.TotalKB), "type", "total")
+	memory.add(kb(snapshot.Memory.AvailableKB), "type", "available")
+	memory.add(kb(snapshot.Memory.FreeKB), "type", "free")
+	memory.add(kb(snapshot.Memory.BuffersKB), "type", "buffers")
+	memory.add(kb(snapshot.Memory.CachedKB), "type", "cached")
+	memory.add(kb(snapshot.Memory.SwapTotalKB), "type", "swap_total")
+	memory.add(kb(snapshot.Memory.SwapFreeKB), "type", "swap_free")
+	families = append(families, memory)
+
+	if snapshot.Zram != nil {
+		zram := &family{name: "syncloud_zram_bytes", kind: "gauge", help: "Zram swap size, stored data before and after compression and memory used."}
+		zram.add(float64(snapshot.Zram.DiskSizeBytes), "type", "disk_size")
+		zram.add(float64(snapshot.Zram.OrigDataBytes), "type", "orig_data")
+		zram.add(float64(snapshot.Zram.ComprDataBytes), "type", "compr_data")
+		zram.add(float64(snapshot.Zram.MemUsedBytes), "type", "mem_used")
+		families = append(families, zram)
+	}
+
+	swap := &family{name: "syncloud_swap_pages", kind: "counter", help: "Pages swapped in and out."}
+	swap.add(float64(snapshot.Memory.SwapInPages), "direction", "in")
+	swap.add(float64(snapshot.Memory.SwapOutPages), "direction", "out")
+	families = append(families, swap)
+
+	reads := &family{name: "syncloud_disk_reads_completed", kind: "counter", help: "Reads completed by a disk."}
+	writes := &family{name: "syncloud_disk_writes_completed", kind: "counter", help: "Writes completed by a disk."}
+	readBytes := &family{name: "syncloud_disk_read_bytes", kind: "counter", help: "Bytes read from a disk."}
+	writtenBytes := &family{name: "syncloud_disk_written_bytes", kind: "counter", help: "Bytes written to a disk."}
+	for _, disk := range snapshot.Disks {
+		reads.add(float64(disk.ReadsTotal), "device", disk.Name)
+		writes.add(float64(disk.WritesTotal), "device", disk.Name)
+		readBytes.add(float64(disk.SectorsRead*sectorBytes), "device", disk.Name)
+		writtenBytes.add(float64(disk.SectorsWrt*sectorBytes), "device", disk.Name)
+	}
+	families = append(families, reads, writes, readBytes, writtenBytes)
+
+	size := &family{name: "syncloud_filesystem_size_bytes", kind: "gauge", help: "Size of a mounted filesystem."}
+	used := &family{name: "syncloud_filesystem_used_bytes", kind: "gauge", help: "Used space of a mounted filesystem."}
+	for _, mount := range snapshot.Mounts {
+		size.add(kb(mount.TotalKB), "mountpoint", mount.Path)
+		used.add(kb(mount.UsedKB), "mountpoint", mount.Path)
+	}
+	families = append(families, size, used)
+
+	received := &family{name: "syncloud_network_receive_bytes", kind: "counter", help: "Bytes received by an interface."}
+	transmitted := &family{name: "syncloud_network_transmit_bytes", kind: "counter", help: "Bytes sent by an interface."}
+	for _, iface := range snapshot.Net {
+		received.add(float64(iface.RxBytes), "device", iface.Name)
+		transmitted.add(float64(iface.TxBytes), "device", iface.Name)
+	}
+	families = append(families, received, transmitted)
+
+	families = append(families, e.btrfsErrors(), e.stabilityEvents(), e.certificateExpiry(), e.jobStatus())
+	return families, nil
+}
+
+func (e *Exporter) btrfsErrors() *family {
+	errors := &family{name: "syncloud_btrfs_device_errors", kind: "counter", help: "Btrfs device error counters of the external disk."}
+	stats, err := e.btrfs.DeviceErrors()
+	if err != nil {
+		e.logger.Debug("no btrfs device stats", zap.Error(err))
+		return errors
+	}
+	for _, device := range stats.DeviceStats {
+		for _, counter := range []struct {
+			name  string
+			value string
+		}{
+			{"write_io", device.WriteIoErrs}, {"read_io", device.ReadIoErrs}, {"flush_io", device.FlushIoErrs},
+			{"corruption", device.CorruptionErrs}, {"generation", device.GenerationErrs},
+		} {
+			value, _ := strconv.ParseFloat(counter.value, 64)
+			errors.add(value, "device", device.Device, "type", counter.name)
+		}
+	}
+	return errors
+}
+
+// stabilityEvents counts the events of the last day, old events leave the window so it is a gauge,
+// a counter would be reset every time an event falls out.
+func (e *Exporter) stabilityEvents() *family {
+	events := &family{name: "syncloud_stability_events_24h", kind: "gauge", help: "Stability events in the last 24 hours by kind."}
+	recent, err := e.events.Counts(stability.EventFilter{From: time.Now().Add(-eventsWindow)})
+	if err != nil {
+		e.logger.Warn("cannot read events", zap.Error(err))
+		return events
+	}
+	counts := make(map[string]int)
+	for _, count := range recent {
+		counts[string(count.Kind)] += count.Count
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		events.add(float64(counts[kind]), "kind", kind)
+	}
+	return events
+}
+
+func (e *Exporter) certificateExpiry() *family {
+	expiry := &family{name: "syncloud_certificate_expiry_days", kind: "gauge", help: "Days until the device certificate expires."}
+	info := e.certificate.ReadCertificateInfo()
+	if info.Subject == "" {
+		return expiry
+	}
+	real := "false"
+	if info.IsReal {
+		real = "true"
+	}
+	expiry.add(float64(info.ValidForDays), "real", real)
+	return expiry
+}
+
+func (e *Exporter) jobStatus() *family {
+	jobs := &family{name: "syncloud_jobs", kind: "gauge", help: "Jobs in the job queue by status."}
+	counts := map[string]int{job.Queued: 0, job.Running: 0, job.Succeeded: 0, job.Failed: 0, job.Cancelled: 0}
+	for _, entry := range e.jobs.List() {
+		counts[entry.Status]++
+	}
+	for _, status := range []string{job.Queued, job.Running, job.Succeeded, job.Failed, job.Cancelled} {
+		jobs.add(float64(counts[status]), "status", status)
+	}
+	return jobs
+}
+
+func kb(value uint64) float64 {
+	return float64(value * 1024)
+}
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], escapeLabel(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,131 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/cert"
+	"github.com/syncloud/platform/job"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/stability"
+	"github.com/syncloud/platform/storage/btrfs"
+)
+
+type EventSourceStub struct {
+	events []stability.Event
+	filter stability.EventFilter
+}
+
+func (e *EventSourceStub) Counts(filter stability.EventFilter) ([]stability.EventCount, error) {
+	e.filter = filter
+	var counts []stability.EventCount
+	for _, event := range e.events {
+		if filter.Matches(event) {
+			counts = append(counts, stability.EventCount{Kind: event.Kind, App: event.App, Count: 1})
+		}
+	}
+	return counts, nil
+}
+
+type DeviceErrorsStub struct {
+	output string
+}
+
+func (d *DeviceErrorsStub) DeviceErrors() (*btrfs.DeviceStats, error) {
+	if d.output == "" {
+		return nil, errors.New("no btrfs")
+	}
+	var stats btrfs.DeviceStats
+	err := json.Unmarshal([]byte(d.output), &stats)
+	return &stats, err
+}
+
+type CertificateInfoStub struct {
+	info cert.Info
+}
+
+func (c *CertificateInfoStub) ReadCertificateInfo() *cert.Info {
+	return &c.info
+}
+
+type JobListStub struct {
+	entries []job.Entry
+}
+
+func (j *JobListStub) List() []job.Entry {
+	return j.entries
+}
+
+func export(t *testing.T, exporter *Exporter) string {
+	var buf bytes.Buffer
+	require.NoError(t, exporter.Write(&buf))
+	return buf.String()
+}
+
+func TestExporter_Write(t *testing.T) {
+	collector, _ := newTestCollector(t)
+	now := time.Now()
+	events := &EventSourceStub{events: []stability.Event{
+		{Time: now, Kind: stability.EventKindPressure},
+		{Time: now, Kind: stability.EventKindPressure, App: "nextcloud"},
+		{Time: now, Kind: stability.EventKindVictimSigkill},
+		{Time: now.Add(-48 * time.Hour), Kind: stability.EventKindVictimSigkill},
+	}}
+	devices := &DeviceErrorsStub{output: `{"device-stats": [{"device": "/dev/sda1", "devid": "1", "write_io_errs": "2", "read_io_errs": "0", "flush_io_errs": "0", "corruption_errs": "5", "generation_errs": "0"}]}`}
+	certificate := &CertificateInfoStub{info: cert.Info{Subject: "example.com", IsReal: true, ValidForDays: 42}}
+	jobs := &JobListStub{entries: []job.Entry{{Status: job.Running}, {Status: job.Queued}, {Status: job.Queued}, {Status: job.Failed}}}
+	exporter := NewExporter(collector, events, devices, certificate, jobs, log.Default())
+
+	output := export(t, exporter)
+
+	assert.Contains(t, output, "# TYPE syncloud_cpu_seconds counter\n")
+	assert.Contains(t, output, `syncloud_cpu_seconds_total{mode="user"} 10`+"\n")
+	assert.Contains(t, output, `syncloud_memory_bytes{type="total"} 3788800000`+"\n")
+	assert.Contains(t, output, `syncloud_swap_pages_total{direction="out"} 5678`+"\n")
+	assert.Contains(t, output, `syncloud_disk_read_bytes_total{device="sda"} 102400`+"\n")
+	assert.Contains(t, output, `syncloud_network_transmit_bytes_total{device="eth0"} 8000`+"\n")
+	assert.Contains(t, output, `syncloud_btrfs_device_errors_total{device="/dev/sda1",type="corruption"} 5`+"\n")
+	assert.Contains(t, output, "# TYPE syncloud_stability_events_24h gauge\n")
+	assert.Contains(t, output, `syncloud_stability_events_24h{kind="pressure_detected"} 2`+"\n")
+	assert.Contains(t, output, `syncloud_stability_events_24h{kind="victim_sigkill"} 1`+"\n")
+	assert.WithinDuration(t, now.Add(-24*time.Hour), events.filter.From, time.Minute)
+	assert.Contains(t, output, `syncloud_certificate_expiry_days{real="true"} 42`+"\n")
+	assert.Contains(t, output, `syncloud_jobs{status="queued"} 2`+"\n")
+	assert.Contains(t, output, `syncloud_jobs{status="succeeded"} 0`+"\n")
+	assert.True(t, strings.HasSuffix(output, "# EOF\n"))
+}
+
+func TestExporter_Write_NoBtrfsNoCertificate(t *testing.T) {
+	collector, _ := newTestCollector(t)
+	exporter := NewExporter(collector, &EventSourceStub{}, &DeviceErrorsStub{}, &CertificateInfoStub{}, &JobListStub{}, log.Default())
+
+	output := export(t, exporter)
+
+	assert.Contains(t, output, "# TYPE syncloud_btrfs_device_errors counter\n")
+	assert.NotContains(t, output, "syncloud_btrfs_device_errors_total")
+	assert.NotContains(t, output, "syncloud_certificate_expiry_days{")
+}
+
+func TestValidateListen(t *testing.T) {
+	assert.Nil(t, ValidateListen("127.0.0.1:9100"))
+	assert.Nil(t, ValidateListen("127.1.2.3:9100"))
+	assert.Nil(t, ValidateListen("[::1]:9100"))
+	assert.Nil(t, ValidateListen("localhost:9100"))
+	assert.NotNil(t, ValidateListen("192.168.1.10:9100"))
+	assert.NotNil(t, ValidateListen("10.0.0.1:9100"))
+	assert.NotNil(t, ValidateListen("[fd00::1]:9100"))
+	assert.NotNil(t, ValidateListen("0.0.0.0:9100"))
+	assert.NotNil(t, ValidateListen("8.8.8.8:9100"))
+	assert.NotNil(t, ValidateListen("example.com:9100"))
+	assert.NotNil(t, ValidateListen("127.0.0.1"))
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\"b\\c\n`, escapeLabel("a\"b\\c\n"))
+}
@@ -1,22 +1,80 @@
 package health
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"github.com/syncloud/platform/stability"
 )
 
 type Health struct {
 	events    *stability.EventLog
 	collector *Collector
+	exporter  *Exporter
+	history   *History
+	apps      *AppCollector
+	policy    *stability.PolicyFile
+	server    *MetricsServer
+}
+
+func NewHealth(events *stability.EventLog, collector *Collector, exporter *Exporter, history *History, apps *AppCollector, policy *stability.PolicyFile, server *MetricsServer) *Health {
+	return &Health{events: events, collector: collector, exporter: exporter, history: history, apps: apps, policy: policy, server: server}
+}
+
+// eventsPollInterval is how often a followed event log is checked for new events.
+const eventsPollInterval = time.Second
+
+func (h *Health) Events(filter stability.EventFilter, cursor string, limit int) (stability.EventPage, error) {
+	return h.events.Query(filter, cursor, limit)
 }
 
-func NewHealth(events *stability.EventLog, collector *Collector) *Health {
-	return &Health{events: events, collector: collector}
+func (h *Health) EventCounts(filter stability.EventFilter) ([]stability.EventCount, error) {
+	return h.events.Counts(filter)
 }
 
-func (h *Health) Events(limit int) ([]stability.Event, error) {
-	return h.events.Recent(limit)
+func (h *Health) ExportEvents(w io.Writer, filter stability.EventFilter) error {
+	return h.events.Export(w, filter)
+}
+
+func (h *Health) FollowEvents(ctx context.Context, filter stability.EventFilter, fn func(stability.Event) error) error {
+	return h.events.Follow(ctx, filter, eventsPollInterval, fn)
 }
 
 func (h *Health) Metrics() (Snapshot, error) {
 	return h.collector.Snapshot()
 }
+
+func (h *Health) Apps() ([]AppUsage, error) {
+	return h.apps.Apps()
+}
+
+func (h *Health) WriteOpenMetrics(w io.Writer) error {
+	return h.exporter.Write(w)
+}
+
+func (h *Health) MetricsListen() string {
+	return h.server.Listen()
+}
+
+// SetMetricsListen changes the address of the unauthenticated metrics listener, only loopback is accepted.
+func (h *Health) SetMetricsListen(address string) error {
+	return h.server.SetListen(address)
+}
+
+func (h *Health) History(from time.Time, to time.Time, names []string) (*Series, error) {
+	return h.history.Query(from, to, names)
+}
+
+func (h *Health) AppHistory(app string, from time.Time, to time.Time, names []string) (*Series, error) {
+	return h.history.QueryApp(app, from, to, names)
+}
+
+func (h *Health) OOMPolicy() (stability.Policy, error) {
+	return h.policy.Load()
+}
+
+// SetOOMPolicy saves the policy, the watcher picks it up on the next check.
+func (h *Health) SetOOMPolicy(policy stability.Policy) error {
+	return h.policy.Save(policy)
+}
@@ -0,0 +1,539 @@
+package health
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Resolution struct {
+	Step time.Duration
+	Keep time.Duration
+}
+
+func (r Resolution) slots() int64 {
+	return int64(r.Keep / r.Step)
+}
+
+func (r Resolution) bucket(unix int64) int64 {
+	return unix / int64(r.Step/time.Second)
+}
+
+// Resolutions of the metric history, finest first, every level is a separate ring file.
+var Resolutions = []Resolution{
+	{Step: 10 * time.Second, Keep: time.Hour},
+	{Step: time.Minute, Keep: 24 * time.Hour},
+	{Step: 15 * time.Minute, Keep: 30 * 24 * time.Hour},
+}
+
+// Sample is the part of a snapshot kept in the history, disks and interfaces are summed up.
+// Counters are stored as they are and turned into rates on query.
+type Sample struct {
+	Time           int64
+	CPUBusy        uint64
+	CPUTotal       uint64
+	MemTotalKB     uint64
+	MemAvailableKB uint64
+	SwapTotalKB    uint64
+	SwapFreeKB     uint64
+	SwapInPages    uint64
+	SwapOutPages   uint64
+	SectorsRead    uint64
+	SectorsWritten uint64
+	RxBytes        uint64
+	TxBytes        uint64
+}
+
+var sampleSize = binary.Size(Sample{})
+
+func NewSample(now time.Time, snapshot Snapshot) Sample {
+	sample := Sample{
+		Time:           now.Unix(),
+		CPUBusy:        snapshot.CPU.Busy(),
+		CPUTotal:       snapshot.CPU.Total(),
+		MemTotalKB:     snapshot.Memory.TotalKB,
+		MemAvailableKB: snapshot.Memory.AvailableKB,
+		SwapTotalKB:    snapshot.Memory.SwapTotalKB,
+		SwapFreeKB:     snapshot.Memory.SwapFreeKB,
+		SwapInPages:    snapshot.Memory.SwapInPages,
+		SwapOutPages:   snapshot.Memory.SwapOutPages,
+	}
+	for _, disk := range snapshot.Disks {
+		sample.SectorsRead += disk.SectorsRead
+		sample.SectorsWritten += disk.SectorsWrt
+	}
+	for _, iface := range snapshot.Net {
+		sample.RxBytes += iface.RxBytes
+		sample.TxBytes += iface.TxBytes
+	}
+	return sample
+}
+
+// counterReset is true when the counters went backwards, usually after a reboot.
+func (s Sample) counterReset(prev Sample) bool {
+	return s.CPUTotal < prev.CPUTotal || s.CPUBusy < prev.CPUBusy ||
+		s.SwapInPages < prev.SwapInPages || s.SwapOutPages < prev.SwapOutPages ||
+		s.SectorsRead < prev.SectorsRead || s.SectorsWritten < prev.SectorsWritten ||
+		s.RxBytes < prev.RxBytes || s.TxBytes < prev.TxBytes
+}
+
+type historyMetric func(prev Sample, cur Sample, seconds float64) float64
+
+func rate(prev uint64, cur uint64, unit float64, seconds float64) float64 {
+	return float64(cur-prev) * unit / seconds
+}
+
+var historyMetrics = map[string]historyMetric{
+	"cpu_percent": func(prev Sample, cur Sample, _ float64) float64 {
+		total := cur.CPUTotal - prev.CPUTotal
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(cur.CPUBusy-prev.CPUBusy) / float64(total)
+	},
+	"memory_available_bytes": func(_ Sample, cur Sample, _ float64) float64 {
+		return float64(cur.MemAvailableKB * 1024)
+	},
+	"memory_used_percent": func(_ Sample, cur Sample, _ float64) float64 {
+		if cur.MemTotalKB == 0 {
+			return 0
+		}
+		return 100 * float64(cur.MemTotalKB-cur.MemAvailableKB) / float64(cur.MemTotalKB)
+	},
+	"swap_used_bytes": func(_ Sample, cur Sample, _ float64) float64 {
+		return float64((cur.SwapTotalKB - cur.SwapFreeKB) * 1024)
+	},
+	"swap_in_pages_rate": func(prev Sample, cur Sample, seconds float64) float64 {
+		return rate(prev.SwapInPages, cur.SwapInPages, 1, seconds)
+	},
+	"swap_out_pages_rate": func(prev Sample, cur Sample, seconds float64) float64 {
+		return rate(prev.SwapOutPages, cur.SwapOutPages, 1, seconds)
+	},
+	"disk_read_bytes_rate": func(prev Sample, cur Sample, seconds float64) float64 {
+		return rate(prev.SectorsRead, cur.SectorsRead, sectorBytes, seconds)
+	},
+	"disk_write_bytes_rate": func(prev Sample, cur Sample, seconds float64) float64 {
+		return rate(prev.SectorsWritten, cur.SectorsWritten, sectorBytes, seconds)
+	},
+	"net_rx_bytes_rate": func(prev Sample, cur Sample, seconds float64) float64 {
+		return rate(prev.RxBytes, cur.RxBytes, 1, seconds)
+	},
+	"net_tx_bytes_rate": func(prev Sample, cur Sample, seconds float64) float64 {
+		return rate(prev.TxBytes, cur.TxBytes, 1, seconds)
+	},
+}
+
+func HistoryMetrics() []string {
+	var names []string
+	for name := range historyMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ring keeps fixed size records of one resolution in a file of slots, every record starts with its unix time
+// and the slot is picked by that time so there is no index to maintain.
+// Only the first record of every step is written to keep writes to the sd card low.
+type ring struct {
+	resolution Resolution
+	file       string
+	size       int
+	lastBucket int64
+}
+
+func newRings(dir string, prefix string, size int) []*ring {
+	var rings []*ring
+	for _, resolution := range Resolutions {
+		rings = append(rings, &ring{
+			resolution: resolution,
+			file:       filepath.Join(dir, fmt.Sprintf("%s-%ds.bin", prefix, int64(resolution.Step/time.Second))),
+			size:       size,
+		})
+	}
+	return rings
+}
+
+func selectRing(rings []*ring, from time.Time, now time.Time) *ring {
+	for _, r := range rings {
+		if !from.Before(now.Add(-r.resolution.Keep)) {
+			return r
+		}
+	}
+	return rings[len(rings)-1]
+}
+
+func (r *ring) write(record interface{}) error {
+	var buf bytes.Buffer
+	err := binary.Write(&buf, binary.LittleEndian, record)
+	if err != nil {
+		return err
+	}
+	unix := int64(binary.LittleEndian.Uint64(buf.Bytes()))
+	bucket := r.resolution.bucket(unix)
+	if bucket == r.lastBucket {
+		return nil
+	}
+	f, err := os.OpenFile(r.file, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteAt(buf.Bytes(), (bucket%r.resolution.slots())*int64(r.size))
+	if err != nil {
+		return err
+	}
+	r.lastBucket = bucket
+	return nil
+}
+
+// read returns the records between from and to sorted by time.
+func (r *ring) read(from int64, to int64) ([][]byte, error) {
+	data, err := os.ReadFile(r.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records [][]byte
+	for offset := 0; offset+r.size <= len(data); offset += r.size {
+		record := data[offset : offset+r.size]
+		unix := int64(binary.LittleEndian.Uint64(record))
+		if unix == 0 || unix < from || unix > to {
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return binary.LittleEndian.Uint64(records[i]) < binary.LittleEndian.Uint64(records[j])
+	})
+	return records, nil
+}
+
+func decode(records [][]byte, item func(i int) interface{}) error {
+	for i, record := range records {
+		err := binary.Read(bytes.NewReader(record), binary.LittleEndian, item(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppSample is the usage of an app kept in the history.
+type AppSample struct {
+	Time        int64
+	CPUUsec     uint64
+	MemoryBytes uint64
+	RSSBytes    uint64
+	ReadBytes   uint64
+	WriteBytes  uint64
+	Pids        uint64
+}
+
+var appSampleSize = binary.Size(AppSample{})
+
+func NewAppSample(now time.Time, usage AppUsage) AppSample {
+	return AppSample{
+		Time:        now.Unix(),
+		CPUUsec:     usage.CPUUsec,
+		MemoryBytes: usage.MemoryBytes,
+		RSSBytes:    usage.RSSBytes,
+		ReadBytes:   usage.ReadBytes,
+		WriteBytes:  usage.WriteBytes,
+		Pids:        usage.Pids,
+	}
+}
+
+// counterReset is true when the counters went backwards, the app was restarted.
+func (s AppSample) counterReset(prev AppSample) bool {
+	return s.CPUUsec < prev.CPUUsec || s.ReadBytes < prev.ReadBytes || s.WriteBytes < prev.WriteBytes
+}
+
+type appHistoryMetric func(prev AppSample, cur AppSample, seconds float64) float64
+
+var appHistoryMetrics = map[string]appHistoryMetric{
+	// cpu_percent is the percent of one cpu, an app using two cpus fully is at 200.
+	"cpu_percent": func(prev AppSample, cur AppSample, seconds float64) float64 {
+		return rate(prev.CPUUsec, cur.CPUUsec, 100.0/1000000, seconds)
+	},
+	"memory_bytes": func(_ AppSample, cur AppSample, _ float64) float64 {
+		return float64(cur.MemoryBytes)
+	},
+	"rss_bytes": func(_ AppSample, cur AppSample, _ float64) float64 {
+		return float64(cur.RSSBytes)
+	},
+	"disk_read_bytes_rate": func(prev AppSample, cur AppSample, seconds float64) float64 {
+		return rate(prev.ReadBytes, cur.ReadBytes, 1, seconds)
+	},
+	"disk_write_bytes_rate": func(prev AppSample, cur AppSample, seconds float64) float64 {
+		return rate(prev.WriteBytes, cur.WriteBytes, 1, seconds)
+	},
+	"pids": func(_ AppSample, cur AppSample, _ float64) float64 {
+		return float64(cur.Pids)
+	},
+}
+
+func AppHistoryMetrics() []string {
+	var names []string
+	for name := range appHistoryMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// points is a list of samples sorted by time which the series are computed from.
+type points interface {
+	len() int
+	unix(i int) int64
+	counterReset(i int) bool
+	value(name string, i int, seconds float64) float64
+}
+
+type hostPoints []Sample
+
+func (p hostPoints) len() int                { return len(p) }
+func (p hostPoints) unix(i int) int64        { return p[i].Time }
+func (p hostPoints) counterReset(i int) bool { return p[i].counterReset(p[i-1]) }
+func (p hostPoints) value(name string, i int, seconds float64) float64 {
+	return historyMetrics[name](p[i-1], p[i], seconds)
+}
+
+type appPoints []AppSample
+
+func (p appPoints) len() int                { return len(p) }
+func (p appPoints) unix(i int) int64        { return p[i].Time }
+func (p appPoints) counterReset(i int) bool { return p[i].counterReset(p[i-1]) }
+func (p appPoints) value(name string, i int, seconds float64) float64 {
+	return appHistoryMetrics[name](p[i-1], p[i], seconds)
+}
+
+type Series struct {
+	Step    int64                `json:"step"`
+	Times   []time.Time          `json:"times"`
+	Metrics map[string][]float64 `json:"metrics"`
+}
+
+// newSeries computes the metrics from consecutive samples, the first sample is before from and is only used for the deltas.
+func newSeries(step time.Duration, samples points, from time.Time, names []string) *Series {
+	series := &Series{
+		Step:    int64(step / time.Second),
+		Times:   []time.Time{},
+		Metrics: make(map[string][]float64),
+	}
+	for _, name := range names {
+		series.Metrics[name] = []float64{}
+	}
+	for i := 1; i < samples.len(); i++ {
+		seconds := float64(samples.unix(i) - samples.unix(i-1))
+		if seconds <= 0 || samples.counterReset(i) || samples.unix(i) < from.Unix() {
+			continue
+		}
+		series.Times = append(series.Times, time.Unix(samples.unix(i), 0).UTC())
+		for _, name := range names {
+			series.Metrics[name] = append(series.Metrics[name], samples.value(name, i, seconds))
+		}
+	}
+	return series
+}
+
+func validateNames(names []string, available []string) ([]string, error) {
+	if len(names) == 0 {
+		return available, nil
+	}
+	for _, name := range names {
+		found := false
+		for _, known := range available {
+			if name == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown metric %s, available: %s", name, strings.Join(available, ", "))
+		}
+	}
+	return names, nil
+}
+
+var snapNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type AppUsageSource interface {
+	Apps() ([]AppUsage, error)
+}
+
+// History samples the collectors in the background and keeps the samples at several resolutions,
+// the host and every app have their own rings.
+type History struct {
+	collector *Collector
+	apps      AppUsageSource
+	dir       string
+	rings     []*ring
+	appRings  map[string][]*ring
+	mutex     *sync.Mutex
+	now       func() time.Time
+	logger    *zap.Logger
+}
+
+func NewHistory(dir string, collector *Collector, apps AppUsageSource, logger *zap.Logger) *History {
+	return &History{
+		collector: collector,
+		apps:      apps,
+		dir:       dir,
+		rings:     newRings(dir, "metrics", sampleSize),
+		appRings:  make(map[string][]*ring),
+		mutex:     &sync.Mutex{},
+		now:       time.Now,
+		logger:    logger,
+	}
+}
+
+func (h *History) Start() error {
+	err := os.MkdirAll(h.dir, 0755)
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(Resolutions[0].Step)
+		defer ticker.Stop()
+		for range ticker.C {
+			h.sample()
+		}
+	}()
+	return nil
+}
+
+func (h *History) sample() {
+	now := h.now()
+	snapshot, err := h.collector.Snapshot()
+	if err != nil {
+		h.logger.Warn("cannot collect metrics", zap.Error(err))
+		return
+	}
+	err = h.Add(NewSample(now, snapshot))
+	if err != nil {
+		h.logger.Warn("cannot save metrics", zap.Error(err))
+	}
+	apps, err := h.apps.Apps()
+	if err != nil {
+		h.logger.Debug("cannot collect app metrics", zap.Error(err))
+		return
+	}
+	for _, usage := range apps {
+		err = h.AddApp(usage.App, NewAppSample(now, usage))
+		if err != nil {
+			h.logger.Warn("cannot save app metrics", zap.String("app", usage.App), zap.Error(err))
+		}
+	}
+}
+
+func (h *History) Add(sample Sample) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return write(h.rings, sample)
+}
+
+func (h *History) AddApp(app string, sample AppSample) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	rings, err := h.appRingsOf(app)
+	if err != nil {
+		return err
+	}
+	return write(rings, sample)
+}
+
+// appRingsOf creates the apps dir along with the rings so they can be written whichever of a query or a sample comes first.
+func (h *History) appRingsOf(app string) ([]*ring, error) {
+	rings, ok := h.appRings[app]
+	if !ok {
+		dir := filepath.Join(h.dir, "apps")
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, err
+		}
+		rings = newRings(dir, app, appSampleSize)
+		h.appRings[app] = rings
+	}
+	return rings, nil
+}
+
+func write(rings []*ring, record interface{}) error {
+	for _, r := range rings {
+		err := r.write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query returns the host metrics between from and to using the finest resolution which still keeps from,
+// rates are computed from the counter deltas of consecutive samples.
+func (h *History) Query(from time.Time, to time.Time, names []string) (*Series, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("from is after to")
+	}
+	names, err := validateNames(names, HistoryMetrics())
+	if err != nil {
+		return nil, err
+	}
+	selected := selectRing(h.rings, from, h.now())
+	step := selected.resolution.Step
+	h.mutex.Lock()
+	records, err := selected.read(from.Add(-step).Unix(), to.Unix())
+	h.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	samples := make(hostPoints, len(records))
+	err = decode(records, func(i int) interface{} { return &samples[i] })
+	if err != nil {
+		return nil, err
+	}
+	return newSeries(step, samples, from, names), nil
+}
+
+// QueryApp is Query for the metrics of one app.
+func (h *History) QueryApp(app string, from time.Time, to time.Time, names []string) (*Series, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("from is after to")
+	}
+	if !snapNameRe.MatchString(app) {
+		return nil, fmt.Errorf("invalid app %s", app)
+	}
+	names, err := validateNames(names, AppHistoryMetrics())
+	if err != nil {
+		return nil, err
+	}
+	h.mutex.Lock()
+	rings, err := h.appRingsOf(app)
+	if err != nil {
+		h.mutex.Unlock()
+		return nil, err
+	}
+	selected := selectRing(rings, from, h.now())
+	step := selected.resolution.Step
+	records, err := selected.read(from.Add(-step).Unix(), to.Unix())
+	h.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	samples := make(appPoints, len(records))
+	err = decode(records, func(i int) interface{} { return &samples[i] })
+	if err != nil {
+		return nil, err
+	}
+	return newSeries(step, samples, from, names), nil
+}
@@ -0,0 +1,155 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/log"
+)
+
+var historyStart = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestHistory(t *testing.T, now time.Time) *History {
+	history := NewHistory(t.TempDir(), nil, nil, log.Default())
+	history.now = func() time.Time { return now }
+	return history
+}
+
+func sampleAt(offset time.Duration, i uint64) Sample {
+	return Sample{
+		Time:           historyStart.Add(offset).Unix(),
+		CPUBusy:        i * 250,
+		CPUTotal:       i * 1000,
+		MemTotalKB:     1000,
+		MemAvailableKB: 250,
+		SwapTotalKB:    100,
+		SwapFreeKB:     40,
+		SectorsRead:    i * 20,
+		RxBytes:        i * 1000,
+	}
+}
+
+func TestHistory_Query_Rates(t *testing.T) {
+	history := newTestHistory(t, historyStart.Add(time.Minute))
+	for i := uint64(0); i <= 6; i++ {
+		require.NoError(t, history.Add(sampleAt(time.Duration(i)*10*time.Second, i)))
+	}
+
+	series, err := history.Query(historyStart, historyStart.Add(time.Minute), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(10), series.Step)
+	assert.Len(t, series.Times, 6)
+	assert.Equal(t, historyStart.Add(10*time.Second), series.Times[0])
+	assert.Equal(t, 25.0, series.Metrics["cpu_percent"][0])
+	assert.Equal(t, 75.0, series.Metrics["memory_used_percent"][0])
+	assert.Equal(t, 61440.0, series.Metrics["swap_used_bytes"][0])
+	assert.Equal(t, 1024.0, series.Metrics["disk_read_bytes_rate"][0])
+	assert.Equal(t, 100.0, series.Metrics["net_rx_bytes_rate"][5])
+}
+
+func TestHistory_Query_SelectedMetrics(t *testing.T) {
+	history := newTestHistory(t, historyStart.Add(time.Minute))
+	require.NoError(t, history.Add(sampleAt(0, 0)))
+	require.NoError(t, history.Add(sampleAt(10*time.Second, 1)))
+
+	series, err := history.Query(historyStart, historyStart.Add(time.Minute), []string{"cpu_percent"})
+	require.NoError(t, err)
+	assert.Len(t, series.Metrics, 1)
+	assert.Equal(t, []float64{25}, series.Metrics["cpu_percent"])
+
+	_, err = history.Query(historyStart, historyStart.Add(time.Minute), []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestHistory_Query_SkipsCounterReset(t *testing.T) {
+	history := newTestHistory(t, historyStart.Add(time.Minute))
+	require.NoError(t, history.Add(sampleAt(0, 5)))
+	require.NoError(t, history.Add(sampleAt(10*time.Second, 1)))
+	require.NoError(t, history.Add(sampleAt(20*time.Second, 2)))
+
+	series, err := history.Query(historyStart, historyStart.Add(time.Minute), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{historyStart.Add(20 * time.Second)}, series.Times)
+}
+
+func TestHistory_Query_Downsampled(t *testing.T) {
+	now := historyStart.Add(3 * time.Hour)
+	history := newTestHistory(t, now)
+	for i := uint64(0); i <= 3*360; i++ {
+		require.NoError(t, history.Add(sampleAt(time.Duration(i)*10*time.Second, i)))
+	}
+
+	series, err := history.Query(historyStart.Add(time.Hour), now, []string{"net_rx_bytes_rate"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), series.Step)
+	assert.Len(t, series.Times, 121)
+	assert.Equal(t, 100.0, series.Metrics["net_rx_bytes_rate"][0])
+
+	series, err = history.Query(now.Add(-30*time.Minute), now, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), series.Step)
+	assert.Len(t, series.Times, 181)
+}
+
+func TestHistory_Ring_Wraps(t *testing.T) {
+	history := newTestHistory(t, historyStart.Add(2*time.Hour))
+	for i := uint64(0); i <= 720; i++ {
+		require.NoError(t, history.Add(sampleAt(time.Duration(i)*10*time.Second, i)))
+	}
+
+	records, err := history.rings[0].read(0, historyStart.Add(3*time.Hour).Unix())
+	require.NoError(t, err)
+	assert.Len(t, records, 360)
+	samples := make([]Sample, len(records))
+	require.NoError(t, decode(records, func(i int) interface{} { return &samples[i] }))
+	assert.Equal(t, historyStart.Add(2*time.Hour).Unix(), samples[len(samples)-1].Time)
+	assert.Equal(t, uint64(720), samples[len(samples)-1].RxBytes/1000)
+}
+
+func TestHistory_QueryApp(t *testing.T) {
+	history := newTestHistory(t, historyStart.Add(time.Minute))
+	for i := uint64(0); i <= 3; i++ {
+		sample := AppSample{
+			Time:        historyStart.Add(time.Duration(i) * 10 * time.Second).Unix(),
+			CPUUsec:     i * 5000000,
+			MemoryBytes: 1000 + i,
+			WriteBytes:  i * 100,
+			Pids:        7,
+		}
+		require.NoError(t, history.AddApp("nextcloud", sample))
+	}
+	require.NoError(t, history.AddApp("mail", AppSample{Time: historyStart.Unix(), MemoryBytes: 5}))
+
+	series, err := history.QueryApp("nextcloud", historyStart, historyStart.Add(time.Minute), nil)
+	require.NoError(t, err)
+	assert.Len(t, series.Times, 3)
+	assert.Equal(t, 50.0, series.Metrics["cpu_percent"][0])
+	assert.Equal(t, 1003.0, series.Metrics["memory_bytes"][2])
+	assert.Equal(t, 10.0, series.Metrics["disk_write_bytes_rate"][1])
+	assert.Equal(t, 7.0, series.Metrics["pids"][0])
+
+	series, err = history.QueryApp("unknown", historyStart, historyStart.Add(time.Minute), []string{"pids"})
+	require.NoError(t, err)
+	assert.Len(t, series.Times, 0)
+
+	_, err = history.QueryApp("../etc", historyStart, historyStart.Add(time.Minute), nil)
+	assert.Error(t, err)
+	_, err = history.QueryApp("nextcloud", historyStart, historyStart.Add(time.Minute), []string{"memory_used_percent"})
+	assert.Error(t, err)
+}
+
+func TestHistory_QueryApp_BeforeAddApp(t *testing.T) {
+	history := newTestHistory(t, historyStart.Add(time.Minute))
+	series, err := history.QueryApp("nextcloud", historyStart, historyStart.Add(time.Minute), nil)
+	require.NoError(t, err)
+	assert.Len(t, series.Times, 0)
+
+	require.NoError(t, history.AddApp("nextcloud", AppSample{Time: historyStart.Unix(), Pids: 7}))
+	require.NoError(t, history.AddApp("nextcloud", AppSample{Time: historyStart.Add(10 * time.Second).Unix(), Pids: 7}))
+	series, err = history.QueryApp("nextcloud", historyStart, historyStart.Add(time.Minute), []string{"pids"})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{7}, series.Metrics["pids"])
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/syncloud/platform/stability"
 )
 
 type CPU struct {
@@ -60,20 +62,31 @@ type Net struct {
 	TxBytes uint64 `json:"tx_bytes"`
 }
 
+// Zram is the zram swap device, OrigDataBytes are stored in ComprDataBytes and take MemUsedBytes of memory.
+type Zram struct {
+	Algorithm      string `json:"algorithm"`
+	DiskSizeBytes  uint64 `json:"disk_size_bytes"`
+	OrigDataBytes  uint64 `json:"orig_data_bytes"`
+	ComprDataBytes uint64 `json:"compr_data_bytes"`
+	MemUsedBytes   uint64 `json:"mem_used_bytes"`
+}
+
 type Snapshot struct {
 	CPU    CPU     `json:"cpu"`
 	Memory Memory  `json:"memory"`
 	Disks  []Disk  `json:"disks"`
 	Mounts []Mount `json:"mounts"`
 	Net    []Net   `json:"net"`
+	Zram   *Zram   `json:"zram,omitempty"`
 }
 
 type Collector struct {
 	procDir string
+	zramDir string
 }
 
-func NewCollector(procDir string) *Collector {
-	return &Collector{procDir: procDir}
+func NewCollector(procDir string, zramDir string) *Collector {
+	return &Collector{procDir: procDir, zramDir: zramDir}
 }
 
 func (c *Collector) Snapshot() (Snapshot, error) {
@@ -92,9 +105,35 @@ func (c *Collector) Snapshot() (Snapshot, error) {
 	s.Disks, _ = c.readDisks()
 	s.Net, _ = c.readNet()
 	s.Mounts = c.Mounts()
+	s.Zram = c.readZram()
 	return s, nil
 }
 
+// readZram reads mm_stat of the zram device, nil when there is no zram.
+func (c *Collector) readZram() *Zram {
+	mmStat, err := os.ReadFile(filepath.Join(c.zramDir, "mm_stat"))
+	if err != nil {
+		return nil
+	}
+	fields := strings.Fields(string(mmStat))
+	if len(fields) < 3 {
+		return nil
+	}
+	z := &Zram{}
+	z.OrigDataBytes, _ = strconv.ParseUint(fields[0], 10, 64)
+	z.ComprDataBytes, _ = strconv.ParseUint(fields[1], 10, 64)
+	z.MemUsedBytes, _ = strconv.ParseUint(fields[2], 10, 64)
+	diskSize, err := os.ReadFile(filepath.Join(c.zramDir, "disksize"))
+	if err == nil {
+		z.DiskSizeBytes, _ = strconv.ParseUint(strings.TrimSpace(string(diskSize)), 10, 64)
+	}
+	algorithm, err := os.ReadFile(filepath.Join(c.zramDir, "comp_algorithm"))
+	if err == nil {
+		_, z.Algorithm = stability.ParseCompAlgorithm(string(algorithm))
+	}
+	return z
+}
+
 func (c *Collector) readCPU() (CPU, error) {
 	f, err := os.Open(filepath.Join(c.procDir, "stat"))
 	if err != nil {
@@ -0,0 +1,131 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type MetricsServerConfig interface {
+	GetMetricsListen() string
+	SetMetricsListen(address string)
+}
+
+// MetricsServer serves the OpenMetrics text without authentication for a local Prometheus,
+// it is disabled unless a listen address is configured and only binds to loopback.
+type MetricsServer struct {
+	exporter *Exporter
+	config   MetricsServerConfig
+	mutex    sync.Mutex
+	started  bool
+	listener net.Listener
+	logger   *zap.Logger
+}
+
+func NewMetricsServer(exporter *Exporter, config MetricsServerConfig, logger *zap.Logger) *MetricsServer {
+	return &MetricsServer{
+		exporter: exporter,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+func (s *MetricsServer) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.started = true
+	err := s.listen(s.config.GetMetricsListen())
+	if err != nil {
+		s.logger.Warn("metrics listener is disabled", zap.Error(err))
+	}
+	return nil
+}
+
+// Listen is the configured address, empty when the listener is disabled.
+func (s *MetricsServer) Listen() string {
+	return s.config.GetMetricsListen()
+}
+
+// SetListen saves the listen address, an empty one disables the listener, a started server rebinds right away.
+func (s *MetricsServer) SetListen(address string) error {
+	if address != "" {
+		err := ValidateListen(address)
+		if err != nil {
+			return err
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.config.SetMetricsListen(address)
+	if !s.started {
+		return nil
+	}
+	if s.listener != nil {
+		err := s.listener.Close()
+		if err != nil {
+			s.logger.Warn("cannot close metrics listener", zap.Error(err))
+		}
+		s.listener = nil
+	}
+	return s.listen(address)
+}
+
+func (s *MetricsServer) listen(address string) error {
+	if address == "" {
+		return nil
+	}
+	err := ValidateListen(address)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handle)
+	s.logger.Info("metrics listener", zap.String("address", address))
+	go func() {
+		err := http.Serve(listener, mux)
+		if errors.Is(err, net.ErrClosed) {
+			s.logger.Info("metrics listener closed", zap.String("address", address))
+			return
+		}
+		s.logger.Error("metrics listener stopped", zap.Error(err))
+	}()
+	return nil
+}
+
+func (s *MetricsServer) handle(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	err := s.exporter.Write(w)
+	if err != nil {
+		s.logger.Error("metrics", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ValidateListen accepts host:port where host is localhost or a loopback ip address,
+// the metrics are not authenticated so they are never exposed to the network.
+func ValidateListen(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%s is not localhost or an ip address", host)
+	}
+	if !ip.IsLoopback() {
+		return fmt.Errorf("%s is not a loopback address", host)
+	}
+	return nil
+}
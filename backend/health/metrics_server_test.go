@@ -0,0 +1,63 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/log"
+)
+
+type MetricsServerConfigStub struct {
+	listen string
+}
+
+func (c *MetricsServerConfigStub) GetMetricsListen() string {
+	return c.listen
+}
+
+func (c *MetricsServerConfigStub) SetMetricsListen(address string) {
+	c.listen = address
+}
+
+func newTestMetricsServer(t *testing.T, config *MetricsServerConfigStub) *MetricsServer {
+	collector, _ := newTestCollector(t)
+	exporter := NewExporter(collector, &EventSourceStub{}, &DeviceErrorsStub{}, &CertificateInfoStub{}, &JobListStub{}, log.Default())
+	return NewMetricsServer(exporter, config, log.Default())
+}
+
+func TestMetricsServer_SetListen_NotLoopback(t *testing.T) {
+	config := &MetricsServerConfigStub{}
+	server := newTestMetricsServer(t, config)
+	assert.Error(t, server.SetListen("192.168.1.10:9100"))
+	assert.Error(t, server.SetListen("0.0.0.0:9100"))
+	assert.Equal(t, "", config.listen)
+}
+
+func TestMetricsServer_SetListen_NotStarted(t *testing.T) {
+	config := &MetricsServerConfigStub{}
+	server := newTestMetricsServer(t, config)
+	require.NoError(t, server.SetListen("127.0.0.1:9100"))
+	assert.Equal(t, "127.0.0.1:9100", server.Listen())
+	assert.Nil(t, server.listener)
+}
+
+func TestMetricsServer_SetListen_Started(t *testing.T) {
+	config := &MetricsServerConfigStub{}
+	server := newTestMetricsServer(t, config)
+	require.NoError(t, server.Start())
+	assert.Nil(t, server.listener)
+
+	require.NoError(t, server.SetListen("127.0.0.1:0"))
+	require.NotNil(t, server.listener)
+	response, err := http.Get(fmt.Sprintf("http://%s/metrics", server.listener.Addr()))
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	require.NoError(t, server.SetListen(""))
+	assert.Nil(t, server.listener)
+	assert.Equal(t, "", config.listen)
+}
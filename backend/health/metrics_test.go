@@ -32,7 +32,7 @@ func newTestCollector(t *testing.T) (*Collector, string) {
     lo: 1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
   eth0: 5000      20    0    0    0     0          0         0     8000      30    0    0    0     0       0          0
 `)
-	return NewCollector(dir), dir
+	return NewCollector(dir, filepath.Join(dir, "zram0")), dir
 }
 
 func TestReadCPU(t *testing.T) {
@@ -61,7 +61,7 @@ func TestReadSwapCounters(t *testing.T) {
 }
 
 func TestReadSwapCountersMissingVmstat(t *testing.T) {
-	c := NewCollector(t.TempDir())
+	c := NewCollector(t.TempDir(), t.TempDir())
 	in, out := c.readSwapCounters()
 	assert.Equal(t, uint64(0), in)
 	assert.Equal(t, uint64(0), out)
@@ -117,3 +117,19 @@ func TestIsPartition(t *testing.T) {
 		assert.Equal(t, c.want, isPartition(c.name), c.name)
 	}
 }
+
+func TestReadZram(t *testing.T) {
+	c, dir := newTestCollector(t)
+	assert.Nil(t, c.readZram())
+
+	writeProc(t, dir, "zram0/mm_stat", "  4096000  1024000  1200000        0  1300000      100        0        0        0\n")
+	writeProc(t, dir, "zram0/disksize", "2147483648\n")
+	writeProc(t, dir, "zram0/comp_algorithm", "lzo lzo-rle lz4 [zstd]\n")
+	assert.Equal(t, &Zram{
+		Algorithm:      "zstd",
+		DiskSizeBytes:  2147483648,
+		OrigDataBytes:  4096000,
+		ComprDataBytes: 1024000,
+		MemUsedBytes:   1200000,
+	}, c.readZram())
+}
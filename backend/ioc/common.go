@@ -5,6 +5,7 @@ import (
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/syncloud/platform/access"
 	"github.com/syncloud/platform/activation"
+	"github.com/syncloud/platform/alert"
 	"github.com/syncloud/platform/auth"
 	"github.com/syncloud/platform/backup"
 	"github.com/syncloud/platform/cert"
@@ -30,6 +31,7 @@ import (
 	"github.com/syncloud/platform/stability"
 	"github.com/syncloud/platform/storage"
 	"github.com/syncloud/platform/storage/btrfs"
+	"github.com/syncloud/platform/storage/smart"
 	"github.com/syncloud/platform/support"
 	"github.com/syncloud/platform/systemd"
 	"github.com/syncloud/platform/timezone"
@@ -87,6 +89,18 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
+	err = c.Singleton(func(db *config.Db, _ *config.Migrator) *config.BackupTargets {
+		return config.NewBackupTargets(db)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(db *config.Db, _ *config.Migrator) *config.JobHistory {
+		return config.NewJobHistory(db)
+	})
+	if err != nil {
+		return nil, err
+	}
 	err = c.Singleton(func() *config.SystemConfig {
 		systemConfig := config.NewSystemConfig(systemConfig)
 		systemConfig.Load()
@@ -256,8 +270,14 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(certGenerator *cert.CertificateGenerator) *cron.CertificateJob {
-		return cron.NewCertificateJob(certGenerator)
+	err = c.Singleton(func(userConfig *config.UserConfig, provider *date.RealProvider) *cron.Schedules {
+		return cron.NewSchedules(userConfig, provider, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(certGenerator *cert.CertificateGenerator, schedules *cron.Schedules, provider *date.RealProvider) *cron.CertificateJob {
+		return cron.NewCertificateJob(certGenerator, schedules, provider)
 	})
 	if err != nil {
 		return nil, err
@@ -282,11 +302,13 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func() *job.SingleJobMaster { return job.NewMaster() })
+	err = c.Singleton(func(systemConfig *config.SystemConfig, history *config.JobHistory) *job.Queue {
+		return job.NewQueue(path.Join(systemConfig.DataDir(), "jobs.json"), history, logger)
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(master *job.SingleJobMaster, logger *zap.Logger) *job.Worker {
+	err = c.Singleton(func(master *job.Queue, logger *zap.Logger) *job.Worker {
 		return job.NewWorker(master, logger)
 	})
 	if err != nil {
@@ -298,31 +320,14 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(executor *cli.ShellExecutor, diskusage *du.ShellDiskUsage, snapCli *snap.Cli, snapServer *snap.Server, logger *zap.Logger, userConfig *config.UserConfig, dateProvider *date.RealProvider) *backup.Backup {
-		return backup.New(backupDir, varDir, executor, diskusage, snapCli, snapServer, userConfig, dateProvider, logger)
-	})
-	if err != nil {
-		return nil, err
-	}
 	err = c.Singleton(func(job *access.ExternalAddress) *cron.ExternalAddressJob {
 		return cron.NewExternalAddressJob(job)
 	})
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func() *cron.SimpleScheduler { return &cron.SimpleScheduler{} })
-
-	if err != nil {
-		return nil, err
-	}
-	err = c.Singleton(func(snapd *snap.Server, userConfig *config.UserConfig, provider *date.RealProvider, backup *backup.Backup, scheduler *cron.SimpleScheduler, logger *zap.Logger) *cron.BackupJob {
-		return cron.NewBackupJob(snapd, userConfig, backup, provider, scheduler, logger)
-	})
-	if err != nil {
-		return nil, err
-	}
-	err = c.Singleton(func(executor *cli.ShellExecutor, dateProvider *date.RealProvider) *cron.TimeSyncJob {
-		return cron.NewTimeSyncJob(executor, dateProvider, logger)
+	err = c.Singleton(func(executor *cli.ShellExecutor, schedules *cron.Schedules, dateProvider *date.RealProvider) *cron.TimeSyncJob {
+		return cron.NewTimeSyncJob(executor, schedules, dateProvider, logger)
 	})
 	if err != nil {
 		return nil, err
@@ -333,8 +338,8 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(snapd *snap.Server, upgrader *snap.Snapd, master *job.SingleJobMaster, scheduler *cron.SimpleScheduler, provider *date.RealProvider) *cron.SnapdUpgradeJob {
-		return cron.NewSnapdUpgradeJob(snapd, upgrader, master, scheduler, provider, logger)
+	err = c.Singleton(func(snapd *snap.Server, master *job.Queue, scheduler *cron.Schedules, provider *date.RealProvider) *cron.SnapdUpgradeJob {
+		return cron.NewSnapdUpgradeJob(snapd, master, scheduler, provider, logger)
 	})
 	if err != nil {
 		return nil, err
@@ -351,12 +356,6 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(job1 *cron.CertificateJob, job2 *cron.ExternalAddressJob, job3 *cron.BackupJob, job4 *cron.TimeSyncJob, job5 *cron.SnapdUpgradeJob, job6 *cron.SnapshotsJob, userConfig *config.UserConfig) *cron.Cron {
-		return cron.New([]cron.Job{job1, job2, job3, job4, job5, job6}, time.Minute*5, userConfig)
-	})
-	if err != nil {
-		return nil, err
-	}
 	err = c.Singleton(func(systemConfig *config.SystemConfig, executor *cli.ShellExecutor, logger *zap.Logger) *storage.Storage {
 		return storage.New(systemConfig, executor, logger)
 	})
@@ -475,6 +474,73 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 		return nil, err
 	}
 
+	err = c.Singleton(func(db *config.Db, migrator *config.Migrator, ldapClient *auth.LdapClient, ldapService *auth.Initializer, web *auth.Authelia, nginxService *nginx.Nginx, systemConfig *config.SystemConfig) *backup.Platform {
+		return backup.NewPlatform(db, migrator, ldapClient, ldapService, web, nginxService, systemConfig, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(executor *cli.ShellExecutor, diskusage *du.ShellDiskUsage, snapCli *snap.Cli, snapServer *snap.Server, logger *zap.Logger, userConfig *config.UserConfig, targets *config.BackupTargets, platform *backup.Platform, dateProvider *date.RealProvider, master *job.Queue) *backup.Backup {
+		return backup.New(backupDir, varDir, executor, diskusage, snapCli, snapServer, userConfig, targets, platform, dateProvider, master, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(systemConfig *config.SystemConfig) *stability.EventLog {
+		return stability.NewEventLog(path.Join(systemConfig.DataDir(), "stability-events.jsonl"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(snapd *snap.Server, snapCli *snap.Cli, userConfig *config.UserConfig) *cron.RestoreVerifier {
+		return cron.NewRestoreVerifier(snapd, snapCli, userConfig, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(snapd *snap.Server, userConfig *config.UserConfig, provider *date.RealProvider, backup *backup.Backup, scheduler *cron.Schedules, history *config.JobHistory, verifier *cron.RestoreVerifier, events *stability.EventLog, logger *zap.Logger) *cron.BackupJob {
+		return cron.NewBackupJob(snapd, userConfig, backup, provider, scheduler, history, verifier, events, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(config *config.SystemConfig) *storage.PathChecker { return storage.NewPathChecker(config, logger) })
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(systemConfig *config.SystemConfig, executor *cli.ShellExecutor, checker *storage.PathChecker) *storage.Lsblk {
+		return storage.NewLsblk(systemConfig, checker, executor, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(executor *cli.ShellExecutor) *smart.Smart {
+		return smart.New(executor, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(lsblk *storage.Lsblk, smart *smart.Smart, events *stability.EventLog, systemConfig *config.SystemConfig) *cron.SmartJob {
+		return cron.NewSmartJob(lsblk, smart, events, path.Join(systemConfig.DataDir(), "smart.json"), logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(job1 *cron.CertificateJob, job2 *cron.ExternalAddressJob, job3 *cron.BackupJob, job4 *cron.TimeSyncJob, job5 *cron.SnapdUpgradeJob, job6 *cron.SnapshotsJob, job7 *cron.SmartJob, provider *date.RealProvider, userConfig *config.UserConfig) *cron.Cron {
+		return cron.New([]cron.Job{
+			job1,
+			cron.NewPeriodicJob(job2, time.Minute*5, provider),
+			job3,
+			job4,
+			job5,
+			cron.NewPeriodicJob(job6, time.Minute*5, provider),
+			cron.NewPeriodicJob(job7, time.Hour, provider),
+		}, time.Minute, userConfig)
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	err = c.Singleton(func(ldapService *auth.Initializer, nginxService *nginx.Nginx, userConfig *config.UserConfig,
 		eventTrigger *event.Trigger, cookies *session.Cookies,
 		storage *storage.Storage, web *auth.Authelia,
@@ -519,8 +585,14 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(activationManaged *activation.Managed, activationCustom *activation.Custom, tz *timezone.Applier) *rest.Activate {
-		return rest.NewActivateBackend(activationManaged, activationCustom, tz)
+	err = c.Singleton(func(backupService *backup.Backup, storage *storage.Storage) *activation.Restore {
+		return activation.NewRestore(backupService, storage, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(activationManaged *activation.Managed, activationCustom *activation.Custom, activationRestore *activation.Restore, tz *timezone.Applier) *rest.Activate {
+		return rest.NewActivateBackend(activationManaged, activationCustom, activationRestore, tz)
 	})
 	if err != nil {
 		return nil, err
@@ -534,16 +606,6 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 		return rest.NewCertificate(certGenerator, journalCtl)
 	})
 
-	if err != nil {
-		return nil, err
-	}
-	err = c.Singleton(func(config *config.SystemConfig) *storage.PathChecker { return storage.NewPathChecker(config, logger) })
-	if err != nil {
-		return nil, err
-	}
-	err = c.Singleton(func(systemConfig *config.SystemConfig, executor *cli.ShellExecutor, checker *storage.PathChecker) *storage.Lsblk {
-		return storage.NewLsblk(systemConfig, checker, executor, logger)
-	})
 	if err != nil {
 		return nil, err
 	}
@@ -581,8 +643,8 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 	}
 	err = c.Singleton(func(systemConfig *config.SystemConfig, freeSpaceChecker *storage.FreeSpaceChecker,
 		systemd *systemd.Control, eventTrigger *event.Trigger, lsblk *storage.Lsblk,
-		executor *cli.ShellExecutor, linker *storage.Linker, btrfs *btrfs.Disks, stats *btrfs.Stats) *storage.Disks {
-		return storage.NewDisks(systemConfig, eventTrigger, lsblk, systemd, freeSpaceChecker, linker, executor, btrfs, stats, logger)
+		executor *cli.ShellExecutor, linker *storage.Linker, btrfs *btrfs.Disks, stats *btrfs.Stats, smart *smart.Smart) *storage.Disks {
+		return storage.NewDisks(systemConfig, eventTrigger, lsblk, systemd, freeSpaceChecker, linker, executor, btrfs, stats, smart, logger)
 	})
 
 	if err != nil {
@@ -657,20 +719,66 @@ func Init(userConfig string, systemConfig string, backupDir string, varDir strin
 		return nil, err
 	}
 
-	err = c.Singleton(func(systemConfig *config.SystemConfig) *stability.EventLog {
-		return stability.NewEventLog(path.Join(systemConfig.DataDir(), "stability-events.jsonl"))
+	err = c.Singleton(func() *health.Collector {
+		return health.NewCollector("/proc", "/sys/block/zram0")
 	})
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func() *health.Collector {
-		return health.NewCollector("/proc")
+	err = c.Singleton(func(collector *health.Collector, events *stability.EventLog, stats *btrfs.Stats, certGenerator *cert.CertificateGenerator, master *job.Queue) *health.Exporter {
+		return health.NewExporter(collector, events, stats, certGenerator, master, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(exporter *health.Exporter, userConfig *config.UserConfig) *health.MetricsServer {
+		return health.NewMetricsServer(exporter, userConfig, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func() *health.AppCollector {
+		return health.NewAppCollector("/sys/fs/cgroup")
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(systemConfig *config.SystemConfig, collector *health.Collector, apps *health.AppCollector) *health.History {
+		return health.NewHistory(path.Join(systemConfig.DataDir(), "health"), collector, apps, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(db *config.Db, _ *config.Migrator) *config.Alerts {
+		return config.NewAlerts(db)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(userConfig *config.UserConfig, redirect *config.Redirect) *alert.Email {
+		return alert.NewEmail(userConfig, redirect)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(email *alert.Email) *alert.Notifier {
+		return alert.NewNotifier(email)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.Singleton(func(alerts *config.Alerts, userConfig *config.UserConfig, collector *health.Collector, diskSpace *storage.DiskSpace,
+		stats *btrfs.Stats, certGenerator *cert.CertificateGenerator, events *stability.EventLog, notifier *alert.Notifier,
+		systemConfig *config.SystemConfig, provider *date.RealProvider) *alert.Monitor {
+		return alert.NewMonitor(alerts, userConfig, collector, diskSpace, stats, certGenerator, events, notifier,
+			path.Join(systemConfig.DataDir(), "alerts.json"), provider, logger)
 	})
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(events *stability.EventLog, collector *health.Collector) *health.Health {
-		return health.NewHealth(events, collector)
+	err = c.Singleton(func(events *stability.EventLog, collector *health.Collector, exporter *health.Exporter, history *health.History, apps *health.AppCollector, systemConfig *config.SystemConfig, server *health.MetricsServer) *health.Health {
+		return health.NewHealth(events, collector, exporter, history, apps,
+			stability.NewPolicyFile(path.Join(systemConfig.DataDir(), stability.PolicyFileName)), server)
 	})
 	if err != nil {
 		return nil, err
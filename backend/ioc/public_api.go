@@ -3,6 +3,7 @@ package ioc
 import (
 	"github.com/golobby/container/v3"
 	"github.com/syncloud/platform/access"
+	"github.com/syncloud/platform/alert"
 	"github.com/syncloud/platform/auth"
 	"github.com/syncloud/platform/backup"
 	"github.com/syncloud/platform/cli"
@@ -39,7 +40,7 @@ func InitPublicApi(userConfig string, systemConfig string, backupDir string, var
 	if err != nil {
 		return nil, err
 	}
-	err = c.Singleton(func(master *job.SingleJobMaster, backupService *backup.Backup, eventTrigger *event.Trigger, worker *job.Worker,
+	err = c.Singleton(func(master *job.Queue, backupService *backup.Backup, eventTrigger *event.Trigger, worker *job.Worker,
 		redirectService *redirect.Service, snapdUpgrader *snap.Snapd, storageService *storage.Storage,
 		id *identification.Parser, activate *rest.Activate, userConfig *config.UserConfig, redirectConfig *config.Redirect, cert *rest.Certificate,
 		externalAddress *access.ExternalAddress, snapd *snap.Server, disks *storage.Disks, diskSpace *storage.DiskSpace, journalCtl *systemd.Journal,
@@ -49,12 +50,15 @@ func InitPublicApi(userConfig string, systemConfig string, backupDir string, var
 		oidcService *auth.OIDCService, authelia *auth.Authelia, totp *auth.TOTP,
 		tz *timezone.Applier,
 		healthService *health.Health,
+		jobHistory *config.JobHistory,
+		schedules *cron.Schedules,
+		alerts *alert.Monitor,
 	) *rest.Backend {
 		return rest.NewBackend(master, backupService, eventTrigger, worker, redirectService,
 			snapdUpgrader, storageService, id, activate, userConfig, redirectConfig, cert, externalAddress,
 			snapd, disks, diskSpace, journalCtl, power, uptime, iface, sender, proxy, customProxy,
 			userManager, groupManager, middleware, cookies, net, address, changesClient,
-			oidcService, authelia, totp, tz, healthService, logger)
+			oidcService, authelia, totp, tz, healthService, jobHistory, schedules, alerts, logger)
 	})
 	if err != nil {
 		return nil, err
@@ -65,6 +69,9 @@ func InitPublicApi(userConfig string, systemConfig string, backupDir string, var
 		cookies *session.Cookies,
 		backend *rest.Backend,
 		lcdDisplay *lcd.Display,
+		metricsServer *health.MetricsServer,
+		metricsHistory *health.History,
+		alerts *alert.Monitor,
 	) []Service {
 		return []Service{
 			cronService,
@@ -72,6 +79,9 @@ func InitPublicApi(userConfig string, systemConfig string, backupDir string, var
 			cookies,
 			backend,
 			lcdDisplay,
+			metricsServer,
+			metricsHistory,
+			alerts,
 		}
 	})
 	if err != nil {
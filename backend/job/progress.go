@@ -0,0 +1,48 @@
+package job
+
+import "time"
+
+type Progress struct {
+	Phase   string `json:"phase"`
+	Done    int64  `json:"done"`
+	Total   int64  `json:"total"`
+	Percent int    `json:"percent"`
+	Eta     int64  `json:"eta"`
+}
+
+// progressTracker keeps the progress of the running job, ETA is estimated from the speed of the current phase.
+type progressTracker struct {
+	phase   string
+	done    int64
+	total   int64
+	started time.Time
+}
+
+func (p *progressTracker) setPhase(phase string, now time.Time) {
+	p.phase = phase
+	p.done = 0
+	p.total = 0
+	p.started = now
+}
+
+func (p *progressTracker) setBytes(done int64, total int64) {
+	p.done = done
+	p.total = total
+}
+
+func (p *progressTracker) progress(now time.Time) *Progress {
+	if p.phase == "" {
+		return nil
+	}
+	progress := &Progress{Phase: p.phase, Done: p.done, Total: p.total}
+	if p.total > 0 && p.done > 0 {
+		done := p.done
+		if done > p.total {
+			done = p.total
+		}
+		progress.Percent = int(done * 100 / p.total)
+		elapsed := now.Sub(p.started)
+		progress.Eta = int64(float64(p.total-done) * elapsed.Seconds() / float64(done))
+	}
+	return progress
+}
@@ -0,0 +1,29 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressTracker_Eta_LargeBackup(t *testing.T) {
+	tracker := &progressTracker{}
+	started := time.Now()
+	tracker.setPhase("compress", started)
+	tracker.setBytes(1<<30, 8<<30)
+
+	progress := tracker.progress(started.Add(10 * time.Minute))
+	assert.Equal(t, 12, progress.Percent)
+	assert.Equal(t, int64(70*60), progress.Eta)
+}
+
+func TestProgressTracker_Eta_EarlyInLargeBackup(t *testing.T) {
+	tracker := &progressTracker{}
+	started := time.Now()
+	tracker.setPhase("compress", started)
+	tracker.setBytes(10<<20, 1<<30)
+
+	progress := tracker.progress(started.Add(10 * time.Second))
+	assert.Equal(t, int64(1014), progress.Eta)
+}
@@ -0,0 +1,360 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/syncloud/platform/config"
+	"go.uber.org/zap"
+)
+
+type Job func() error
+
+// Factory creates a job from the arguments it was submitted with,
+// it is used again for jobs which were still queued when the backend restarted.
+type Factory func(args json.RawMessage) (Job, error)
+
+const (
+	Queued    = "queued"
+	Running   = "running"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+	Cancelled = "cancelled"
+)
+
+const (
+	SourceUser = "user"
+	SourceCron = "cron"
+)
+
+// KeepFinished is the number of finished jobs kept in the list.
+const KeepFinished = 50
+
+type Entry struct {
+	Id       int64           `json:"id"`
+	Name     string          `json:"name"`
+	Source   string          `json:"source,omitempty"`
+	Status   string          `json:"status"`
+	Args     json.RawMessage `json:"args,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Created  time.Time       `json:"created"`
+	Started  *time.Time      `json:"started,omitempty"`
+	Finished *time.Time      `json:"finished,omitempty"`
+	Progress *Progress       `json:"progress,omitempty"`
+}
+
+func (e Entry) finished() bool {
+	return e.Status != Queued && e.Status != Running
+}
+
+type History interface {
+	Add(record config.JobRecord) error
+}
+
+// Queue runs offered jobs one at a time in FIFO order, so jobs which must not overlap
+// (backups, upgrades, disk changes) stay serialized.
+// The job list is saved to a file. Submitted jobs which were still queued before a restart are created
+// again from their registered factory, running jobs and jobs offered as plain functions are marked as failed.
+type Queue struct {
+	mutex     *sync.Mutex
+	file      string
+	entries   []Entry
+	jobs      map[int64]Job
+	factories map[string]Factory
+	lastId    int64
+	progress  progressTracker
+	history   History
+	now       func() time.Time
+	logger    *zap.Logger
+}
+
+func NewQueue(file string, history History, logger *zap.Logger) *Queue {
+	q := &Queue{
+		mutex:     &sync.Mutex{},
+		file:      file,
+		jobs:      make(map[int64]Job),
+		factories: make(map[string]Factory),
+		history:   history,
+		now:       time.Now,
+		logger:    logger,
+	}
+	q.load()
+	return q
+}
+
+func (q *Queue) load() {
+	data, err := os.ReadFile(q.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			q.logger.Warn("cannot read job list", zap.Error(err))
+		}
+		return
+	}
+	err = json.Unmarshal(data, &q.entries)
+	if err != nil {
+		q.logger.Warn("cannot parse job list", zap.Error(err))
+		q.entries = nil
+		return
+	}
+	now := q.now()
+	for i := range q.entries {
+		if q.entries[i].Id > q.lastId {
+			q.lastId = q.entries[i].Id
+		}
+		entry := q.entries[i]
+		if entry.Status == Running || entry.Status == Queued && entry.Args == nil {
+			q.fail(i, "interrupted by restart", now)
+		}
+	}
+}
+
+func (q *Queue) fail(i int, reason string, now time.Time) {
+	q.entries[i].Status = Failed
+	q.entries[i].Error = reason
+	q.entries[i].Finished = &now
+	q.record(q.entries[i])
+}
+
+func (q *Queue) save() {
+	data, err := json.Marshal(q.entries)
+	if err != nil {
+		q.logger.Warn("cannot save job list", zap.Error(err))
+		return
+	}
+	tmp := q.file + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err == nil {
+		err = os.Rename(tmp, q.file)
+	}
+	if err != nil {
+		q.logger.Warn("cannot save job list", zap.Error(err))
+	}
+}
+
+func (q *Queue) trim() {
+	finished := 0
+	for _, entry := range q.entries {
+		if entry.finished() {
+			finished++
+		}
+	}
+	var entries []Entry
+	for _, entry := range q.entries {
+		if entry.finished() && finished > KeepFinished {
+			finished--
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	q.entries = entries
+}
+
+func (q *Queue) find(status string) int {
+	for i, entry := range q.entries {
+		if entry.Status == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// Status is the summary of the queue kept for the clients which wait for jobs to finish.
+func (q *Queue) Status() Status {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if i := q.find(Running); i >= 0 {
+		status := NewStatus(q.entries[i].Name, Busy)
+		status.Progress = q.progress.progress(q.now())
+		return status
+	}
+	if i := q.find(Queued); i >= 0 {
+		return NewStatus(q.entries[i].Name, Waiting)
+	}
+	return NewStatus("", Idle)
+}
+
+// List returns queued, running and recently finished jobs, oldest first.
+func (q *Queue) List() []Entry {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	entries := make([]Entry, len(q.entries))
+	copy(entries, q.entries)
+	if i := q.find(Running); i >= 0 {
+		entries[i].Progress = q.progress.progress(q.now())
+	}
+	return entries
+}
+
+// Phase starts a new phase of the running job.
+func (q *Queue) Phase(phase string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.progress.setPhase(phase, q.now())
+}
+
+// Bytes reports bytes processed in the current phase of the running job.
+func (q *Queue) Bytes(done int64, total int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.progress.setBytes(done, total)
+}
+
+// Register sets the factory of the jobs submitted with name.
+func (q *Queue) Register(name string, factory Factory) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.factories[name] = factory
+}
+
+// Submit queues a job created by the factory registered for name, args are saved with the job
+// so that it is created again if the backend restarts before the job runs.
+func (q *Queue) Submit(source string, name string, args interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	factory, ok := q.factories[name]
+	if !ok {
+		return fmt.Errorf("job %s is not registered", name)
+	}
+	job, err := factory(data)
+	if err != nil {
+		return err
+	}
+	q.add(source, name, data, job)
+	return nil
+}
+
+func (q *Queue) Offer(name string, job Job) error {
+	return q.OfferFrom(SourceUser, name, job)
+}
+
+// OfferFrom queues a job triggered by source, the source is kept in the job history.
+func (q *Queue) OfferFrom(source string, name string, job Job) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.add(source, name, nil, job)
+	return nil
+}
+
+func (q *Queue) add(source string, name string, args json.RawMessage, job Job) {
+	q.lastId++
+	q.entries = append(q.entries, Entry{
+		Id:      q.lastId,
+		Name:    name,
+		Source:  source,
+		Status:  Queued,
+		Args:    args,
+		Created: q.now(),
+	})
+	q.jobs[q.lastId] = job
+	q.save()
+}
+
+// Cancel removes a job which has not started yet.
+func (q *Queue) Cancel(id int64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i, entry := range q.entries {
+		if entry.Id != id {
+			continue
+		}
+		if entry.Status != Queued {
+			return fmt.Errorf("job %d is %s, only queued jobs can be cancelled", id, entry.Status)
+		}
+		now := q.now()
+		q.entries[i].Status = Cancelled
+		q.entries[i].Finished = &now
+		delete(q.jobs, id)
+		q.record(q.entries[i])
+		q.trim()
+		q.save()
+		return nil
+	}
+	return fmt.Errorf("job %d is not found", id)
+}
+
+func (q *Queue) Take() Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.find(Running) >= 0 {
+		return nil
+	}
+	for {
+		i := q.find(Queued)
+		if i < 0 {
+			return nil
+		}
+		now := q.now()
+		job, err := q.job(q.entries[i])
+		if err != nil {
+			q.logger.Warn("cannot restore queued job", zap.String("name", q.entries[i].Name), zap.Error(err))
+			q.fail(i, err.Error(), now)
+			q.trim()
+			q.save()
+			continue
+		}
+		q.entries[i].Status = Running
+		q.entries[i].Started = &now
+		q.progress = progressTracker{}
+		delete(q.jobs, q.entries[i].Id)
+		q.save()
+		return job
+	}
+}
+
+// job returns the function of a queued entry, entries loaded after a restart are created by their factory.
+func (q *Queue) job(entry Entry) (Job, error) {
+	if job, ok := q.jobs[entry.Id]; ok {
+		return job, nil
+	}
+	factory, ok := q.factories[entry.Name]
+	if !ok {
+		return nil, fmt.Errorf("job %s is not registered", entry.Name)
+	}
+	return factory(entry.Args)
+}
+
+func (q *Queue) Complete(result error) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	i := q.find(Running)
+	if i < 0 {
+		return fmt.Errorf("nothing to complete")
+	}
+	now := q.now()
+	q.entries[i].Status = Succeeded
+	if result != nil {
+		q.entries[i].Status = Failed
+		q.entries[i].Error = result.Error()
+	}
+	q.entries[i].Finished = &now
+	q.progress = progressTracker{}
+	q.record(q.entries[i])
+	q.trim()
+	q.save()
+	return nil
+}
+
+func (q *Queue) record(entry Entry) {
+	record := config.JobRecord{
+		Name:     entry.Name,
+		Source:   entry.Source,
+		Started:  *entry.Finished,
+		Finished: *entry.Finished,
+		Result:   entry.Status,
+		Error:    entry.Error,
+	}
+	if entry.Started != nil {
+		record.Started = *entry.Started
+	}
+	err := q.history.Add(record)
+	if err != nil {
+		q.logger.Warn("cannot record job history", zap.Error(err))
+	}
+}
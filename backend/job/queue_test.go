@@ -0,0 +1,233 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/log"
+)
+
+type HistoryStub struct {
+	records []config.JobRecord
+}
+
+func (h *HistoryStub) Add(record config.JobRecord) error {
+	h.records = append(h.records, record)
+	return nil
+}
+
+func newTestQueue(t *testing.T) *Queue {
+	return NewQueue(filepath.Join(t.TempDir(), "jobs.json"), &HistoryStub{}, log.Default())
+}
+
+func TestStatusIdle(t *testing.T) {
+	queue := newTestQueue(t)
+	assert.Equal(t, "Idle", queue.Status().Status)
+}
+
+func TestOffer(t *testing.T) {
+	queue := newTestQueue(t)
+	err := queue.Offer("test", func() error { return nil })
+	assert.Nil(t, err)
+	assert.Equal(t, Status{Name: "test", Status: "Waiting"}, queue.Status())
+}
+
+func TestOffer_Queued(t *testing.T) {
+	queue := newTestQueue(t)
+	assert.Nil(t, queue.Offer("first", func() error { return nil }))
+	assert.Nil(t, queue.Offer("second", func() error { return nil }))
+	jobs := queue.List()
+	assert.Equal(t, 2, len(jobs))
+	assert.Equal(t, int64(1), jobs[0].Id)
+	assert.Equal(t, int64(2), jobs[1].Id)
+	assert.Equal(t, Queued, jobs[1].Status)
+}
+
+func TestTakeIdle(t *testing.T) {
+	queue := newTestQueue(t)
+	assert.Nil(t, queue.Take())
+}
+
+func TestTake_Fifo(t *testing.T) {
+	queue := newTestQueue(t)
+	var ran []string
+	_ = queue.Offer("first", func() error { ran = append(ran, "first"); return nil })
+	_ = queue.Offer("second", func() error { ran = append(ran, "second"); return nil })
+
+	job := queue.Take()
+	assert.NotNil(t, job)
+	assert.Nil(t, queue.Take())
+	assert.Equal(t, Status{Name: "first", Status: "Busy"}, queue.Status())
+	assert.Nil(t, job())
+	assert.Nil(t, queue.Complete(nil))
+
+	job = queue.Take()
+	assert.NotNil(t, job)
+	assert.Nil(t, job())
+	assert.Nil(t, queue.Complete(fmt.Errorf("error")))
+
+	assert.Equal(t, []string{"first", "second"}, ran)
+	jobs := queue.List()
+	assert.Equal(t, Succeeded, jobs[0].Status)
+	assert.NotNil(t, jobs[0].Started)
+	assert.NotNil(t, jobs[0].Finished)
+	assert.Equal(t, Failed, jobs[1].Status)
+	assert.Equal(t, "error", jobs[1].Error)
+	assert.Equal(t, "Idle", queue.Status().Status)
+}
+
+func TestCompleteIdle(t *testing.T) {
+	queue := newTestQueue(t)
+	assert.NotNil(t, queue.Complete(nil))
+}
+
+func TestCancel(t *testing.T) {
+	queue := newTestQueue(t)
+	_ = queue.Offer("first", func() error { return nil })
+	_ = queue.Offer("second", func() error { return nil })
+	queue.Take()
+
+	assert.NotNil(t, queue.Cancel(1))
+	assert.NotNil(t, queue.Cancel(3))
+	assert.Nil(t, queue.Cancel(2))
+	assert.Nil(t, queue.Complete(nil))
+	assert.Nil(t, queue.Take())
+	assert.Equal(t, Cancelled, queue.List()[1].Status)
+}
+
+func TestCancel_Recorded(t *testing.T) {
+	history := &HistoryStub{}
+	queue := NewQueue(filepath.Join(t.TempDir(), "jobs.json"), history, log.Default())
+	_ = queue.Offer("first", func() error { return nil })
+	_ = queue.Offer("second", func() error { return nil })
+	queue.Take()
+
+	assert.Nil(t, queue.Cancel(2))
+	assert.Equal(t, 1, len(history.records))
+	record := history.records[0]
+	assert.Equal(t, "second", record.Name)
+	assert.Equal(t, Cancelled, record.Result)
+	assert.Equal(t, record.Finished, record.Started)
+}
+
+func TestPersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jobs.json")
+	queue := NewQueue(file, &HistoryStub{}, log.Default())
+	_ = queue.Offer("done", func() error { return nil })
+	queue.Take()
+	_ = queue.Complete(nil)
+	_ = queue.Offer("running", func() error { return nil })
+	queue.Take()
+	_ = queue.Offer("queued", func() error { return nil })
+
+	history := &HistoryStub{}
+	queue = NewQueue(file, history, log.Default())
+	assert.Equal(t, 2, len(history.records))
+	jobs := queue.List()
+	assert.Equal(t, 3, len(jobs))
+	assert.Equal(t, Succeeded, jobs[0].Status)
+	assert.Equal(t, Failed, jobs[1].Status)
+	assert.Equal(t, "interrupted by restart", jobs[1].Error)
+	assert.Equal(t, Failed, jobs[2].Status)
+	assert.Nil(t, queue.Take())
+	_ = queue.Offer("next", func() error { return nil })
+	assert.Equal(t, int64(4), queue.List()[3].Id)
+}
+
+func TestPersistence_SubmittedJobIsRestored(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jobs.json")
+	var ran []string
+	factory := func(args json.RawMessage) (Job, error) {
+		var app string
+		err := json.Unmarshal(args, &app)
+		return func() error { ran = append(ran, app); return nil }, err
+	}
+	queue := NewQueue(file, &HistoryStub{}, log.Default())
+	queue.Register("backup.create", factory)
+	assert.Nil(t, queue.Submit(SourceUser, "backup.create", "files"))
+	assert.Error(t, queue.Submit(SourceUser, "unknown", "files"))
+
+	queue = NewQueue(file, &HistoryStub{}, log.Default())
+	assert.Equal(t, Status{Name: "backup.create", Status: "Waiting"}, queue.Status())
+	queue.Register("backup.create", factory)
+	job := queue.Take()
+	assert.NotNil(t, job)
+	assert.Nil(t, job())
+	assert.Equal(t, []string{"files"}, ran)
+	assert.Equal(t, SourceUser, queue.List()[0].Source)
+}
+
+func TestPersistence_UnregisteredJobFails(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jobs.json")
+	queue := NewQueue(file, &HistoryStub{}, log.Default())
+	queue.Register("installer.upgrade", func(_ json.RawMessage) (Job, error) { return func() error { return nil }, nil })
+	assert.Nil(t, queue.Submit(SourceCron, "installer.upgrade", "2.60"))
+
+	history := &HistoryStub{}
+	queue = NewQueue(file, history, log.Default())
+	assert.Nil(t, queue.Take())
+	jobs := queue.List()
+	assert.Equal(t, Failed, jobs[0].Status)
+	assert.Equal(t, "job installer.upgrade is not registered", jobs[0].Error)
+	assert.Equal(t, 1, len(history.records))
+}
+
+func TestTrim(t *testing.T) {
+	queue := newTestQueue(t)
+	for i := 0; i < KeepFinished+5; i++ {
+		_ = queue.Offer("test", func() error { return nil })
+		queue.Take()
+		_ = queue.Complete(nil)
+	}
+	jobs := queue.List()
+	assert.Equal(t, KeepFinished, len(jobs))
+	assert.Equal(t, int64(6), jobs[0].Id)
+}
+
+func TestProgress(t *testing.T) {
+	now := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	queue := newTestQueue(t)
+	queue.now = func() time.Time { return now }
+	err := queue.Offer("test", func() error { return nil })
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Status().Progress)
+	queue.Take()
+
+	queue.Phase("compress")
+	now = now.Add(10 * time.Second)
+	queue.Bytes(25, 100)
+
+	progress := queue.Status().Progress
+	assert.Equal(t, &Progress{Phase: "compress", Done: 25, Total: 100, Percent: 25, Eta: 30}, progress)
+	assert.Equal(t, progress, queue.List()[0].Progress)
+
+	err = queue.Complete(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Status().Progress)
+}
+
+func TestComplete_History(t *testing.T) {
+	history := &HistoryStub{}
+	queue := NewQueue(filepath.Join(t.TempDir(), "jobs.json"), history, log.Default())
+	_ = queue.Offer("backup.create", func() error { return nil })
+	_ = queue.OfferFrom(SourceCron, "installer.upgrade", func() error { return nil })
+
+	queue.Take()
+	assert.Nil(t, queue.Complete(nil))
+	queue.Take()
+	assert.Nil(t, queue.Complete(fmt.Errorf("store is not available")))
+
+	assert.Equal(t, 2, len(history.records))
+	assert.Equal(t, "backup.create", history.records[0].Name)
+	assert.Equal(t, SourceUser, history.records[0].Source)
+	assert.Equal(t, Succeeded, history.records[0].Result)
+	assert.Equal(t, SourceCron, history.records[1].Source)
+	assert.Equal(t, Failed, history.records[1].Result)
+	assert.Equal(t, "store is not available", history.records[1].Error)
+	assert.False(t, history.records[1].Started.IsZero())
+}
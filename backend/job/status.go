@@ -7,8 +7,9 @@ const (
 )
 
 type Status struct {
-	Name   string `json:"name"`
-	Status string `json:"status"`
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Progress *Progress `json:"progress,omitempty"`
 }
 
 func NewStatus(name string, status int) Status {
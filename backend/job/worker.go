@@ -7,7 +7,7 @@ import (
 
 type Master interface {
 	Take() Job
-	Complete() error
+	Complete(result error) error
 }
 
 type Worker struct {
@@ -32,11 +32,11 @@ func (w *Worker) Do() bool {
 	if job == nil {
 		return false
 	}
-	err := job()
-	if err != nil {
-		w.logger.Error("error in the task", zap.Error(err))
+	result := job()
+	if result != nil {
+		w.logger.Error("error in the task", zap.Error(result))
 	}
-	err = w.master.Complete()
+	err := w.master.Complete(result)
 	if err != nil {
 		w.logger.Error("cannot complete task", zap.Error(err))
 	}
@@ -1,6 +1,7 @@
 package job
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/syncloud/platform/log"
 	"testing"
@@ -10,6 +11,7 @@ type MasterStub struct {
 	job       Job
 	taken     int
 	completed int
+	result    error
 }
 
 func (m *MasterStub) Take() Job {
@@ -17,8 +19,9 @@ func (m *MasterStub) Take() Job {
 	return m.job
 }
 
-func (m *MasterStub) Complete() error {
+func (m *MasterStub) Complete(result error) error {
 	m.completed++
+	m.result = result
 	return nil
 }
 
@@ -35,6 +38,7 @@ func TestJob(t *testing.T) {
 
 	assert.True(t, ran)
 	assert.Equal(t, 1, master.completed)
+	assert.Nil(t, master.result)
 
 }
 
@@ -47,3 +51,14 @@ func TestNoJob(t *testing.T) {
 
 	assert.Equal(t, 0, master.completed)
 }
+
+func TestJobFailed(t *testing.T) {
+	master := &MasterStub{}
+	worker := NewWorker(master, log.Default())
+
+	master.job = func() error { return fmt.Errorf("failed") }
+	worker.Do()
+
+	assert.Equal(t, 1, master.completed)
+	assert.EqualError(t, master.result, "failed")
+}
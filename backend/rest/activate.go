@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/syncloud/platform/activation"
+	"github.com/syncloud/platform/backup"
 	"github.com/syncloud/platform/rest/model"
 	"github.com/syncloud/platform/timezone"
 )
@@ -13,13 +14,15 @@ import (
 type Activate struct {
 	managed  activation.ManagedActivation
 	custom   activation.CustomActivation
+	restore  activation.RestoreActivation
 	timezone *timezone.Applier
 }
 
-func NewActivateBackend(managed activation.ManagedActivation, custom activation.CustomActivation, timezone *timezone.Applier) *Activate {
+func NewActivateBackend(managed activation.ManagedActivation, custom activation.CustomActivation, restore activation.RestoreActivation, timezone *timezone.Applier) *Activate {
 	return &Activate{
 		managed:  managed,
 		custom:   custom,
+		restore:  restore,
 		timezone: timezone,
 	}
 }
@@ -56,6 +59,18 @@ func (a *Activate) Managed(req *http.Request) (interface{}, error) {
 	return "ok", a.managed.Activate(request.RedirectEmail, request.RedirectPassword, request.Domain, request.DeviceUsername, request.DevicePassword)
 }
 
+func (a *Activate) Restore(req *http.Request) (interface{}, error) {
+	file, fields, err := uploadedForm(req)
+	if err != nil {
+		return nil, err
+	}
+	keys := backup.Encryption{Passphrase: fields.Get("passphrase")}
+	if identity := fields.Get("identity"); identity != "" {
+		keys.Identities = []string{identity}
+	}
+	return "ok", a.restore.Activate(file.FileName(), file, keys)
+}
+
 func (a *Activate) applyOptionalTimezone(tz string) error {
 	if tz == "" {
 		return nil
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/syncloud/platform/activation"
+	"github.com/syncloud/platform/backup"
 	"github.com/syncloud/platform/timezone"
 )
 
@@ -50,6 +53,20 @@ func (a *ManagedActivationStub) Activate(_ string, _ string, _ string, _ string,
 	return nil
 }
 
+type RestoreActivationStub struct {
+	file    string
+	content string
+	keys    backup.Encryption
+}
+
+func (a *RestoreActivationStub) Activate(fileName string, reader io.Reader, keys backup.Encryption) error {
+	content, err := io.ReadAll(reader)
+	a.file = fileName
+	a.keys = keys
+	a.content = string(content)
+	return err
+}
+
 type CustomActivationStub struct{}
 
 func (a CustomActivationStub) Activate(_ string, _ string, _ string) error {
@@ -57,7 +74,7 @@ func (a CustomActivationStub) Activate(_ string, _ string, _ string) error {
 }
 
 func TestActivate_CustomLoginShort(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.CustomActivateRequest{Domain: "example.com", DeviceUsername: "a", DevicePassword: "password123"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -68,7 +85,7 @@ func TestActivate_CustomLoginShort(t *testing.T) {
 }
 
 func TestActivate_CustomPasswordShort(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.CustomActivateRequest{Domain: "example.com", DeviceUsername: "username", DevicePassword: "pass"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -79,7 +96,7 @@ func TestActivate_CustomPasswordShort(t *testing.T) {
 }
 
 func TestActivate_CustomGood(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.CustomActivateRequest{Domain: "example.com", DeviceUsername: "username", DevicePassword: "password"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -90,7 +107,7 @@ func TestActivate_CustomGood(t *testing.T) {
 }
 
 func TestActivate_ManagedLoginShort(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.ManagedActivateRequest{Domain: "example.com", DeviceUsername: "a", DevicePassword: "password"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -101,7 +118,7 @@ func TestActivate_ManagedLoginShort(t *testing.T) {
 }
 
 func TestActivate_ManagedPasswordShort(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.ManagedActivateRequest{Domain: "example.com", DeviceUsername: "username", DevicePassword: "pass"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -112,7 +129,7 @@ func TestActivate_ManagedPasswordShort(t *testing.T) {
 }
 
 func TestActivate_ManagedGood(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.ManagedActivateRequest{Domain: "example.com", DeviceUsername: "username", DevicePassword: "password"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -124,7 +141,7 @@ func TestActivate_ManagedGood(t *testing.T) {
 
 func TestActivate_ManagedRedirectError(t *testing.T) {
 	managed := &ManagedActivationStub{error: true}
-	activate := NewActivateBackend(managed, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(managed, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.ManagedActivateRequest{Domain: "example.com", DeviceUsername: "username", DevicePassword: "password"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -139,7 +156,7 @@ func TestActivate_Managed_AppliesOptionalTimezone(t *testing.T) {
 	store := &fakeTimezoneStore{}
 	applier := timezone.NewApplier(exec, store)
 
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, applier)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, applier)
 	request := &activation.ManagedActivateRequest{
 		Domain: "example.com", DeviceUsername: "username", DevicePassword: "password",
 		Timezone: tz,
@@ -160,7 +177,7 @@ func TestActivate_Managed_RejectsInvalidTimezone(t *testing.T) {
 	exec := &fakeExecutor{}
 	applier := timezone.NewApplier(exec, &fakeTimezoneStore{})
 
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, applier)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, applier)
 	request := &activation.ManagedActivateRequest{
 		Domain: "example.com", DeviceUsername: "username", DevicePassword: "password",
 		Timezone: "Not/A/Real/Zone",
@@ -176,7 +193,7 @@ func TestActivate_Managed_RejectsInvalidTimezone(t *testing.T) {
 }
 
 func TestActivate_Managed_LowerCase(t *testing.T) {
-	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, nil)
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
 	request := &activation.ManagedActivateRequest{Domain: "example.com", DeviceUsername: "Boris@example.com", DevicePassword: "password"}
 	body, err := json.Marshal(request)
 	assert.Nil(t, err)
@@ -185,3 +202,51 @@ func TestActivate_Managed_LowerCase(t *testing.T) {
 	assert.Nil(t, message)
 	assert.NotNil(t, err)
 }
+
+func TestActivate_Restore(t *testing.T) {
+	restore := &RestoreActivationStub{}
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, restore, nil)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "platform-2001-0203-040506.tar.gz")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("backup"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	req, _ := http.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	_, err = activate.Restore(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "platform-2001-0203-040506.tar.gz", restore.file)
+	assert.Equal(t, "backup", restore.content)
+	assert.Equal(t, backup.Encryption{}, restore.keys)
+}
+
+func TestActivate_Restore_Keys(t *testing.T) {
+	restore := &RestoreActivationStub{}
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, restore, nil)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	assert.NoError(t, writer.WriteField("passphrase", "secret"))
+	assert.NoError(t, writer.WriteField("identity", "AGE-SECRET-KEY-1"))
+	part, err := writer.CreateFormFile("file", "platform-2001-0203-040506.tar.gz.age")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("backup"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	req, _ := http.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	_, err = activate.Restore(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "backup", restore.content)
+	assert.Equal(t, "secret", restore.keys.Passphrase)
+	assert.Equal(t, []string{"AGE-SECRET-KEY-1"}, restore.keys.Identities)
+}
+
+func TestActivate_Restore_FileMissing(t *testing.T) {
+	activate := NewActivateBackend(&ManagedActivationStub{}, &CustomActivationStub{}, &RestoreActivationStub{}, nil)
+	req, _ := http.NewRequest("POST", "/", bytes.NewBufferString("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	_, err := activate.Restore(req)
+	assert.Error(t, err)
+}
@@ -11,9 +11,11 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/syncloud/platform/access"
+	"github.com/syncloud/platform/alert"
 	"github.com/syncloud/platform/auth"
 	"github.com/syncloud/platform/backup"
 	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/cron"
 	"github.com/syncloud/platform/event"
 	"github.com/syncloud/platform/health"
 	"github.com/syncloud/platform/identification"
@@ -23,6 +25,7 @@ import (
 	"github.com/syncloud/platform/rest/model"
 	"github.com/syncloud/platform/session"
 	"github.com/syncloud/platform/snap"
+	"github.com/syncloud/platform/stability"
 	"github.com/syncloud/platform/storage"
 	"github.com/syncloud/platform/support"
 	"github.com/syncloud/platform/system"
@@ -34,7 +37,9 @@ import (
 )
 
 type Backend struct {
-	JobMaster       *job.SingleJobMaster
+	JobMaster       *job.Queue
+	jobHistory      *config.JobHistory
+	schedules       *cron.Schedules
 	backup          *backup.Backup
 	eventTrigger    *event.Trigger
 	worker          *job.Worker
@@ -67,13 +72,14 @@ type Backend struct {
 	totp            *auth.TOTP
 	timezone        *timezone.Applier
 	health          *health.Health
+	alerts          *alert.Monitor
 	network         string
 	address         string
 	logger          *zap.Logger
 }
 
 func NewBackend(
-	master *job.SingleJobMaster, backup *backup.Backup, eventTrigger *event.Trigger, worker *job.Worker,
+	master *job.Queue, backup *backup.Backup, eventTrigger *event.Trigger, worker *job.Worker,
 	redirect *redirect.Service, snapdUpgrader *snap.Snapd, storageService *storage.Storage,
 	identification *identification.Parser, activate *Activate, userConfig *config.UserConfig,
 	redirectConfig *config.Redirect,
@@ -85,9 +91,12 @@ func NewBackend(
 	oidcService *auth.OIDCService, authelia *auth.Authelia, totp *auth.TOTP,
 	timezone *timezone.Applier,
 	healthService *health.Health,
+	jobHistory *config.JobHistory,
+	schedules *cron.Schedules,
+	alerts *alert.Monitor,
 	logger *zap.Logger) *Backend {
 
-	return &Backend{
+	b := &Backend{
 		JobMaster:       master,
 		backup:          backup,
 		eventTrigger:    eventTrigger,
@@ -120,11 +129,46 @@ func NewBackend(
 		totp:            totp,
 		timezone:        timezone,
 		health:          healthService,
+		jobHistory:      jobHistory,
+		schedules:       schedules,
+		alerts:          alerts,
 		network:         network,
 		address:         address,
 		changesClient:   changesClient,
 		logger:          logger,
 	}
+	b.registerJobs()
+	return b
+}
+
+// registerJobs lets the queue create jobs from their saved arguments, so queued jobs survive a restart.
+func (b *Backend) registerJobs() {
+	b.JobMaster.Register("backup.create", stringJob(b.backup.Create))
+	b.JobMaster.Register("backup.restore", stringJob(b.backup.Restore))
+	b.JobMaster.Register("installer.upgrade", stringJob(b.snapdUpgrader.Upgrade))
+	b.JobMaster.Register("storage.boot.extend", func(_ json.RawMessage) (job.Job, error) {
+		return b.storage.BootExtend, nil
+	})
+	b.JobMaster.Register("storage.activate.partition", stringJob(b.disks.ActivatePartition))
+	b.JobMaster.Register("storage.activate.disks", func(args json.RawMessage) (job.Job, error) {
+		var request model.StorageActivateDisksRequest
+		err := json.Unmarshal(args, &request)
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return b.disks.ActivateDisks(request.Devices, request.Format) }, nil
+	})
+}
+
+func stringJob(run func(string) error) job.Factory {
+	return func(args json.RawMessage) (job.Job, error) {
+		var arg string
+		err := json.Unmarshal(args, &arg)
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return run(arg) }, nil
+	}
 }
 
 func (b *Backend) Start() error {
@@ -153,6 +197,7 @@ func (b *Backend) Start() error {
 	r.PathPrefix("/rest/redirect/domain/availability").Handler(http.StripPrefix("/rest/redirect", NewFailIfActivatedHandler(b.userConfig, proxyRedirect)))
 	r.HandleFunc("/rest/activate/managed", b.mw.FailIfActivated(b.mw.Handle(b.activate.Managed))).Methods("POST")
 	r.HandleFunc("/rest/activate/custom", b.mw.FailIfActivated(b.mw.Handle(b.activate.Custom))).Methods("POST")
+	r.HandleFunc("/rest/activate/restore", b.mw.FailIfActivated(b.mw.Handle(b.activate.Restore))).Methods("POST")
 
 	r.HandleFunc("/rest/user", b.mw.FailIfNotActivated(b.mw.SecuredHandle(b.User))).Methods("GET")
 	r.HandleFunc("/rest/users", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.Users))).Methods("GET")
@@ -174,11 +219,49 @@ func (b *Backend) Start() error {
 	r.HandleFunc("/rest/timezone", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.SetTimezone))).Methods("POST")
 	r.HandleFunc("/rest/time", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.GetTime))).Methods("GET")
 	r.HandleFunc("/rest/health/events", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthEvents))).Methods("GET")
+	r.HandleFunc("/rest/health/events/counts", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthEventCounts))).Methods("GET")
+	r.HandleFunc("/rest/health/events/export", b.mw.FailIfNotActivated(b.mw.AdminSecured(b.HealthEventsExport))).Methods("GET")
+	r.HandleFunc("/rest/health/events/stream", b.mw.FailIfNotActivated(b.mw.AdminSecured(b.HealthEventsStream))).Methods("GET")
 	r.HandleFunc("/rest/health/metrics", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthMetrics))).Methods("GET")
+	r.HandleFunc("/rest/health/oom/policy", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthOOMPolicy))).Methods("GET")
+	r.HandleFunc("/rest/health/oom/policy", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthOOMPolicySet))).Methods("POST")
+	r.HandleFunc("/rest/health/apps", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthApps))).Methods("GET")
+	r.HandleFunc("/rest/health/metrics/history", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthMetricsHistory))).Methods("GET")
+	r.HandleFunc("/rest/health/metrics/listen", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthMetricsListen))).Methods("GET")
+	r.HandleFunc("/rest/health/metrics/listen", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.HealthMetricsListenSet))).Methods("POST")
+	r.HandleFunc("/rest/health/metrics/prometheus", b.mw.FailIfNotActivated(b.mw.AdminSecured(b.HealthMetricsPrometheus))).Methods("GET")
+	r.HandleFunc("/rest/alerts", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.Alerts))).Methods("GET")
+	r.HandleFunc("/rest/alert/rules", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertRules))).Methods("GET")
+	r.HandleFunc("/rest/alert/rules/add", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertRuleAdd))).Methods("POST")
+	r.HandleFunc("/rest/alert/rules/remove", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertRuleRemove))).Methods("POST")
+	r.HandleFunc("/rest/alert/channels", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertChannels))).Methods("GET")
+	r.HandleFunc("/rest/alert/channels/add", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertChannelAdd))).Methods("POST")
+	r.HandleFunc("/rest/alert/channels/remove", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertChannelRemove))).Methods("POST")
+	r.HandleFunc("/rest/alert/channels/test", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.AlertChannelTest))).Methods("POST")
 	r.HandleFunc("/rest/job/status", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.JobStatus))).Methods("GET")
+	r.HandleFunc("/rest/jobs", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.Jobs))).Methods("GET")
+	r.HandleFunc("/rest/jobs/history", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.JobHistory))).Methods("GET")
+	r.HandleFunc("/rest/cron/schedules", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.CronSchedules))).Methods("GET")
+	r.HandleFunc("/rest/cron/schedule", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.CronScheduleSet))).Methods("POST")
+	r.HandleFunc("/rest/jobs/cancel", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.JobCancel))).Methods("POST")
 	r.HandleFunc("/rest/backup/list", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupList))).Methods("GET")
 	r.HandleFunc("/rest/backup/auto", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.GetBackupAuto))).Methods("GET")
 	r.HandleFunc("/rest/backup/auto", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.SetBackupAuto))).Methods("POST")
+	r.HandleFunc("/rest/backup/auto/app", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.SetBackupAppAuto))).Methods("POST")
+	r.HandleFunc("/rest/backup/auto/app/remove", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.RemoveBackupAppAuto))).Methods("POST")
+	r.HandleFunc("/rest/backup/mode", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.GetBackupMode))).Methods("GET")
+	r.HandleFunc("/rest/backup/mode", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.SetBackupMode))).Methods("POST")
+	r.HandleFunc("/rest/backup/encryption", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.GetBackupEncryption))).Methods("GET")
+	r.HandleFunc("/rest/backup/encryption", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.SetBackupEncryption))).Methods("POST")
+	r.HandleFunc("/rest/backup/retention", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.GetBackupRetention))).Methods("GET")
+	r.HandleFunc("/rest/backup/retention", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.SetBackupRetention))).Methods("POST")
+	r.HandleFunc("/rest/backup/retention/remove", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.RemoveBackupRetention))).Methods("POST")
+	r.HandleFunc("/rest/backup/retention/preview", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.PreviewBackupRetention))).Methods("GET")
+	r.HandleFunc("/rest/backup/targets", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupTargets))).Methods("GET")
+	r.HandleFunc("/rest/backup/targets/add", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupTargetAdd))).Methods("POST")
+	r.HandleFunc("/rest/backup/targets/remove", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupTargetRemove))).Methods("POST")
+	r.HandleFunc("/rest/backup/upload", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupUpload))).Methods("POST")
+	r.HandleFunc("/rest/backup/verify", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupVerify))).Methods("POST")
 	r.HandleFunc("/rest/backup/create", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupCreate))).Methods("POST")
 	r.HandleFunc("/rest/backup/restore", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupRestore))).Methods("POST")
 	r.HandleFunc("/rest/backup/remove", b.mw.FailIfNotActivated(b.mw.AdminSecuredHandle(b.BackupRemove))).Methods("POST")
@@ -248,6 +331,131 @@ func (b *Backend) SetBackupAuto(req *http.Request) (interface{}, error) {
 	return "OK", nil
 }
 
+func (b *Backend) SetBackupAppAuto(req *http.Request) (interface{}, error) {
+	var request backup.AppAuto
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	err = b.backup.SetAppAuto(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) RemoveBackupAppAuto(req *http.Request) (interface{}, error) {
+	var request model.BackupAppAutoRemoveRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	b.backup.RemoveAppAuto(request.App)
+	return "OK", nil
+}
+
+func (b *Backend) GetBackupMode(_ *http.Request) (interface{}, error) {
+	return b.backup.Mode(), nil
+}
+
+func (b *Backend) SetBackupMode(req *http.Request) (interface{}, error) {
+	var request backup.Mode
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	err = b.backup.SetMode(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) GetBackupEncryption(_ *http.Request) (interface{}, error) {
+	return b.backup.Encryption(), nil
+}
+
+func (b *Backend) SetBackupEncryption(req *http.Request) (interface{}, error) {
+	var request backup.Encryption
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	err = b.backup.SetEncryption(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) GetBackupRetention(_ *http.Request) (interface{}, error) {
+	return b.backup.Retention(), nil
+}
+
+func (b *Backend) SetBackupRetention(req *http.Request) (interface{}, error) {
+	var request backup.AppRetention
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	err = b.backup.SetRetention(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) RemoveBackupRetention(req *http.Request) (interface{}, error) {
+	var request model.BackupRetentionRemoveRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	b.backup.RemoveRetention(request.App)
+	return "OK", nil
+}
+
+func (b *Backend) PreviewBackupRetention(req *http.Request) (interface{}, error) {
+	return b.backup.PlanRetention(req.URL.Query().Get("app"))
+}
+
+func (b *Backend) BackupTargets(_ *http.Request) (interface{}, error) {
+	return b.backup.Targets()
+}
+
+func (b *Backend) BackupTargetAdd(req *http.Request) (interface{}, error) {
+	var request config.BackupTarget
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("bad request")
+	}
+	err = b.backup.AddTarget(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) BackupTargetRemove(req *http.Request) (interface{}, error) {
+	var request model.BackupTargetRemoveRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil || request.Name == "" {
+		return nil, errors.New("name is missing")
+	}
+	err = b.backup.RemoveTarget(request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return "removed", nil
+}
+
 func (b *Backend) BackupRemove(req *http.Request) (interface{}, error) {
 	var request model.BackupRemoveRequest
 	err := json.NewDecoder(req.Body).Decode(&request)
@@ -255,13 +463,31 @@ func (b *Backend) BackupRemove(req *http.Request) (interface{}, error) {
 		fmt.Printf("parse error: %v\n", err.Error())
 		return nil, errors.New("file is missing")
 	}
-	err = b.backup.Remove(request.File)
+	err = b.backup.Remove(request.File, request.Remote)
 	if err != nil {
 		return nil, err
 	}
 	return "removed", nil
 }
 
+func (b *Backend) BackupVerify(req *http.Request) (interface{}, error) {
+	var request model.BackupVerifyRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("file is missing")
+	}
+	return b.backup.Verify(request.File)
+}
+
+func (b *Backend) BackupUpload(req *http.Request) (interface{}, error) {
+	file, err := uploadedFile(req)
+	if err != nil {
+		return nil, err
+	}
+	return b.backup.Upload(file.FileName(), file)
+}
+
 func (b *Backend) BackupCreate(req *http.Request) (interface{}, error) {
 	var request model.BackupCreateRequest
 	err := json.NewDecoder(req.Body).Decode(&request)
@@ -269,7 +495,11 @@ func (b *Backend) BackupCreate(req *http.Request) (interface{}, error) {
 		fmt.Printf("parse error: %v\n", err.Error())
 		return nil, errors.New("app is missing")
 	}
-	err = b.JobMaster.Offer("backup.create", func() error { return b.backup.Create(request.App) })
+	err = b.backup.ValidateMode(request.App)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	err = b.JobMaster.Submit(job.SourceUser, "backup.create", request.App)
 	return "submitted", err
 }
 
@@ -280,7 +510,7 @@ func (b *Backend) BackupRestore(req *http.Request) (interface{}, error) {
 		fmt.Printf("parse error: %v\n", err.Error())
 		return nil, errors.New("file is missing")
 	}
-	err = b.JobMaster.Offer("backup.restore", func() error { return b.backup.Restore(request.File) })
+	err = b.JobMaster.Submit(job.SourceUser, "backup.restore", request.File)
 	return "submitted", err
 }
 
@@ -290,7 +520,7 @@ func (b *Backend) InstallerUpgrade(_ *http.Request) (interface{}, error) {
 		return nil, err
 	}
 	version := info.StoreVersion
-	err = b.JobMaster.Offer("installer.upgrade", func() error { return b.snapdUpgrader.Upgrade(version) })
+	err = b.JobMaster.Submit(job.SourceUser, "installer.upgrade", version)
 	return "submitted", err
 }
 
@@ -298,6 +528,54 @@ func (b *Backend) JobStatus(_ *http.Request) (interface{}, error) {
 	return b.JobMaster.Status(), nil
 }
 
+func (b *Backend) Jobs(_ *http.Request) (interface{}, error) {
+	return b.JobMaster.List(), nil
+}
+
+func (b *Backend) JobHistory(req *http.Request) (interface{}, error) {
+	limit := config.KeepJobHistory
+	if value := req.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", value)
+		}
+		limit = parsed
+	}
+	return b.jobHistory.List(limit)
+}
+
+func (b *Backend) CronSchedules(_ *http.Request) (interface{}, error) {
+	return b.schedules.List(), nil
+}
+
+func (b *Backend) CronScheduleSet(req *http.Request) (interface{}, error) {
+	var request model.CronScheduleRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("job is missing")
+	}
+	err = b.schedules.Set(request.Job, request.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return b.schedules.List(), nil
+}
+
+func (b *Backend) JobCancel(req *http.Request) (interface{}, error) {
+	var request model.JobCancelRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		fmt.Printf("parse error: %v\n", err.Error())
+		return nil, errors.New("id is missing")
+	}
+	err = b.JobMaster.Cancel(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	return "cancelled", nil
+}
+
 func (b *Backend) EventTrigger(req *http.Request) (interface{}, error) {
 	var request model.EventTriggerRequest
 	err := json.NewDecoder(req.Body).Decode(&request)
@@ -554,7 +832,7 @@ func (b *Backend) Deactivate(_ *http.Request) (interface{}, error) {
 }
 
 func (b *Backend) StorageBootExtend(_ *http.Request) (interface{}, error) {
-	_ = b.JobMaster.Offer("storage.boot.extend", func() error { return b.storage.BootExtend() })
+	_ = b.JobMaster.Submit(job.SourceUser, "storage.boot.extend", nil)
 	return "submitted", nil
 }
 
@@ -585,7 +863,7 @@ func (b *Backend) StorageActivatePartition(req *http.Request) (interface{}, erro
 		}
 	}
 
-	return "OK", b.JobMaster.Offer("storage.activate.partition", func() error { return b.disks.ActivatePartition(request.Device) })
+	return "OK", b.JobMaster.Submit(job.SourceUser, "storage.activate.partition", request.Device)
 
 }
 
@@ -597,7 +875,7 @@ func (b *Backend) StorageActivateDisks(req *http.Request) (interface{}, error) {
 		return nil, err
 	}
 
-	return "OK", b.JobMaster.Offer("storage.activate.disks", func() error { return b.disks.ActivateDisks(request.Devices, request.Format) })
+	return "OK", b.JobMaster.Submit(job.SourceUser, "storage.activate.disks", request)
 }
 
 func (b *Backend) StorageSpace(_ *http.Request) (interface{}, error) {
@@ -752,20 +1030,277 @@ func (b *Backend) GroupMemberRemove(req *http.Request) (interface{}, error) {
 	return "ok", b.groupManager.RemoveGroupMember(request.Group, request.Username)
 }
 
+// eventFilter takes comma separated kinds, an app and RFC3339 from and to.
+func eventFilter(req *http.Request) (stability.EventFilter, error) {
+	query := req.URL.Query()
+	filter := stability.EventFilter{App: query.Get("app")}
+	if v := query.Get("kind"); v != "" {
+		for _, kind := range strings.Split(v, ",") {
+			filter.Kinds = append(filter.Kinds, stability.EventKind(kind))
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("from: %w", err)
+		}
+		filter.From = parsed
+	}
+	if v := query.Get("to"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("to: %w", err)
+		}
+		filter.To = parsed
+	}
+	return filter, nil
+}
+
+// HealthEvents returns a page of the filtered events, newest first, the next page is requested with the returned cursor.
 func (b *Backend) HealthEvents(req *http.Request) (interface{}, error) {
+	filter, err := eventFilter(req)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
 	limit := 100
 	if v := req.URL.Query().Get("limit"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
 			limit = n
 		}
 	}
-	return b.health.Events(limit)
+	page, err := b.health.Events(filter, req.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return page, nil
+}
+
+// HealthEventCounts counts the filtered events per kind and app, the last day by default.
+func (b *Backend) HealthEventCounts(req *http.Request) (interface{}, error) {
+	filter, err := eventFilter(req)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	if filter.From.IsZero() {
+		filter.From = time.Now().Add(-24 * time.Hour)
+	}
+	return b.health.EventCounts(filter)
+}
+
+func (b *Backend) HealthEventsExport(w http.ResponseWriter, req *http.Request) {
+	filter, err := eventFilter(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=stability-events.jsonl")
+	err = b.health.ExportEvents(w, filter)
+	if err != nil {
+		b.logger.Error("events export", zap.Error(err))
+	}
+}
+
+// HealthEventsStream sends the new filtered events as server-sent events until the client disconnects.
+func (b *Backend) HealthEventsStream(w http.ResponseWriter, req *http.Request) {
+	filter, err := eventFilter(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	err = b.health.FollowEvents(req.Context(), filter, func(event stability.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		b.logger.Info("events stream closed", zap.Error(err))
+	}
 }
 
 func (b *Backend) HealthMetrics(_ *http.Request) (interface{}, error) {
 	return b.health.Metrics()
 }
 
+func (b *Backend) HealthOOMPolicy(_ *http.Request) (interface{}, error) {
+	return b.health.OOMPolicy()
+}
+
+func (b *Backend) HealthOOMPolicySet(req *http.Request) (interface{}, error) {
+	policy := stability.DefaultPolicy()
+	err := json.NewDecoder(req.Body).Decode(&policy)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	err = b.health.SetOOMPolicy(policy)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) HealthMetricsListen(_ *http.Request) (interface{}, error) {
+	return model.MetricsListenRequest{Address: b.health.MetricsListen()}, nil
+}
+
+// HealthMetricsListenSet takes a loopback host:port for the unauthenticated metrics listener, an empty address disables it.
+func (b *Backend) HealthMetricsListenSet(req *http.Request) (interface{}, error) {
+	var request model.MetricsListenRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	err = b.health.SetMetricsListen(request.Address)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) HealthApps(_ *http.Request) (interface{}, error) {
+	return b.health.Apps()
+}
+
+// HealthMetricsHistory takes RFC3339 from and to, the last hour by default, and comma separated metric names,
+// with an app it returns the metrics of the app instead of the host.
+func (b *Backend) HealthMetricsHistory(req *http.Request) (interface{}, error) {
+	query := req.URL.Query()
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, model.BadRequest(fmt.Errorf("to: %w", err))
+		}
+		to = parsed
+	}
+	from := to.Add(-time.Hour)
+	if v := query.Get("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, model.BadRequest(fmt.Errorf("from: %w", err))
+		}
+		from = parsed
+	}
+	var names []string
+	if v := query.Get("metrics"); v != "" {
+		names = strings.Split(v, ",")
+	}
+	var series *health.Series
+	var err error
+	if app := query.Get("app"); app != "" {
+		series, err = b.health.AppHistory(app, from, to, names)
+	} else {
+		series, err = b.health.History(from, to, names)
+	}
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return series, nil
+}
+
+func (b *Backend) Alerts(_ *http.Request) (interface{}, error) {
+	return b.alerts.Firing(), nil
+}
+
+func (b *Backend) AlertRules(_ *http.Request) (interface{}, error) {
+	return b.alerts.Rules()
+}
+
+func (b *Backend) AlertRuleAdd(req *http.Request) (interface{}, error) {
+	var request config.AlertRule
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	err = b.alerts.AddRule(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) AlertRuleRemove(req *http.Request) (interface{}, error) {
+	var request model.AlertRuleRemoveRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil || request.Name == "" {
+		return nil, errors.New("name is missing")
+	}
+	err = b.alerts.RemoveRule(request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return "removed", nil
+}
+
+func (b *Backend) AlertChannels(_ *http.Request) (interface{}, error) {
+	return b.alerts.Channels()
+}
+
+func (b *Backend) AlertChannelAdd(req *http.Request) (interface{}, error) {
+	var request config.AlertChannel
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	err = b.alerts.AddChannel(request)
+	if err != nil {
+		return nil, model.BadRequest(err)
+	}
+	return "OK", nil
+}
+
+func (b *Backend) AlertChannelRemove(req *http.Request) (interface{}, error) {
+	var request model.AlertChannelRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil || request.Name == "" {
+		return nil, errors.New("name is missing")
+	}
+	err = b.alerts.RemoveChannel(request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return "removed", nil
+}
+
+func (b *Backend) AlertChannelTest(req *http.Request) (interface{}, error) {
+	var request model.AlertChannelRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil || request.Name == "" {
+		return nil, errors.New("name is missing")
+	}
+	err = b.alerts.TestChannel(request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return "sent", nil
+}
+
+func (b *Backend) HealthMetricsPrometheus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", health.ContentType)
+	err := b.health.WriteOpenMetrics(w)
+	if err != nil {
+		b.logger.Error("metrics", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (b *Backend) GetTwoFactorSettings(_ *http.Request) (interface{}, error) {
 	return map[string]interface{}{
 		"enabled":      b.userConfig.IsTwoFactorEnabled(),
@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/backup"
+	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/rest/model"
+)
+
+func newTestBackupBackend(t *testing.T) (*Backend, *config.UserConfig, *config.BackupTargets) {
+	db := config.NewDb(path.Join(t.TempDir(), "db"), log.Default())
+	assert.NoError(t, config.NewMigrator(db).Migrate())
+	userConfig := config.NewUserConfig(db, log.Default())
+	targets := config.NewBackupTargets(db)
+	service := backup.New(t.TempDir(), t.TempDir(), nil, nil, nil, nil, userConfig, targets, nil, nil, nil, log.Default())
+	return &Backend{backup: service, logger: log.Default()}, userConfig, targets
+}
+
+func post(t *testing.T, body string) *http.Request {
+	req, err := http.NewRequest("POST", "/", strings.NewReader(body))
+	assert.NoError(t, err)
+	return req
+}
+
+func TestSetBackupMode_Incremental(t *testing.T) {
+	backend, userConfig, _ := newTestBackupBackend(t)
+
+	_, err := backend.SetBackupMode(post(t, `{"incremental": true}`))
+	assert.NoError(t, err)
+	assert.True(t, userConfig.IsBackupIncremental())
+	mode, err := backend.GetBackupMode(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, backup.Mode{Incremental: true}, mode)
+}
+
+func TestSetBackupMode_Streaming(t *testing.T) {
+	backend, userConfig, _ := newTestBackupBackend(t)
+	userConfig.SetBackupPassphrase("secret")
+
+	_, err := backend.SetBackupMode(post(t, `{"streaming": true}`))
+	assert.NoError(t, err)
+	assert.True(t, userConfig.IsBackupStreaming())
+	assert.False(t, userConfig.IsBackupIncremental())
+	mode, err := backend.GetBackupMode(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, backup.Mode{Streaming: true}, mode)
+
+	_, err = backend.SetBackupMode(post(t, `{"streaming": false}`))
+	assert.NoError(t, err)
+	assert.False(t, userConfig.IsBackupStreaming())
+}
+
+func TestSetBackupMode_IncrementalWithEncryption_BadRequest(t *testing.T) {
+	backend, userConfig, _ := newTestBackupBackend(t)
+	userConfig.SetBackupPassphrase("secret")
+
+	_, err := backend.SetBackupMode(post(t, `{"incremental": true}`))
+	var serviceError *model.ServiceError
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, http.StatusBadRequest, serviceError.StatusCode)
+	assert.ErrorContains(t, err, "cannot be encrypted")
+	assert.False(t, userConfig.IsBackupIncremental())
+}
+
+func TestSetBackupMode_IncrementalWithTarget_BadRequest(t *testing.T) {
+	backend, userConfig, targets := newTestBackupBackend(t)
+	assert.NoError(t, targets.Add(config.BackupTarget{Name: "usb", Type: backup.TargetLocal, Path: "/mnt/usb"}))
+
+	_, err := backend.SetBackupMode(post(t, `{"incremental": true}`))
+	assert.ErrorContains(t, err, "remote targets")
+	assert.False(t, userConfig.IsBackupIncremental())
+}
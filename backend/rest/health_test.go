@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syncloud/platform/config"
+	"github.com/syncloud/platform/health"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/rest/model"
+)
+
+func TestHealthMetricsListenSet(t *testing.T) {
+	db := config.NewDb(path.Join(t.TempDir(), "db"), log.Default())
+	assert.NoError(t, config.NewMigrator(db).Migrate())
+	userConfig := config.NewUserConfig(db, log.Default())
+	server := health.NewMetricsServer(nil, userConfig, log.Default())
+	backend := &Backend{health: health.NewHealth(nil, nil, nil, nil, nil, nil, server), logger: log.Default()}
+
+	_, err := backend.HealthMetricsListenSet(post(t, `{"address": "192.168.1.10:9100"}`))
+	var serviceError *model.ServiceError
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, http.StatusBadRequest, serviceError.StatusCode)
+	assert.Equal(t, "", userConfig.GetMetricsListen())
+
+	_, err = backend.HealthMetricsListenSet(post(t, `{"address": "127.0.0.1:9100"}`))
+	assert.NoError(t, err)
+	listen, err := backend.HealthMetricsListen(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.MetricsListenRequest{Address: "127.0.0.1:9100"}, listen)
+}
@@ -46,9 +46,47 @@ type BackupRestoreRequest struct {
 }
 
 type BackupRemoveRequest struct {
+	File   string `json:"file"`
+	Remote bool   `json:"remote"`
+}
+
+type BackupVerifyRequest struct {
 	File string `json:"file"`
 }
 
+type MetricsListenRequest struct {
+	Address string `json:"address"`
+}
+
+type JobCancelRequest struct {
+	Id int64 `json:"id"`
+}
+
+type CronScheduleRequest struct {
+	Job        string `json:"job"`
+	Expression string `json:"expression"`
+}
+
+type BackupRetentionRemoveRequest struct {
+	App string `json:"app"`
+}
+
+type BackupAppAutoRemoveRequest struct {
+	App string `json:"app"`
+}
+
+type BackupTargetRemoveRequest struct {
+	Name string `json:"name"`
+}
+
+type AlertRuleRemoveRequest struct {
+	Name string `json:"name"`
+}
+
+type AlertChannelRequest struct {
+	Name string `json:"name"`
+}
+
 type StorageActivatePartitionRequest struct {
 	Device string `json:"device"`
 	Format bool   `json:"format"`
@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// maxFieldSize limits the form fields read ahead of an uploaded file.
+const maxFieldSize = 64 * 1024
+
+// uploadedFile returns the "file" part of a multipart request without buffering the whole body.
+func uploadedFile(req *http.Request) (*multipart.Part, error) {
+	file, _, err := uploadedForm(req)
+	return file, err
+}
+
+// uploadedForm is uploadedFile also returning the form fields, only the fields sent before the file are read.
+func uploadedForm(req *http.Request) (*multipart.Part, url.Values, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, nil, errors.New("multipart upload expected")
+	}
+	fields := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, nil, errors.New("file is missing")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if part.FormName() == "file" {
+			return part, fields, nil
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize))
+		if err != nil {
+			return nil, nil, err
+		}
+		fields.Add(part.FormName(), string(value))
+	}
+}
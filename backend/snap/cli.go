@@ -30,10 +30,10 @@ func (s *Cli) Run(name string) error {
 	return s.run("run", name)
 }
 
-func (s *Cli) RunCmdIfExists(snap model.Snap, name string) error {
+func (s *Cli) RunCmdIfExists(snap model.Snap, name string, args ...string) error {
 	cmd := snap.FindCommand(name)
 	if cmd != nil {
-		err := s.Run(cmd.FullName())
+		err := s.run("run", append([]string{cmd.FullName()}, args...)...)
 		if err != nil {
 			return err
 		}
@@ -41,8 +41,8 @@ func (s *Cli) RunCmdIfExists(snap model.Snap, name string) error {
 	return nil
 }
 
-func (s *Cli) run(command string, name string) error {
-	_, err := s.executor.CombinedOutput("snap", command, name)
+func (s *Cli) run(command string, args ...string) error {
+	_, err := s.executor.CombinedOutput("snap", append([]string{command}, args...)...)
 	if err != nil {
 		s.logger.Error("snap failed", zap.String("command", command), zap.Error(err))
 		return err
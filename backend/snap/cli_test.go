@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/snap/model"
 	"strings"
 	"testing"
 )
@@ -34,3 +35,20 @@ func TestStop(t *testing.T) {
 	assert.Len(t, executor.executions, 1)
 	assert.Equal(t, "snap stop service1", executor.executions[0])
 }
+
+func TestRunCmdIfExists_Args(t *testing.T) {
+	executor := &ExecutorStub{}
+	service := NewCli(executor, log.Default())
+	snap := model.Snap{Name: "app", Apps: []model.App{{Name: "restore-migrate", Snap: "app"}}}
+	err := service.RunCmdIfExists(snap, "restore-migrate", "1", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"snap run app.restore-migrate 1 2"}, executor.executions)
+}
+
+func TestRunCmdIfExists_Missing(t *testing.T) {
+	executor := &ExecutorStub{}
+	service := NewCli(executor, log.Default())
+	err := service.RunCmdIfExists(model.Snap{Name: "app"}, "restore-migrate")
+	assert.Nil(t, err)
+	assert.Len(t, executor.executions, 0)
+}
@@ -10,7 +10,7 @@ import (
 
 const (
 	maxLogFileBytes = 256 * 1024
-	keepEvents      = 1000
+	keepSegments    = 20
 	defaultLimit    = 100
 )
 
@@ -23,6 +23,16 @@ const (
 	EventKindPressure       EventKind = "pressure_detected"
 	EventKindVictimSigterm  EventKind = "victim_sigterm"
 	EventKindVictimSigkill  EventKind = "victim_sigkill"
+	EventKindVictimDryRun   EventKind = "victim_would_kill"
+	EventKindMemoryLimit    EventKind = "memory_limit_applied"
+	EventKindMemoryUnlimit  EventKind = "memory_limit_removed"
+	EventKindMemoryThrottle EventKind = "memory_throttled"
+	EventKindRestarted      EventKind = "victim_restarted"
+	EventKindNoRestart      EventKind = "restart_suppressed"
+	EventKindDiskDegraded   EventKind = "disk_degraded"
+	EventKindRestoreFailed  EventKind = "restore_failed"
+	EventKindRestoreOk      EventKind = "restore_verified"
+	EventKindRestoreBroken  EventKind = "restore_verify_failed"
 )
 
 type Event struct {
@@ -36,6 +46,7 @@ type Event struct {
 	Cgroup     string    `json:"cgroup,omitempty"`
 	AvailRatio float64   `json:"avail_ratio,omitempty"`
 	PSIavg10   float64   `json:"psi_avg10,omitempty"`
+	SwapUsedKB uint64    `json:"swap_used_kb,omitempty"`
 	Path       string    `json:"path,omitempty"`
 	SizeBytes  uint64    `json:"size_bytes,omitempty"`
 }
@@ -69,82 +80,40 @@ func (l *EventLog) Append(e Event) error {
 	}
 	f.Close()
 	if size > maxLogFileBytes {
-		return l.rotateLocked(keepEvents)
+		return l.rotateLocked()
 	}
 	return nil
 }
 
 func (l *EventLog) Recent(limit int) ([]Event, error) {
-	if limit <= 0 {
-		limit = defaultLimit
-	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.readLastLocked(limit, true)
-}
-
-func (l *EventLog) readLastLocked(limit int, reverse bool) ([]Event, error) {
-	f, err := os.Open(l.path)
+	page, err := l.Query(EventFilter{}, "", limit)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return []Event{}, nil
-		}
 		return nil, err
 	}
-	defer f.Close()
-	ring := make([]Event, limit)
-	n := 0
-	dec := json.NewDecoder(f)
-	for {
-		var e Event
-		if err := dec.Decode(&e); err != nil {
-			break
-		}
-		ring[n%limit] = e
-		n++
-	}
-	count := n
-	if count > limit {
-		count = limit
-	}
-	out := make([]Event, count)
-	for i := 0; i < count; i++ {
-		var idx int
-		if reverse {
-			idx = ((n - 1 - i) % limit + limit) % limit
-		} else {
-			start := 0
-			if n > limit {
-				start = n % limit
-			}
-			idx = (start + i) % limit
-		}
-		out[i] = ring[idx]
-	}
-	return out, nil
+	return page.Events, nil
 }
 
-func (l *EventLog) rotateLocked(keep int) error {
-	events, err := l.readLastLocked(keep, false)
-	if err != nil {
+// rotateLocked archives the log into a compressed segment, the oldest segments over keepSegments are removed.
+// The log is renamed first, so the other process appending to it starts a new one.
+func (l *EventLog) rotateLocked() error {
+	rotating := l.path + ".rotating"
+	if err := os.Rename(l.path, rotating); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
 		return err
 	}
-	tmp := l.path + ".tmp"
-	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	defer os.Remove(rotating)
+	events, err := readEvents(rotating)
 	if err != nil {
 		return err
 	}
-	enc := json.NewEncoder(f)
-	for _, e := range events {
-		if err := enc.Encode(e); err != nil {
-			f.Close()
-			os.Remove(tmp)
-			return err
-		}
+	if len(events) == 0 {
+		return nil
 	}
-	if err := f.Close(); err != nil {
-		os.Remove(tmp)
+	err = writeSegment(l.path, events)
+	if err != nil {
 		return err
 	}
-	return os.Rename(tmp, l.path)
+	return l.pruneSegments()
 }
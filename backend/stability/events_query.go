@@ -0,0 +1,279 @@
+package stability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventFilter selects events by kind, app and time range [From, To), empty fields match all events.
+type EventFilter struct {
+	Kinds []EventKind
+	App   string
+	From  time.Time
+	To    time.Time
+}
+
+func (f EventFilter) Matches(e Event) bool {
+	if len(f.Kinds) > 0 {
+		found := false
+		for _, kind := range f.Kinds {
+			if e.Kind == kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.App != "" && !appMatches(e.App, f.App) {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func (f EventFilter) overlaps(s segment) bool {
+	if !f.From.IsZero() && s.last.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !s.first.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// EventPage is a page of events, newest first, Next is the cursor of the following page.
+type EventPage struct {
+	Events []Event `json:"events"`
+	Next   string  `json:"next,omitempty"`
+}
+
+type EventCount struct {
+	Kind  EventKind `json:"kind"`
+	App   string    `json:"app,omitempty"`
+	Count int       `json:"count"`
+}
+
+// sources returns the log and the segments which may have events of the filter, newest first.
+func (l *EventLog) sources(filter EventFilter) ([]string, error) {
+	segments, err := l.segments()
+	if err != nil {
+		return nil, err
+	}
+	sources := []string{l.path}
+	for i := len(segments) - 1; i >= 0; i-- {
+		if filter.overlaps(segments[i]) {
+			sources = append(sources, segments[i].path)
+		}
+	}
+	return sources, nil
+}
+
+// eachNewest calls fn with the events of the filter, newest first, until it returns false.
+func (l *EventLog) eachNewest(filter EventFilter, fn func(Event) bool) error {
+	sources, err := l.sources(filter)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		events, err := readEvents(source)
+		if err != nil {
+			return err
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			if !filter.Matches(events[i]) {
+				continue
+			}
+			if !fn(events[i]) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Query returns a page of the events of the filter, newest first.
+// The cursor is the time of the last event of the previous page with the number of events
+// at that time already returned, so the pages stay stable while new events are appended and rotated.
+func (l *EventLog) Query(filter EventFilter, cursor string, limit int) (EventPage, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	before, skip, err := parseCursor(cursor)
+	if err != nil {
+		return EventPage{}, err
+	}
+	if !before.IsZero() && (filter.To.IsZero() || before.Before(filter.To)) {
+		filter.To = before.Add(time.Nanosecond)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	page := EventPage{Events: []Event{}}
+	var runTime time.Time
+	run, lastRun := 0, 0
+	err = l.eachNewest(filter, func(e Event) bool {
+		if e.Time.Equal(runTime) {
+			run++
+		} else {
+			runTime = e.Time
+			run = 1
+		}
+		if !before.IsZero() && e.Time.Equal(before) && run <= skip {
+			return true
+		}
+		if len(page.Events) == limit {
+			last := page.Events[len(page.Events)-1]
+			page.Next = formatCursor(last.Time, lastRun)
+			return false
+		}
+		page.Events = append(page.Events, e)
+		lastRun = run
+		return true
+	})
+	if err != nil {
+		return EventPage{}, err
+	}
+	return page, nil
+}
+
+// Counts aggregates the events of the filter per kind and app, the most frequent first.
+func (l *EventLog) Counts(filter EventFilter) ([]EventCount, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := make(map[EventCount]int)
+	err := l.eachNewest(filter, func(e Event) bool {
+		app, _, _ := strings.Cut(e.App, ".")
+		counts[EventCount{Kind: e.Kind, App: app}]++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]EventCount, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		result = append(result, key)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].App < result[j].App
+	})
+	return result, nil
+}
+
+// Export writes the events of the filter as JSON lines, oldest first.
+func (l *EventLog) Export(w io.Writer, filter EventFilter) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sources, err := l.sources(filter)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := len(sources) - 1; i >= 0; i-- {
+		events, err := readEvents(sources[i])
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if !filter.Matches(e) {
+				continue
+			}
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Follow polls the log and calls fn with the events of the filter appended after it started,
+// oldest first, until the context is done or fn fails.
+func (l *EventLog) Follow(ctx context.Context, filter EventFilter, interval time.Duration, fn func(Event) error) error {
+	last := time.Now().UTC()
+	seen := 0
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current := filter
+		if current.From.Before(last) {
+			current.From = last
+		}
+		var events []Event
+		l.mu.Lock()
+		err := l.eachNewest(current, func(e Event) bool {
+			events = append(events, e)
+			return true
+		})
+		l.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		var runTime time.Time
+		run := 0
+		for i := len(events) - 1; i >= 0; i-- {
+			e := events[i]
+			if e.Time.Equal(runTime) {
+				run++
+			} else {
+				runTime = e.Time
+				run = 1
+			}
+			if e.Time.Equal(last) && run <= seen {
+				continue
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+			if !e.Time.Equal(last) {
+				last = e.Time
+			}
+			seen = run
+		}
+	}
+}
+
+func formatCursor(t time.Time, skip int) string {
+	return fmt.Sprintf("%d.%d", t.UnixNano(), skip)
+}
+
+func parseCursor(cursor string) (time.Time, int, error) {
+	if cursor == "" {
+		return time.Time{}, 0, nil
+	}
+	nano, skip, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	n, err := strconv.ParseInt(nano, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	s, err := strconv.Atoi(skip)
+	if err != nil || s < 0 {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	return time.Unix(0, n).UTC(), s, nil
+}
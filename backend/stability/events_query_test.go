@@ -0,0 +1,154 @@
+package stability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_FiltersAndPages(t *testing.T) {
+	log := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		app := "gitea.server"
+		if i%2 == 0 {
+			app = "nextcloud.php-fpm"
+		}
+		require.NoError(t, log.Append(Event{Time: start.Add(time.Duration(i) * time.Minute), Kind: EventKindVictimSigterm, App: app, PID: i}))
+	}
+	require.NoError(t, log.Append(Event{Time: start.Add(time.Hour), Kind: EventKindPressure}))
+
+	filter := EventFilter{Kinds: []EventKind{EventKindVictimSigterm}, App: "nextcloud", From: start.Add(time.Minute)}
+	page, err := log.Query(filter, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, 8, page.Events[0].PID)
+	assert.Equal(t, 6, page.Events[1].PID)
+	require.NotEmpty(t, page.Next)
+
+	page, err = log.Query(filter, page.Next, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	assert.Equal(t, 4, page.Events[0].PID)
+	assert.Equal(t, 2, page.Events[1].PID)
+	assert.Empty(t, page.Next)
+}
+
+func TestQuery_PagesEventsWithSameTime(t *testing.T) {
+	log := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, log.Append(Event{Time: now, Kind: EventKindPressure, PID: i}))
+	}
+	var pids []int
+	cursor := ""
+	for {
+		page, err := log.Query(EventFilter{}, cursor, 2)
+		require.NoError(t, err)
+		for _, e := range page.Events {
+			pids = append(pids, e.PID)
+		}
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	assert.Equal(t, []int{4, 3, 2, 1, 0}, pids)
+}
+
+func TestQuery_InvalidCursor(t *testing.T) {
+	log := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	_, err := log.Query(EventFilter{}, "abc", 10)
+	assert.Error(t, err)
+}
+
+func TestQuery_ReadsRotatedSegments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	log := NewEventLog(path)
+	for i := 0; i < 5000; i++ {
+		require.NoError(t, log.Append(Event{Kind: EventKindPressure, PID: i, Comm: "memhog"}))
+	}
+	segments, err := log.segments()
+	require.NoError(t, err)
+	assert.NotEmpty(t, segments)
+
+	var buf bytes.Buffer
+	require.NoError(t, log.Export(&buf, EventFilter{}))
+	dec := json.NewDecoder(&buf)
+	count := 0
+	for {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			break
+		}
+		assert.Equal(t, count, e.PID)
+		count++
+	}
+	assert.Equal(t, 5000, count)
+}
+
+func TestRotation_KeepsSegmentsBounded(t *testing.T) {
+	log := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	for i := 0; i < keepSegments+3; i++ {
+		require.NoError(t, log.Append(Event{Time: time.Unix(int64(i), 0), Kind: EventKindPressure, PID: i}))
+		log.mu.Lock()
+		require.NoError(t, log.rotateLocked())
+		log.mu.Unlock()
+	}
+	segments, err := log.segments()
+	require.NoError(t, err)
+	require.Len(t, segments, keepSegments)
+	assert.Equal(t, time.Unix(3, 0).UTC(), segments[0].first)
+}
+
+func TestCounts_PerKindAndApp(t *testing.T) {
+	log := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	now := time.Now().UTC()
+	require.NoError(t, log.Append(Event{Time: now.Add(-2 * time.Hour), Kind: EventKindVictimSigkill, App: "gitea.server"}))
+	require.NoError(t, log.Append(Event{Time: now, Kind: EventKindVictimSigkill, App: "nextcloud.php-fpm"}))
+	require.NoError(t, log.Append(Event{Time: now, Kind: EventKindVictimSigterm, App: "nextcloud.mysql"}))
+	require.NoError(t, log.Append(Event{Time: now, Kind: EventKindVictimSigterm, App: "nextcloud.php-fpm"}))
+	require.NoError(t, log.Append(Event{Time: now, Kind: EventKindPressure}))
+
+	counts, err := log.Counts(EventFilter{From: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, []EventCount{
+		{Kind: EventKindVictimSigterm, App: "nextcloud", Count: 2},
+		{Kind: EventKindPressure, Count: 1},
+		{Kind: EventKindVictimSigkill, App: "nextcloud", Count: 1},
+	}, counts)
+}
+
+func TestFollow_SendsNewEvents(t *testing.T) {
+	log := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	require.NoError(t, log.Append(Event{Time: time.Now().Add(-time.Minute), Kind: EventKindPressure, PID: 1}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := errors.New("done")
+	var got []int
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = log.Append(Event{Kind: EventKindVictimSigterm, PID: 2})
+		_ = log.Append(Event{Kind: EventKindPressure, PID: 3})
+		_ = log.Append(Event{Kind: EventKindVictimSigkill, PID: 4})
+	}()
+	filter := EventFilter{Kinds: []EventKind{EventKindVictimSigterm, EventKindVictimSigkill}}
+	err := log.Follow(ctx, filter, 10*time.Millisecond, func(e Event) error {
+		got = append(got, e.PID)
+		if len(got) == 2 {
+			return done
+		}
+		return nil
+	})
+	assert.Equal(t, done, err)
+	assert.Equal(t, []int{2, 4}, got)
+}
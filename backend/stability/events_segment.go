@@ -0,0 +1,139 @@
+package stability
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const segmentExt = ".gz"
+
+// segment is a rotated part of the event log named by the time range of its events:
+// stability-events.jsonl.<first unix nano>-<last unix nano>.gz
+type segment struct {
+	path  string
+	first time.Time
+	last  time.Time
+}
+
+func (l *EventLog) segments() ([]segment, error) {
+	files, err := filepath.Glob(l.path + ".*" + segmentExt)
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(l.path) + "."
+	var segments []segment
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), prefix), segmentExt)
+		first, last, ok := strings.Cut(name, "-")
+		if !ok {
+			continue
+		}
+		firstNano, err := strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			continue
+		}
+		lastNano, err := strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{
+			path:  file,
+			first: time.Unix(0, firstNano).UTC(),
+			last:  time.Unix(0, lastNano).UTC(),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].last.Before(segments[j].last) })
+	return segments, nil
+}
+
+func (l *EventLog) pruneSegments() error {
+	segments, err := l.segments()
+	if err != nil {
+		return err
+	}
+	for len(segments) > keepSegments {
+		err = os.Remove(segments[0].path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
+
+func writeSegment(logPath string, events []Event) error {
+	first, last := events[0].Time, events[0].Time
+	for _, e := range events {
+		if e.Time.Before(first) {
+			first = e.Time
+		}
+		if e.Time.After(last) {
+			last = e.Time
+		}
+	}
+	path := fmt.Sprintf("%s.%d-%d%s", logPath, first.UnixNano(), last.UnixNano(), segmentExt)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readEvents reads a log or a segment in the order the events were written, a broken tail is skipped.
+func readEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Event{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var reader io.Reader = f
+	if strings.HasSuffix(path, segmentExt) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	events := []Event{}
+	dec := json.NewDecoder(reader)
+	for {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			break
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,248 @@
+package stability
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	memoryHighFile    = "memory.high"
+	memoryMaxFile     = "memory.max"
+	memoryCurrentFile = "memory.current"
+	memoryUnlimited   = "max"
+	// throttleRatio is the part of the current usage memory.high is lowered to when an app is throttled.
+	throttleRatio = 0.9
+)
+
+var appServiceRe = regexp.MustCompile(`^snap\.([^.]+)\.[^/]+\.service$`)
+
+// MemoryLimits shares Ratio of the total memory between the apps by Weights, an app without a weight has 1.
+// The app share is split evenly between the app services, memory.max of a service is its part and memory.high is HighRatio of it.
+type MemoryLimits struct {
+	Enabled   bool               `json:"enabled"`
+	Ratio     float64            `json:"ratio"`
+	HighRatio float64            `json:"high_ratio"`
+	Weights   map[string]float64 `json:"weights,omitempty"`
+}
+
+func DefaultMemoryLimits() MemoryLimits {
+	return MemoryLimits{
+		Ratio:     0.9,
+		HighRatio: 0.8,
+	}
+}
+
+func (m MemoryLimits) Validate() error {
+	if m.Ratio <= 0 || m.Ratio > 1 {
+		return fmt.Errorf("memory_limits.ratio should be a ratio between 0 and 1")
+	}
+	if m.HighRatio <= 0 || m.HighRatio > 1 {
+		return fmt.Errorf("memory_limits.high_ratio should be a ratio between 0 and 1")
+	}
+	for app, weight := range m.Weights {
+		if weight <= 0 {
+			return fmt.Errorf("memory weight of %s should be positive", app)
+		}
+	}
+	return nil
+}
+
+func (m MemoryLimits) weight(app string) float64 {
+	weight, ok := m.Weights[app]
+	if !ok {
+		return 1
+	}
+	return weight
+}
+
+type Limit struct {
+	App  string
+	Unit string
+	High uint64
+	Max  uint64
+}
+
+// Limits sets memory.high and memory.max on the cgroups of the app services.
+type Limits struct {
+	cgroupDir string
+	events    *EventLog
+	log       *zap.Logger
+}
+
+func NewLimits(cgroupDir string, events *EventLog, log *zap.Logger) *Limits {
+	return &Limits{cgroupDir: cgroupDir, events: events, log: log}
+}
+
+// units returns the app services which are not protected, keyed by unit.
+func (l *Limits) units(protect Protect) (map[string]string, error) {
+	entries, err := os.ReadDir(filepath.Join(l.cgroupDir, "system.slice"))
+	if err != nil {
+		return nil, err
+	}
+	units := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		match := appServiceRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		cgroup := "0::/system.slice/" + entry.Name()
+		if protect.IsProtected(Victim{App: parseAppLabel(cgroup), Cgroup: cgroup}) {
+			continue
+		}
+		units[entry.Name()] = match[1]
+	}
+	return units, nil
+}
+
+// Plan derives the limits of the app services from the total memory.
+func (l *Limits) Plan(limits MemoryLimits, protect Protect, totalBytes uint64) ([]Limit, error) {
+	units, err := l.units(protect)
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[string]int)
+	for _, app := range units {
+		services[app]++
+	}
+	total := 0.0
+	for app := range services {
+		total += limits.weight(app)
+	}
+	var plan []Limit
+	for unit, app := range units {
+		share := float64(totalBytes) * limits.Ratio * limits.weight(app) / total / float64(services[app])
+		plan = append(plan, Limit{
+			App:  app,
+			Unit: unit,
+			High: uint64(share * limits.HighRatio),
+			Max:  uint64(share),
+		})
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Unit < plan[j].Unit })
+	return plan, nil
+}
+
+// Apply writes the planned limits, only the changed ones are written and recorded.
+func (l *Limits) Apply(limits MemoryLimits, protect Protect, totalBytes uint64) error {
+	plan, err := l.Plan(limits, protect, totalBytes)
+	if err != nil {
+		return err
+	}
+	for _, limit := range plan {
+		high := strconv.FormatUint(limit.High, 10)
+		max := strconv.FormatUint(limit.Max, 10)
+		changed, err := l.set(limit.Unit, high, max)
+		if err != nil {
+			l.log.Warn("oom-watcher: cannot set memory limit", zap.String("unit", limit.Unit), zap.Error(err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		l.log.Info("oom-watcher: memory limit applied",
+			zap.String("unit", limit.Unit),
+			zap.Uint64("high", limit.High),
+			zap.Uint64("max", limit.Max),
+		)
+		l.record(Event{
+			Kind:      EventKindMemoryLimit,
+			App:       limit.App,
+			Cgroup:    limit.Unit,
+			SizeBytes: limit.Max,
+			Message:   fmt.Sprintf("memory.high=%s memory.max=%s", high, max),
+		})
+	}
+	return nil
+}
+
+// Reset removes the limits of the app services.
+func (l *Limits) Reset(protect Protect) error {
+	units, err := l.units(protect)
+	if err != nil {
+		return err
+	}
+	for unit, app := range units {
+		changed, err := l.set(unit, memoryUnlimited, memoryUnlimited)
+		if err != nil {
+			l.log.Warn("oom-watcher: cannot remove memory limit", zap.String("unit", unit), zap.Error(err))
+			continue
+		}
+		if changed {
+			l.record(Event{Kind: EventKindMemoryUnlimit, App: app, Cgroup: unit})
+		}
+	}
+	return nil
+}
+
+// Throttle lowers memory.high of an app service below its usage so the kernel reclaims the app memory.
+func (l *Limits) Throttle(cgroup string) (uint64, error) {
+	dir := l.cgroupPath(cgroup)
+	if !appServiceRe.MatchString(filepath.Base(dir)) {
+		return 0, fmt.Errorf("%s is not an app service", cgroup)
+	}
+	current, err := readUint(filepath.Join(dir, memoryCurrentFile))
+	if err != nil {
+		return 0, err
+	}
+	high := uint64(float64(current) * throttleRatio)
+	err = os.WriteFile(filepath.Join(dir, memoryHighFile), []byte(strconv.FormatUint(high, 10)), 0644)
+	if err != nil {
+		return 0, err
+	}
+	return high, nil
+}
+
+// cgroupPath converts a cgroup from /proc/<pid>/cgroup to its directory.
+func (l *Limits) cgroupPath(cgroup string) string {
+	return filepath.Join(l.cgroupDir, strings.TrimPrefix(cgroup, "0::"))
+}
+
+func (l *Limits) set(unit string, high string, max string) (bool, error) {
+	dir := filepath.Join(l.cgroupDir, "system.slice", unit)
+	changed := false
+	for file, value := range map[string]string{memoryHighFile: high, memoryMaxFile: max} {
+		path := filepath.Join(dir, file)
+		current, err := os.ReadFile(path)
+		if err != nil {
+			return changed, err
+		}
+		if strings.TrimSpace(string(current)) == value {
+			continue
+		}
+		err = os.WriteFile(path, []byte(value), 0644)
+		if err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+func (l *Limits) record(event Event) {
+	if l.events != nil {
+		_ = l.events.Append(event)
+	}
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return 0, errors.New("empty " + filepath.Base(path))
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
@@ -0,0 +1,139 @@
+package stability
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writeUnit(t *testing.T, cgroupDir, unit string, current string) {
+	t.Helper()
+	writeProcFile(t, cgroupDir, filepath.Join("system.slice", unit, "memory.high"), "max\n")
+	writeProcFile(t, cgroupDir, filepath.Join("system.slice", unit, "memory.max"), "max\n")
+	writeProcFile(t, cgroupDir, filepath.Join("system.slice", unit, "memory.current"), current+"\n")
+}
+
+func readUnit(t *testing.T, cgroupDir, unit, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(cgroupDir, "system.slice", unit, file))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLimits_Plan_SharesByWeight(t *testing.T) {
+	dir := t.TempDir()
+	writeUnit(t, dir, "snap.nextcloud.php-fpm.service", "0")
+	writeUnit(t, dir, "snap.nextcloud.mysql.service", "0")
+	writeUnit(t, dir, "snap.gitea.server.service", "0")
+	writeUnit(t, dir, "snap.platform.backend.service", "0")
+	writeUnit(t, dir, "ssh.service", "0")
+
+	limits := DefaultMemoryLimits()
+	limits.Ratio = 1
+	limits.HighRatio = 0.5
+	limits.Weights = map[string]float64{"nextcloud": 3}
+	plan, err := NewLimits(dir, nil, zap.NewNop()).Plan(limits, DefaultProtect(), 4000)
+	require.NoError(t, err)
+	assert.Equal(t, []Limit{
+		{App: "gitea", Unit: "snap.gitea.server.service", High: 500, Max: 1000},
+		{App: "nextcloud", Unit: "snap.nextcloud.mysql.service", High: 750, Max: 1500},
+		{App: "nextcloud", Unit: "snap.nextcloud.php-fpm.service", High: 750, Max: 1500},
+	}, plan)
+}
+
+func TestLimits_Plan_TotalWithinRatio(t *testing.T) {
+	dir := t.TempDir()
+	writeUnit(t, dir, "snap.nextcloud.php-fpm.service", "0")
+	writeUnit(t, dir, "snap.nextcloud.mysql.service", "0")
+	writeUnit(t, dir, "snap.nextcloud.nginx.service", "0")
+	writeUnit(t, dir, "snap.nextcloud.redis.service", "0")
+	writeUnit(t, dir, "snap.mail.postfix.service", "0")
+	writeUnit(t, dir, "snap.mail.dovecot.service", "0")
+	writeUnit(t, dir, "snap.gitea.server.service", "0")
+
+	limits := DefaultMemoryLimits()
+	limits.Weights = map[string]float64{"nextcloud": 2}
+	totalBytes := uint64(8 * 1024 * 1024 * 1024)
+	plan, err := NewLimits(dir, nil, zap.NewNop()).Plan(limits, DefaultProtect(), totalBytes)
+	require.NoError(t, err)
+	require.Len(t, plan, 7)
+	sum := uint64(0)
+	apps := make(map[string]uint64)
+	for _, limit := range plan {
+		sum += limit.Max
+		apps[limit.App] += limit.Max
+		assert.LessOrEqual(t, limit.High, limit.Max)
+	}
+	assert.LessOrEqual(t, float64(sum), float64(totalBytes)*limits.Ratio)
+	assert.InDelta(t, float64(totalBytes)*limits.Ratio, float64(sum), 10)
+	assert.InDelta(t, float64(apps["nextcloud"]), float64(apps["mail"]*2), 10)
+	assert.InDelta(t, float64(apps["mail"]), float64(apps["gitea"]), 10)
+}
+
+func TestLimits_Apply_RecordsChangedOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeUnit(t, dir, "snap.gitea.server.service", "0")
+	events := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	limits := NewLimits(dir, events, zap.NewNop())
+	config := MemoryLimits{Enabled: true, Ratio: 0.5, HighRatio: 0.5}
+
+	require.NoError(t, limits.Apply(config, DefaultProtect(), 4000))
+	require.NoError(t, limits.Apply(config, DefaultProtect(), 4000))
+	assert.Equal(t, "1000", readUnit(t, dir, "snap.gitea.server.service", "memory.high"))
+	assert.Equal(t, "2000", readUnit(t, dir, "snap.gitea.server.service", "memory.max"))
+
+	require.NoError(t, limits.Reset(DefaultProtect()))
+	assert.Equal(t, "max", readUnit(t, dir, "snap.gitea.server.service", "memory.high"))
+	assert.Equal(t, "max", readUnit(t, dir, "snap.gitea.server.service", "memory.max"))
+
+	recent, err := events.Recent(10)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	kinds := []EventKind{recent[0].Kind, recent[1].Kind}
+	assert.ElementsMatch(t, []EventKind{EventKindMemoryLimit, EventKindMemoryUnlimit}, kinds)
+}
+
+func TestLimits_Throttle_OnlyAppServices(t *testing.T) {
+	dir := t.TempDir()
+	writeUnit(t, dir, "snap.gitea.server.service", "1000")
+	limits := NewLimits(dir, nil, zap.NewNop())
+
+	high, err := limits.Throttle("0::/system.slice/snap.gitea.server.service")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(900), high)
+	assert.Equal(t, "900", readUnit(t, dir, "snap.gitea.server.service", "memory.high"))
+
+	_, err = limits.Throttle("0::/user.slice/user-1000.slice/session-1.scope")
+	assert.Error(t, err)
+}
+
+func TestWatcher_ThrottlesBeforeKill(t *testing.T) {
+	procDir := t.TempDir()
+	cgroupDir := t.TempDir()
+	unit := "snap.photoprism.web.service"
+	writeFakeProc(t, procDir, fakeProc{pid: 100, name: "photoprism", rssKB: 500000, cgroup: "0::/system.slice/" + unit})
+	writeUnit(t, cgroupDir, unit, "1000")
+	w := newWatcherWithProc(t, 4000000, 100000, procDir)
+	w.limits = NewLimits(cgroupDir, nil, zap.NewNop())
+	w.policy.MemoryLimits.Enabled = true
+	w.policy.GraceSeconds = 0.5
+	k := &fakeKill{alive: map[int]bool{}}
+	w.kill = k.fn
+
+	require.NoError(t, w.tick())
+	assert.Empty(t, k.calls)
+	assert.Equal(t, "900", readUnit(t, cgroupDir, unit, "memory.high"))
+
+	require.NoError(t, w.tick())
+	assert.Empty(t, k.calls)
+
+	w.throttled["0::/system.slice/"+unit] = w.throttled["0::/system.slice/"+unit].Add(-w.policy.Grace())
+	require.NoError(t, w.tick())
+	require.NotEmpty(t, k.calls)
+	assert.Equal(t, syscall.SIGTERM, k.calls[0].sig)
+}
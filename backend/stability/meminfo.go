@@ -16,6 +16,15 @@ type MemInfo struct {
 type MemSnap struct {
 	TotalKB     uint64
 	AvailableKB uint64
+	SwapTotalKB uint64
+	SwapFreeKB  uint64
+}
+
+func (s MemSnap) SwapUsedKB() uint64 {
+	if s.SwapFreeKB > s.SwapTotalKB {
+		return 0
+	}
+	return s.SwapTotalKB - s.SwapFreeKB
 }
 
 func (s MemSnap) AvailableRatio() float64 {
@@ -45,6 +54,10 @@ func (m *MemInfo) Snapshot() (MemSnap, error) {
 			s.TotalKB = val
 		case "MemAvailable":
 			s.AvailableKB = val
+		case "SwapTotal":
+			s.SwapTotalKB = val
+		case "SwapFree":
+			s.SwapFreeKB = val
 		}
 	}
 	if err := sc.Err(); err != nil {
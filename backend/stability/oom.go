@@ -3,6 +3,7 @@ package stability
 import (
 	"errors"
 	"os"
+	"sort"
 	"syscall"
 	"time"
 
@@ -11,45 +12,120 @@ import (
 
 type KillFn func(pid int, sig syscall.Signal) error
 
+const (
+	// dryRunCooldown limits the events of a dry run, nothing is killed so the pressure stays.
+	dryRunCooldown = time.Minute
+	// limitsInterval is how often the memory limits are applied again, a restarted service loses them.
+	limitsInterval = time.Minute
+)
+
 type Watcher struct {
-	mem      *MemInfo
-	scan     *ProcScanner
-	protect  Protect
-	kill     KillFn
-	events   *EventLog
-	log      *zap.Logger
-	interval time.Duration
-	availMin float64
-	psiMax   float64
-	grace    time.Duration
-	selfPID  int
+	mem            *MemInfo
+	scan           *ProcScanner
+	policy         Policy
+	protect        Protect
+	policyFile     *PolicyFile
+	policyModified time.Time
+	lastDryRun     time.Time
+	limits         *Limits
+	limited        bool
+	limitsApplied  time.Time
+	throttled      map[string]time.Time
+	restarter      *Restarter
+	kill           KillFn
+	events         *EventLog
+	log            *zap.Logger
+	interval       time.Duration
+	selfPID        int
 }
 
-func NewWatcher(mem *MemInfo, scan *ProcScanner, kill KillFn, events *EventLog, log *zap.Logger) *Watcher {
+func NewWatcher(mem *MemInfo, scan *ProcScanner, kill KillFn, events *EventLog, policyFile *PolicyFile, limits *Limits, restarter *Restarter, log *zap.Logger) *Watcher {
+	policy := DefaultPolicy()
 	return &Watcher{
-		mem:      mem,
-		scan:     scan,
-		protect:  DefaultProtect(),
-		kill:     kill,
-		events:   events,
-		log:      log,
-		interval: 2 * time.Second,
-		availMin: 0.08,
-		psiMax:   40.0,
-		grace:    4 * time.Second,
-		selfPID:  os.Getpid(),
+		mem:        mem,
+		scan:       scan,
+		policy:     policy,
+		protect:    policy.protect(),
+		policyFile: policyFile,
+		limits:     limits,
+		throttled:  make(map[string]time.Time),
+		restarter:  restarter,
+		kill:       kill,
+		events:     events,
+		log:        log,
+		interval:   2 * time.Second,
+		selfPID:    os.Getpid(),
+	}
+}
+
+// reload applies the policy file when it was changed, a broken file keeps the current policy.
+func (w *Watcher) reload() {
+	if w.policyFile == nil {
+		return
+	}
+	modified := w.policyFile.modified()
+	if modified.Equal(w.policyModified) {
+		return
+	}
+	w.policyModified = modified
+	policy, err := w.policyFile.Load()
+	if err != nil {
+		w.log.Warn("oom-watcher: policy is not valid, keeping the current one", zap.Error(err))
+		return
+	}
+	w.policy = policy
+	w.protect = policy.protect()
+	w.log.Info("oom-watcher: policy loaded",
+		zap.Float64("avail_min", policy.AvailMin),
+		zap.Float64("psi_max", policy.PSIMax),
+		zap.Duration("grace", policy.Grace()),
+		zap.Bool("dry_run", policy.DryRun),
+		zap.Bool("memory_limits", policy.MemoryLimits.Enabled),
+	)
+	w.applyLimits()
+}
+
+// applyLimits keeps the memory limits of the app services in line with the policy.
+func (w *Watcher) applyLimits() {
+	if w.limits == nil {
+		return
+	}
+	w.limitsApplied = time.Now()
+	if !w.policy.MemoryLimits.Enabled {
+		if !w.limited {
+			return
+		}
+		w.limited = false
+		if err := w.limits.Reset(w.protect); err != nil {
+			w.log.Warn("oom-watcher: cannot remove memory limits", zap.Error(err))
+		}
+		return
+	}
+	snap, err := w.mem.Snapshot()
+	if err != nil {
+		w.log.Warn("oom-watcher: cannot apply memory limits", zap.Error(err))
+		return
+	}
+	w.limited = true
+	if err := w.limits.Apply(w.policy.MemoryLimits, w.protect, snap.TotalKB*1024); err != nil {
+		w.log.Warn("oom-watcher: cannot apply memory limits", zap.Error(err))
 	}
 }
 
 func (w *Watcher) Run() {
 	t := time.NewTicker(w.interval)
 	defer t.Stop()
+	w.reload()
 	w.log.Info("oom-watcher: started",
 		zap.Duration("interval", w.interval),
-		zap.Float64("avail_min", w.availMin),
-		zap.Float64("psi_max", w.psiMax),
+		zap.Float64("avail_min", w.policy.AvailMin),
+		zap.Float64("psi_max", w.policy.PSIMax),
 	)
 	for range t.C {
+		w.reload()
+		if time.Since(w.limitsApplied) >= limitsInterval {
+			w.applyLimits()
+		}
 		if err := w.tick(); err != nil {
 			w.log.Warn("oom-watcher: tick error", zap.Error(err))
 		}
@@ -71,38 +147,125 @@ func (w *Watcher) tick() error {
 		}
 	}
 	if !w.pressureExceeded(avail, psi, psiOK) {
+		if len(w.throttled) > 0 {
+			w.throttled = make(map[string]time.Time)
+			w.applyLimits()
+		}
+		if w.restarter != nil {
+			w.restarter.Restart(w.policy.Restart, time.Now())
+		}
 		return nil
 	}
+	if w.restarter != nil {
+		w.restarter.Pressure(time.Now())
+	}
+	if w.policy.DryRun {
+		if time.Since(w.lastDryRun) < dryRunCooldown {
+			return nil
+		}
+		w.lastDryRun = time.Now()
+	}
 	w.log.Warn("oom-watcher: pressure detected",
 		zap.Float64("avail_ratio", avail),
 		zap.Float64("psi_avg10", psi),
 		zap.Bool("psi_ok", psiOK),
 	)
 	if w.events != nil {
-		_ = w.events.Append(Event{Kind: EventKindPressure, AvailRatio: avail, PSIavg10: psi})
+		_ = w.events.Append(Event{Kind: EventKindPressure, AvailRatio: avail, PSIavg10: psi, SwapUsedKB: snap.SwapUsedKB()})
+	}
+	if w.policy.DryRun {
+		return w.reportWorst()
+	}
+	throttling, err := w.throttleWorst()
+	if err != nil {
+		return err
+	}
+	if throttling {
+		return nil
 	}
 	return w.killWorst()
 }
 
 func (w *Watcher) pressureExceeded(avail, psi float64, psiOK bool) bool {
-	if avail < w.availMin {
+	if avail < w.policy.AvailMin {
 		return true
 	}
-	if psiOK && psi > w.psiMax {
+	if psiOK && psi > w.policy.PSIMax {
 		return true
 	}
 	return false
 }
 
-func (w *Watcher) killWorst() error {
+func (w *Watcher) worst() (Victim, error) {
 	cands, err := w.scan.Candidates(w.protect, w.selfPID)
 	if err != nil {
-		return err
+		return Victim{}, err
 	}
 	if len(cands) == 0 {
-		return ErrNoVictim
+		return Victim{}, ErrNoVictim
+	}
+	for i := range cands {
+		cands[i].Score = w.policy.prioritized(cands[i])
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Score > cands[j].Score })
+	return cands[0], nil
+}
+
+// throttleWorst lowers memory.high of the worst app first, it is killed when the pressure stays for the grace period.
+func (w *Watcher) throttleWorst() (bool, error) {
+	if w.limits == nil || !w.policy.MemoryLimits.Enabled {
+		return false, nil
+	}
+	v, err := w.worst()
+	if err != nil {
+		return false, err
+	}
+	since, ok := w.throttled[v.Cgroup]
+	if ok {
+		return time.Since(since) < w.policy.Grace(), nil
+	}
+	high, err := w.limits.Throttle(v.Cgroup)
+	if err != nil {
+		w.log.Warn("oom-watcher: cannot throttle victim", zap.String("cgroup", v.Cgroup), zap.Error(err))
+		return false, nil
+	}
+	w.throttled[v.Cgroup] = time.Now()
+	w.log.Warn("oom-watcher: throttled victim",
+		zap.Int("pid", v.PID),
+		zap.String("comm", v.Comm),
+		zap.Uint64("rss_kb", v.RSSkB),
+		zap.String("cgroup", v.Cgroup),
+		zap.Uint64("high", high),
+	)
+	if w.events != nil {
+		_ = w.events.Append(Event{Kind: EventKindMemoryThrottle, PID: v.PID, Comm: v.Comm, App: v.App, RSSkb: v.RSSkB, Cgroup: v.Cgroup, SizeBytes: high})
+	}
+	return true, nil
+}
+
+// reportWorst records the process which would be killed without killing it.
+func (w *Watcher) reportWorst() error {
+	v, err := w.worst()
+	if err != nil {
+		return err
+	}
+	w.log.Warn("oom-watcher: dry run, would kill victim",
+		zap.Int("pid", v.PID),
+		zap.String("comm", v.Comm),
+		zap.Uint64("rss_kb", v.RSSkB),
+		zap.String("cgroup", v.Cgroup),
+	)
+	if w.events != nil {
+		_ = w.events.Append(Event{Kind: EventKindVictimDryRun, PID: v.PID, Comm: v.Comm, App: v.App, RSSkb: v.RSSkB, Cgroup: v.Cgroup})
+	}
+	return nil
+}
+
+func (w *Watcher) killWorst() error {
+	v, err := w.worst()
+	if err != nil {
+		return err
 	}
-	v := cands[0]
 	w.log.Warn("oom-watcher: SIGTERM victim",
 		zap.Int("pid", v.PID),
 		zap.String("comm", v.Comm),
@@ -118,7 +281,10 @@ func (w *Watcher) killWorst() error {
 		}
 		return err
 	}
-	deadline := time.Now().Add(w.grace)
+	if w.restarter != nil {
+		w.restarter.Killed(v, time.Now())
+	}
+	deadline := time.Now().Add(w.policy.Grace())
 	for time.Now().Before(deadline) {
 		if w.kill(v.PID, 0) != nil {
 			return nil
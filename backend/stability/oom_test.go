@@ -35,7 +35,7 @@ func newWatcherWithProc(t *testing.T, memTotal, memAvail uint64, procDir string)
 	root := t.TempDir()
 	procRoot := root
 	writeProcFile(t, procRoot, "meminfo", "MemTotal: "+strconvUint(memTotal)+" kB\nMemAvailable: "+strconvUint(memAvail)+" kB\n")
-	return NewWatcher(NewMemInfo(procRoot), NewProcScanner(procDir), nil, nil, zap.NewNop())
+	return NewWatcher(NewMemInfo(procRoot), NewProcScanner(procDir), nil, nil, nil, nil, nil, zap.NewNop())
 }
 
 func TestTickNoActionWhenHealthy(t *testing.T) {
@@ -54,7 +54,7 @@ func TestTickSigtermsThenSigkills(t *testing.T) {
 	w := newWatcherWithProc(t, 4000000, 100000, procDir)
 	k := &fakeKill{alive: map[int]bool{100: true}}
 	w.kill = k.fn
-	w.grace = 500_000_000
+	w.policy.GraceSeconds = 0.5
 	require.NoError(t, w.killWorst())
 	require.NotEmpty(t, k.calls)
 	assert.Equal(t, syscall.SIGTERM, k.calls[0].sig)
@@ -85,7 +85,7 @@ func TestKillWorstReturnsErrNoVictim(t *testing.T) {
 }
 
 func TestPressureExceededByAvailOrPSI(t *testing.T) {
-	w := NewWatcher(NewMemInfo(t.TempDir()), nil, nil, nil, zap.NewNop())
+	w := NewWatcher(NewMemInfo(t.TempDir()), nil, nil, nil, nil, nil, nil, zap.NewNop())
 	assert.True(t, w.pressureExceeded(0.05, 0, false))
 	assert.False(t, w.pressureExceeded(0.30, 0, false))
 	assert.True(t, w.pressureExceeded(0.30, 50, true))
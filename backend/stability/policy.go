@@ -0,0 +1,147 @@
+package stability
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const PolicyFileName = "stability-policy.json"
+
+// Policy is the OOM watcher configuration, missing fields keep the defaults.
+// Priority scales the kill score of the app processes by 1+priority/1000, a positive one gets the app killed sooner.
+// Protect is added to the default protection, NeverKill lists apps which are never killed.
+// MemoryLimits caps the app memory ahead of the pressure, the apps are throttled then before they are killed.
+// Restart brings back the killed app services after the pressure is gone.
+// Zram is applied when the zram swap is set up on boot.
+type Policy struct {
+	AvailMin     float64        `json:"avail_min"`
+	PSIMax       float64        `json:"psi_max"`
+	GraceSeconds float64        `json:"grace_seconds"`
+	DryRun       bool           `json:"dry_run"`
+	Priority     map[string]int `json:"priority,omitempty"`
+	Protect      Protect        `json:"protect"`
+	NeverKill    []string       `json:"never_kill,omitempty"`
+	MemoryLimits MemoryLimits   `json:"memory_limits"`
+	Restart      RestartPolicy  `json:"restart"`
+	Zram         ZramPolicy     `json:"zram"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		AvailMin:     0.08,
+		PSIMax:       40,
+		GraceSeconds: 4,
+		MemoryLimits: DefaultMemoryLimits(),
+		Restart:      DefaultRestartPolicy(),
+		Zram:         DefaultZramPolicy(),
+	}
+}
+
+func (p Policy) Grace() time.Duration {
+	return time.Duration(p.GraceSeconds * float64(time.Second))
+}
+
+func (p Policy) Validate() error {
+	if p.AvailMin <= 0 || p.AvailMin >= 1 {
+		return fmt.Errorf("avail_min should be a ratio between 0 and 1")
+	}
+	if p.PSIMax <= 0 || p.PSIMax > 100 {
+		return fmt.Errorf("psi_max should be a percent between 0 and 100")
+	}
+	if p.GraceSeconds <= 0 {
+		return fmt.Errorf("grace_seconds should be positive")
+	}
+	for app, priority := range p.Priority {
+		if priority < -1000 || priority > 1000 {
+			return fmt.Errorf("priority of %s should be between -1000 and 1000", app)
+		}
+	}
+	err := p.MemoryLimits.Validate()
+	if err != nil {
+		return err
+	}
+	return p.Restart.Validate()
+}
+
+// protect is the default protection with the configured one added.
+func (p Policy) protect() Protect {
+	protect := DefaultProtect()
+	protect.Comms = append(protect.Comms, p.Protect.Comms...)
+	protect.CgroupSubstrings = append(protect.CgroupSubstrings, p.Protect.CgroupSubstrings...)
+	protect.Apps = append(protect.Apps, p.NeverKill...)
+	return protect
+}
+
+// prioritized scales the score by the app priority, -1000 makes the score 0 and 1000 doubles it.
+func (p Policy) prioritized(v Victim) float64 {
+	for app, priority := range p.Priority {
+		if appMatches(v.App, app) {
+			return v.Score * (1 + float64(priority)/1000)
+		}
+	}
+	return v.Score
+}
+
+// appMatches matches an app label like nextcloud.php-fpm against a snap name.
+func appMatches(label string, app string) bool {
+	return label == app || strings.HasPrefix(label, app+".")
+}
+
+// PolicyFile keeps the policy in a file shared by the backend, which changes it, and the watcher, which reloads it.
+type PolicyFile struct {
+	path string
+}
+
+func NewPolicyFile(path string) *PolicyFile {
+	return &PolicyFile{path: path}
+}
+
+func (f *PolicyFile) Load() (Policy, error) {
+	policy := DefaultPolicy()
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return policy, nil
+		}
+		return policy, err
+	}
+	err = json.Unmarshal(data, &policy)
+	if err != nil {
+		return DefaultPolicy(), err
+	}
+	err = policy.Validate()
+	if err != nil {
+		return DefaultPolicy(), err
+	}
+	return policy, nil
+}
+
+func (f *PolicyFile) Save(policy Policy) error {
+	err := policy.Validate()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// modified returns the modification time of the file, zero when there is no file.
+func (f *PolicyFile) modified() time.Time {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
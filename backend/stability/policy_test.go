@@ -0,0 +1,126 @@
+package stability
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPolicyFile_LoadDefaultWithoutFile(t *testing.T) {
+	policy, err := NewPolicyFile(filepath.Join(t.TempDir(), PolicyFileName)).Load()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPolicy(), policy)
+	assert.Equal(t, 4*time.Second, policy.Grace())
+}
+
+func TestPolicyFile_LoadPartial(t *testing.T) {
+	file := filepath.Join(t.TempDir(), PolicyFileName)
+	require.NoError(t, os.WriteFile(file, []byte(`{"avail_min": 0.03, "dry_run": true, "never_kill": ["nextcloud"]}`), 0644))
+	policy, err := NewPolicyFile(file).Load()
+	require.NoError(t, err)
+	assert.Equal(t, 0.03, policy.AvailMin)
+	assert.Equal(t, 40.0, policy.PSIMax)
+	assert.True(t, policy.DryRun)
+	assert.Equal(t, []string{"nextcloud"}, policy.NeverKill)
+}
+
+func TestPolicyFile_SaveValidates(t *testing.T) {
+	file := NewPolicyFile(filepath.Join(t.TempDir(), PolicyFileName))
+	policy := DefaultPolicy()
+	policy.AvailMin = 2
+	assert.Error(t, file.Save(policy))
+
+	policy = DefaultPolicy()
+	policy.Priority = map[string]int{"photoprism": 500}
+	require.NoError(t, file.Save(policy))
+	loaded, err := file.Load()
+	require.NoError(t, err)
+	assert.Equal(t, policy, loaded)
+}
+
+func TestPolicy_Protect(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Protect = Protect{Comms: []string{"mysqld"}}
+	policy.NeverKill = []string{"nextcloud"}
+	protect := policy.protect()
+	assert.True(t, protect.IsProtected(Victim{Comm: "mysqld"}))
+	assert.True(t, protect.IsProtected(Victim{Comm: "sshd"}))
+	assert.True(t, protect.IsProtected(Victim{Comm: "php-fpm", App: "nextcloud.php-fpm"}))
+	assert.False(t, protect.IsProtected(Victim{Comm: "php-fpm", App: "nextcloudx.php-fpm"}))
+}
+
+func TestPolicy_Prioritized(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Priority = map[string]int{"photoprism": 500, "syncthing": -1000, "nextcloud": 1000}
+	assert.Equal(t, 150.0, policy.prioritized(Victim{Score: 100, App: "photoprism"}))
+	assert.Equal(t, 150.0, policy.prioritized(Victim{Score: 100, App: "photoprism.server"}))
+	assert.Equal(t, 0.0, policy.prioritized(Victim{Score: 100, App: "syncthing"}))
+	assert.Equal(t, 200.0, policy.prioritized(Victim{Score: 100, App: "nextcloud.php-fpm"}))
+	assert.Equal(t, 100.0, policy.prioritized(Victim{Score: 100, App: "photoprismx"}))
+	assert.Equal(t, 100.0, policy.prioritized(Victim{Score: 100, OOMAdj: 300, App: "mail"}))
+}
+
+func TestWatcher_ReloadsChangedPolicy(t *testing.T) {
+	file := NewPolicyFile(filepath.Join(t.TempDir(), PolicyFileName))
+	w := NewWatcher(NewMemInfo(t.TempDir()), nil, nil, nil, file, nil, nil, zap.NewNop())
+	w.reload()
+	assert.Equal(t, DefaultPolicy(), w.policy)
+
+	policy := DefaultPolicy()
+	policy.PSIMax = 80
+	require.NoError(t, file.Save(policy))
+	w.reload()
+	assert.Equal(t, 80.0, w.policy.PSIMax)
+	assert.False(t, w.pressureExceeded(0.30, 50, true))
+
+	require.NoError(t, os.WriteFile(file.path, []byte(`{"psi_max": 500}`), 0644))
+	require.NoError(t, os.Chtimes(file.path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	w.reload()
+	assert.Equal(t, 80.0, w.policy.PSIMax)
+}
+
+func TestWatcher_DryRunDoesNotKill(t *testing.T) {
+	procDir := t.TempDir()
+	writeFakeProc(t, procDir, fakeProc{pid: 100, name: "photoprism", rssKB: 500000, cgroup: "0::/system.slice/snap.photoprism.web.service"})
+	w := newWatcherWithProc(t, 4000000, 100000, procDir)
+	w.events = NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	w.policy.DryRun = true
+	k := &fakeKill{alive: map[int]bool{100: true}}
+	w.kill = k.fn
+
+	require.NoError(t, w.tick())
+	require.NoError(t, w.tick())
+
+	assert.Empty(t, k.calls)
+	events, err := w.events.Recent(10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventKindVictimDryRun, events[0].Kind)
+	assert.Equal(t, "photoprism.web", events[0].App)
+	assert.Equal(t, EventKindPressure, events[1].Kind)
+}
+
+func TestWatcher_PriorityAndNeverKill(t *testing.T) {
+	procDir := t.TempDir()
+	writeFakeProc(t, procDir, fakeProc{pid: 100, name: "photoprism", rssKB: 500000, cgroup: "0::/system.slice/snap.photoprism.web.service"})
+	writeFakeProc(t, procDir, fakeProc{pid: 200, name: "syncthing", rssKB: 300000, cgroup: "0::/system.slice/snap.syncthing.syncthing.service"})
+	writeFakeProc(t, procDir, fakeProc{pid: 300, name: "mysqld", rssKB: 900000, cgroup: "0::/system.slice/snap.nextcloud.mysql.service"})
+	w := newWatcherWithProc(t, 4000000, 100000, procDir)
+	policy := DefaultPolicy()
+	policy.NeverKill = []string{"nextcloud"}
+	policy.Priority = map[string]int{"photoprism": -500, "syncthing": 0}
+	w.policy = policy
+	w.protect = policy.protect()
+	k := &fakeKill{alive: map[int]bool{}}
+	w.kill = k.fn
+
+	require.NoError(t, w.killWorst())
+	assert.Equal(t, 200, k.calls[0].pid)
+	assert.Equal(t, syscall.SIGTERM, k.calls[0].sig)
+}
@@ -3,8 +3,9 @@ package stability
 import "strings"
 
 type Protect struct {
-	Comms            []string
-	CgroupSubstrings []string
+	Comms            []string `json:"comms,omitempty"`
+	CgroupSubstrings []string `json:"cgroups,omitempty"`
+	Apps             []string `json:"-"`
 }
 
 func DefaultProtect() Protect {
@@ -38,5 +39,10 @@ func (p Protect) IsProtected(v Victim) bool {
 			return true
 		}
 	}
+	for _, app := range p.Apps {
+		if appMatches(v.App, app) {
+			return true
+		}
+	}
 	return false
 }
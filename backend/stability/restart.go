@@ -0,0 +1,164 @@
+package stability
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// restartWindow is the period the restart budget of an app is counted over.
+	restartWindow = time.Hour
+	// maxRestartWait caps the doubled wait, restarts older than the window are not counted anyway.
+	maxRestartWait = restartWindow
+	// maxRestartBudget bounds the restarts per window, a service killed more often is crash looping.
+	maxRestartBudget = 20
+)
+
+// RestartPolicy restarts the killed app services once the pressure is gone for QuietSeconds,
+// the wait doubles with every restart of the app within an hour up to an hour and Budget restarts are done per hour at most.
+type RestartPolicy struct {
+	Enabled      bool    `json:"enabled"`
+	QuietSeconds float64 `json:"quiet_seconds"`
+	Budget       int     `json:"budget"`
+}
+
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		Enabled:      true,
+		QuietSeconds: 30,
+		Budget:       3,
+	}
+}
+
+func (p RestartPolicy) Validate() error {
+	if p.QuietSeconds <= 0 {
+		return fmt.Errorf("restart.quiet_seconds should be positive")
+	}
+	if p.QuietSeconds > maxRestartWait.Seconds() {
+		return fmt.Errorf("restart.quiet_seconds should be at most %d", int(maxRestartWait.Seconds()))
+	}
+	if p.Budget < 0 || p.Budget > maxRestartBudget {
+		return fmt.Errorf("restart.budget should be between 0 and %d", maxRestartBudget)
+	}
+	return nil
+}
+
+func (p RestartPolicy) wait(restarts int) time.Duration {
+	wait := time.Duration(p.QuietSeconds * float64(time.Second))
+	for i := 0; i < restarts && wait < maxRestartWait; i++ {
+		wait *= 2
+	}
+	if wait > maxRestartWait {
+		return maxRestartWait
+	}
+	return wait
+}
+
+type ServiceControl interface {
+	RestartService(service string) error
+	IsServiceActive(service string) (bool, error)
+}
+
+// Restarter tracks the app services with killed processes and restarts the ones systemd did not bring back.
+type Restarter struct {
+	control      ServiceControl
+	events       *EventLog
+	log          *zap.Logger
+	killed       map[string]time.Time
+	restarts     map[string][]time.Time
+	lastPressure time.Time
+}
+
+func NewRestarter(control ServiceControl, events *EventLog, log *zap.Logger) *Restarter {
+	return &Restarter{
+		control:  control,
+		events:   events,
+		log:      log,
+		killed:   make(map[string]time.Time),
+		restarts: make(map[string][]time.Time),
+	}
+}
+
+// Killed remembers the service of a killed victim, processes outside of the app services are not restarted.
+func (r *Restarter) Killed(v Victim, now time.Time) {
+	unit := filepath.Base(strings.TrimPrefix(v.Cgroup, "0::"))
+	if !appServiceRe.MatchString(unit) {
+		return
+	}
+	service := strings.TrimSuffix(strings.TrimPrefix(unit, "snap."), ".service")
+	if _, ok := r.killed[service]; !ok {
+		r.killed[service] = now
+	}
+}
+
+func (r *Restarter) Pressure(now time.Time) {
+	r.lastPressure = now
+}
+
+// Restart restarts the killed services which waited long enough without pressure,
+// a service over the budget is not restarted until it is killed again.
+func (r *Restarter) Restart(policy RestartPolicy, now time.Time) {
+	for service, killed := range r.killed {
+		if !policy.Enabled {
+			delete(r.killed, service)
+			continue
+		}
+		restarts := r.recent(service, now)
+		if len(restarts) >= policy.Budget {
+			delete(r.killed, service)
+			r.log.Warn("oom-watcher: restart suppressed", zap.String("service", service), zap.Int("restarts", len(restarts)))
+			r.record(Event{
+				Kind:    EventKindNoRestart,
+				App:     service,
+				Message: fmt.Sprintf("%d restarts in the last %s, budget %d", len(restarts), restartWindow, policy.Budget),
+			})
+			continue
+		}
+		since := killed
+		if r.lastPressure.After(since) {
+			since = r.lastPressure
+		}
+		if now.Sub(since) < policy.wait(len(restarts)) {
+			continue
+		}
+		delete(r.killed, service)
+		active, err := r.control.IsServiceActive(service)
+		if err != nil {
+			r.log.Warn("oom-watcher: cannot check service", zap.String("service", service), zap.Error(err))
+			continue
+		}
+		if active {
+			r.log.Info("oom-watcher: service is back", zap.String("service", service))
+			continue
+		}
+		r.restarts[service] = append(restarts, now)
+		err = r.control.RestartService(service)
+		if err != nil {
+			r.log.Warn("oom-watcher: cannot restart service", zap.String("service", service), zap.Error(err))
+			continue
+		}
+		r.log.Info("oom-watcher: service restarted", zap.String("service", service))
+		r.record(Event{Kind: EventKindRestarted, App: service})
+	}
+}
+
+func (r *Restarter) recent(service string, now time.Time) []time.Time {
+	var recent []time.Time
+	for _, restart := range r.restarts[service] {
+		if now.Sub(restart) < restartWindow {
+			recent = append(recent, restart)
+		}
+	}
+	r.restarts[service] = recent
+	return recent
+}
+
+func (r *Restarter) record(event Event) {
+	if r.events != nil {
+		_ = r.events.Append(event)
+	}
+}
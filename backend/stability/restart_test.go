@@ -0,0 +1,111 @@
+package stability
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type ServiceControlStub struct {
+	active    bool
+	restarted []string
+}
+
+func (s *ServiceControlStub) RestartService(service string) error {
+	s.restarted = append(s.restarted, service)
+	return nil
+}
+
+func (s *ServiceControlStub) IsServiceActive(_ string) (bool, error) {
+	return s.active, nil
+}
+
+func victim(unit string) Victim {
+	cgroup := "0::/system.slice/" + unit
+	return Victim{PID: 100, Cgroup: cgroup, App: parseAppLabel(cgroup)}
+}
+
+func TestRestarter_RestartsAfterQuietPeriod(t *testing.T) {
+	control := &ServiceControlStub{}
+	events := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	restarter := NewRestarter(control, events, zap.NewNop())
+	policy := DefaultRestartPolicy()
+	now := time.Now()
+
+	restarter.Killed(victim("snap.nextcloud.php-fpm.service"), now)
+	restarter.Killed(Victim{PID: 200, Cgroup: "0::/user.slice/user-0.slice/session-1.scope"}, now)
+	restarter.Pressure(now.Add(10 * time.Second))
+	restarter.Restart(policy, now.Add(30*time.Second))
+	assert.Empty(t, control.restarted)
+
+	restarter.Restart(policy, now.Add(40*time.Second))
+	assert.Equal(t, []string{"nextcloud.php-fpm"}, control.restarted)
+
+	recent, err := events.Recent(10)
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, EventKindRestarted, recent[0].Kind)
+	assert.Equal(t, "nextcloud.php-fpm", recent[0].App)
+}
+
+func TestRestarter_SkipsServiceBroughtBackBySystemd(t *testing.T) {
+	control := &ServiceControlStub{active: true}
+	restarter := NewRestarter(control, nil, zap.NewNop())
+	now := time.Now()
+
+	restarter.Killed(victim("snap.gitea.server.service"), now)
+	restarter.Restart(DefaultRestartPolicy(), now.Add(time.Minute))
+	assert.Empty(t, control.restarted)
+	assert.Empty(t, restarter.killed)
+}
+
+func TestRestarter_BacksOffAndSuppressesOverBudget(t *testing.T) {
+	control := &ServiceControlStub{}
+	events := NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	restarter := NewRestarter(control, events, zap.NewNop())
+	policy := RestartPolicy{Enabled: true, QuietSeconds: 10, Budget: 2}
+	now := time.Now()
+
+	restarter.Killed(victim("snap.gitea.server.service"), now)
+	restarter.Restart(policy, now.Add(10*time.Second))
+	require.Len(t, control.restarted, 1)
+
+	now = now.Add(time.Minute)
+	restarter.Killed(victim("snap.gitea.server.service"), now)
+	restarter.Restart(policy, now.Add(10*time.Second))
+	assert.Len(t, control.restarted, 1, "second restart waits twice as long")
+	restarter.Restart(policy, now.Add(20*time.Second))
+	require.Len(t, control.restarted, 2)
+
+	now = now.Add(time.Minute)
+	restarter.Killed(victim("snap.gitea.server.service"), now)
+	restarter.Restart(policy, now.Add(time.Hour/2))
+	assert.Len(t, control.restarted, 2)
+
+	recent, err := events.Recent(1)
+	require.NoError(t, err)
+	assert.Equal(t, EventKindNoRestart, recent[0].Kind)
+	assert.Empty(t, restarter.killed)
+}
+
+func TestRestartPolicy_WaitIsCapped(t *testing.T) {
+	policy := RestartPolicy{Enabled: true, QuietSeconds: 30, Budget: maxRestartBudget}
+	assert.Equal(t, 30*time.Second, policy.wait(0))
+	assert.Equal(t, 2*time.Minute, policy.wait(2))
+	assert.Equal(t, time.Hour, policy.wait(7))
+	assert.Equal(t, time.Hour, policy.wait(maxRestartBudget))
+	assert.Equal(t, time.Hour, policy.wait(100))
+}
+
+func TestRestartPolicy_Validate(t *testing.T) {
+	assert.NoError(t, DefaultRestartPolicy().Validate())
+	assert.NoError(t, RestartPolicy{QuietSeconds: 3600, Budget: maxRestartBudget}.Validate())
+	assert.Error(t, RestartPolicy{QuietSeconds: 30, Budget: maxRestartBudget + 1}.Validate())
+	assert.Error(t, RestartPolicy{QuietSeconds: 30, Budget: -1}.Validate())
+	assert.Error(t, RestartPolicy{QuietSeconds: 3601, Budget: 3}.Validate())
+	assert.Error(t, RestartPolicy{QuietSeconds: 0, Budget: 3}.Validate())
+}
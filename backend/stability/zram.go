@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"go.uber.org/zap"
@@ -24,8 +25,33 @@ const (
 	zramMaxSizeBytes    = uint64(2 * 1024 * 1024 * 1024)
 	zramPriority        = 10
 	swapMagicV1         = "SWAPSPACE2"
+	// zramHistory is how far back the pressure events are checked for the swap usage.
+	zramHistory = 30 * 24 * time.Hour
 )
 
+// ZramPolicy orders the compression algorithms by preference, the first one supported by the kernel is used.
+type ZramPolicy struct {
+	Algorithms []string `json:"algorithms,omitempty"`
+}
+
+func DefaultZramPolicy() ZramPolicy {
+	return ZramPolicy{Algorithms: []string{"zstd", "lz4", "lzo-rle", "lzo"}}
+}
+
+// ParseCompAlgorithm parses comp_algorithm like "lzo lzo-rle lz4 [zstd]", the current one is in brackets.
+func ParseCompAlgorithm(content string) ([]string, string) {
+	var supported []string
+	current := ""
+	for _, field := range strings.Fields(content) {
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			field = strings.Trim(field, "[]")
+			current = field
+		}
+		supported = append(supported, field)
+	}
+	return supported, current
+}
+
 type Zram struct {
 	sysBlock  string
 	hotAdd    string
@@ -48,7 +74,8 @@ func NewZram(mem *MemInfo, events *EventLog, log *zap.Logger) *Zram {
 	}
 }
 
-func (z *Zram) EnsureConfigured() error {
+// EnsureConfigured sets up zram swap on boot, the size and the algorithm are kept until the next boot.
+func (z *Zram) EnsureConfigured(policy ZramPolicy) error {
 	snap, err := z.mem.Snapshot()
 	if err != nil {
 		return fmt.Errorf("zram: meminfo: %w", err)
@@ -67,8 +94,9 @@ func (z *Zram) EnsureConfigured() error {
 	if err := z.ensureDevice(); err != nil {
 		return fmt.Errorf("zram: device: %w", err)
 	}
-	size := z.sizeBytes(snap.TotalKB * 1024)
-	if err := z.configureSysfs(size); err != nil {
+	size := z.sizeBytes(snap.TotalKB*1024, z.peakSwapUsed(time.Now().Add(-zramHistory)))
+	algorithm := z.selectAlgorithm(policy.Algorithms)
+	if err := z.configureSysfs(size, algorithm); err != nil {
 		return fmt.Errorf("zram: configure: %w", err)
 	}
 	if err := mkswapInPlace(z.devPath); err != nil {
@@ -77,9 +105,9 @@ func (z *Zram) EnsureConfigured() error {
 	if err := z.swapon(z.devPath, swaponFlags(zramPriority)); err != nil {
 		return fmt.Errorf("zram: swapon: %w", err)
 	}
-	z.log.Info("zram: enabled", zap.Uint64("size_bytes", size), zap.Int("priority", zramPriority))
+	z.log.Info("zram: enabled", zap.Uint64("size_bytes", size), zap.String("algorithm", algorithm), zap.Int("priority", zramPriority))
 	if z.events != nil {
-		_ = z.events.Append(Event{Kind: EventKindZramEnabled, SizeBytes: size})
+		_ = z.events.Append(Event{Kind: EventKindZramEnabled, SizeBytes: size, Message: "algorithm " + algorithm})
 	}
 	if err := z.disableFileSwaps(); err != nil {
 		z.log.Warn("zram: file-swap disable failed", zap.Error(err))
@@ -160,17 +188,66 @@ func (z *Zram) ensureDevice() error {
 	return nil
 }
 
-func (z *Zram) sizeBytes(totalBytes uint64) uint64 {
-	half := totalBytes / 2
-	if half > zramMaxSizeBytes {
-		return zramMaxSizeBytes
+// sizeBytes is half of the memory up to zramMaxSizeBytes, when the pressure history shows more swap used
+// it grows to one and a half of the peak usage, up to the memory size.
+func (z *Zram) sizeBytes(totalBytes uint64, peakSwapBytes uint64) uint64 {
+	size := totalBytes / 2
+	if size > zramMaxSizeBytes {
+		size = zramMaxSizeBytes
+	}
+	observed := peakSwapBytes / 2 * 3
+	if observed > size {
+		size = observed
+	}
+	if size > totalBytes {
+		size = totalBytes
+	}
+	return size
+}
+
+// peakSwapUsed is the largest swap usage recorded with the pressure events since the time.
+func (z *Zram) peakSwapUsed(since time.Time) uint64 {
+	if z.events == nil {
+		return 0
+	}
+	peak := uint64(0)
+	z.events.mu.Lock()
+	defer z.events.mu.Unlock()
+	err := z.events.eachNewest(EventFilter{Kinds: []EventKind{EventKindPressure}, From: since}, func(e Event) bool {
+		if e.SwapUsedKB*1024 > peak {
+			peak = e.SwapUsedKB * 1024
+		}
+		return true
+	})
+	if err != nil {
+		z.log.Warn("zram: cannot read swap history", zap.Error(err))
 	}
-	return half
+	return peak
 }
 
-func (z *Zram) configureSysfs(sizeBytes uint64) error {
-	if err := os.WriteFile(filepath.Join(z.sysBlock, "comp_algorithm"), []byte("zstd"), 0644); err != nil {
-		z.log.Warn("zram: zstd unsupported, keeping kernel default algorithm", zap.Error(err))
+// selectAlgorithm returns the first preferred algorithm the kernel supports, the current one otherwise.
+func (z *Zram) selectAlgorithm(preferred []string) string {
+	content, err := os.ReadFile(filepath.Join(z.sysBlock, "comp_algorithm"))
+	if err != nil {
+		z.log.Warn("zram: cannot read supported algorithms", zap.Error(err))
+		return ""
+	}
+	supported, current := ParseCompAlgorithm(string(content))
+	for _, algorithm := range preferred {
+		for _, available := range supported {
+			if algorithm == available {
+				return algorithm
+			}
+		}
+	}
+	return current
+}
+
+func (z *Zram) configureSysfs(sizeBytes uint64, algorithm string) error {
+	if algorithm != "" {
+		if err := os.WriteFile(filepath.Join(z.sysBlock, "comp_algorithm"), []byte(algorithm), 0644); err != nil {
+			z.log.Warn("zram: cannot set algorithm, keeping kernel default", zap.String("algorithm", algorithm), zap.Error(err))
+		}
 	}
 	return os.WriteFile(filepath.Join(z.sysBlock, "disksize"), []byte(fmt.Sprintf("%d", sizeBytes)), 0644)
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -59,7 +60,7 @@ func TestEnsureSkipsIfAlreadyOn(t *testing.T) {
 	sc, err := os.ReadFile(z.procSwaps)
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(z.procSwaps, []byte(string(sc)+z.devPath+" partition 2097148 0 100\n"), 0644))
-	require.NoError(t, z.EnsureConfigured())
+	require.NoError(t, z.EnsureConfigured(DefaultZramPolicy()))
 	size, _ := os.ReadFile(filepath.Join(sysBlock, "disksize"))
 	assert.Equal(t, "0", string(size))
 }
@@ -79,7 +80,7 @@ func TestEnsureDeviceReturnsNilWhenPresent(t *testing.T) {
 
 func TestConfigureSysfsWritesAlgoAndSize(t *testing.T) {
 	z, sysBlock := newTestZram(t, 4*1024*1024, "")
-	require.NoError(t, z.configureSysfs(123456))
+	require.NoError(t, z.configureSysfs(123456, "zstd"))
 	algo, _ := os.ReadFile(filepath.Join(sysBlock, "comp_algorithm"))
 	assert.Equal(t, "zstd", string(algo))
 	size, _ := os.ReadFile(filepath.Join(sysBlock, "disksize"))
@@ -90,15 +91,46 @@ func TestConfigureSysfsToleratesUnsupportedAlgo(t *testing.T) {
 	z, sysBlock := newTestZram(t, 4*1024*1024, "")
 	require.NoError(t, os.Remove(filepath.Join(sysBlock, "comp_algorithm")))
 	require.NoError(t, os.Mkdir(filepath.Join(sysBlock, "comp_algorithm"), 0755))
-	require.NoError(t, z.configureSysfs(123456))
+	require.NoError(t, z.configureSysfs(123456, "zstd"))
 	size, _ := os.ReadFile(filepath.Join(sysBlock, "disksize"))
 	assert.Equal(t, "123456", string(size))
 }
 
 func TestSizeBytesCapped(t *testing.T) {
 	z, _ := newTestZram(t, 4*1024*1024, "")
-	assert.Equal(t, uint64(2*1024*1024*1024), z.sizeBytes(8*1024*1024*1024))
-	assert.Equal(t, uint64(1024*1024*1024), z.sizeBytes(2*1024*1024*1024))
+	assert.Equal(t, uint64(2*1024*1024*1024), z.sizeBytes(8*1024*1024*1024, 0))
+	assert.Equal(t, uint64(1024*1024*1024), z.sizeBytes(2*1024*1024*1024, 0))
+}
+
+func TestSizeBytesGrowsWithObservedSwap(t *testing.T) {
+	z, _ := newTestZram(t, 4*1024*1024, "")
+	assert.Equal(t, uint64(3*1024*1024*1024), z.sizeBytes(8*1024*1024*1024, 2*1024*1024*1024))
+	assert.Equal(t, uint64(2*1024*1024*1024), z.sizeBytes(2*1024*1024*1024, 2*1024*1024*1024))
+}
+
+func TestPeakSwapUsedFromPressureEvents(t *testing.T) {
+	z, _ := newTestZram(t, 4*1024*1024, "")
+	z.events = NewEventLog(filepath.Join(t.TempDir(), "events.jsonl"))
+	now := time.Now()
+	require.NoError(t, z.events.Append(Event{Time: now.Add(-60 * 24 * time.Hour), Kind: EventKindPressure, SwapUsedKB: 4000}))
+	require.NoError(t, z.events.Append(Event{Time: now.Add(-time.Hour), Kind: EventKindPressure, SwapUsedKB: 2000}))
+	require.NoError(t, z.events.Append(Event{Time: now, Kind: EventKindPressure, SwapUsedKB: 1000}))
+	require.NoError(t, z.events.Append(Event{Time: now, Kind: EventKindZramEnabled, SizeBytes: 8000000}))
+	assert.Equal(t, uint64(2000*1024), z.peakSwapUsed(now.Add(-zramHistory)))
+}
+
+func TestSelectAlgorithmByPreference(t *testing.T) {
+	z, sysBlock := newTestZram(t, 4*1024*1024, "")
+	require.NoError(t, os.WriteFile(filepath.Join(sysBlock, "comp_algorithm"), []byte("lzo lzo-rle [lz4] 842\n"), 0644))
+	assert.Equal(t, "lz4", z.selectAlgorithm(DefaultZramPolicy().Algorithms))
+	assert.Equal(t, "lzo", z.selectAlgorithm([]string{"zstd", "lzo"}))
+	assert.Equal(t, "lz4", z.selectAlgorithm([]string{"zstd"}))
+}
+
+func TestParseCompAlgorithm(t *testing.T) {
+	supported, current := ParseCompAlgorithm("lzo lzo-rle lz4 [zstd]\n")
+	assert.Equal(t, []string{"lzo", "lzo-rle", "lz4", "zstd"}, supported)
+	assert.Equal(t, "zstd", current)
 }
 
 func TestMkswapInPlaceWritesHeader(t *testing.T) {
@@ -43,14 +43,23 @@ func (s *Stats) RaidMode(uuid string) (string, error) {
 }
 
 func (s *Stats) HasErrors(device string) (bool, error) {
-	output, err := s.executor.CombinedOutput(BTRFS, "--format", "json", "device", "stats", s.config.ExternalDiskDir())
+	result, err := s.DeviceErrors()
 	if err != nil {
 		return false, err
 	}
+	return result.HasErrors(device), nil
+}
+
+// DeviceErrors returns the error counters of the devices of the external disk filesystem.
+func (s *Stats) DeviceErrors() (*DeviceStats, error) {
+	output, err := s.executor.CombinedOutput(BTRFS, "--format", "json", "device", "stats", s.config.ExternalDiskDir())
+	if err != nil {
+		return nil, err
+	}
 
 	var result DeviceStats
 	if err := json.Unmarshal(output, &result); err != nil {
-		return false, err
+		return nil, err
 	}
-	return result.HasErrors(device), nil
+	return &result, nil
 }
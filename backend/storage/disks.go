@@ -27,6 +27,7 @@ type Disks struct {
 	executor         cli.Executor
 	btrfs            BtrfsDisks
 	btrfsStats       BtrfsDiskStats
+	smart            DisksSmart
 	lastError        error
 	logger           *zap.Logger
 }
@@ -69,6 +70,10 @@ type BtrfsDiskStats interface {
 	HasErrors(device string) (bool, error)
 }
 
+type DisksSmart interface {
+	Health(device string) (*model.SmartHealth, error)
+}
+
 func NewDisks(
 	config DisksConfig,
 	trigger DisksEventTrigger,
@@ -79,6 +84,7 @@ func NewDisks(
 	executor cli.Executor,
 	btrfs BtrfsDisks,
 	btrfsStats BtrfsDiskStats,
+	smart DisksSmart,
 	logger *zap.Logger) *Disks {
 
 	return &Disks{
@@ -91,6 +97,7 @@ func NewDisks(
 		executor:         executor,
 		btrfs:            btrfs,
 		btrfsStats:       btrfsStats,
+		smart:            smart,
 		logger:           logger,
 	}
 }
@@ -134,6 +141,12 @@ func (d *Disks) AvailableDisks() ([]model.Disk, error) {
 		} else {
 			disks[i].HasErrors = hasErrors
 		}
+		health, err := d.smart.Health(disk.Device)
+		if err != nil {
+			d.logger.Info("unable to get smart", zap.String("device", disk.Device), zap.Error(err))
+		} else {
+			disks[i].Smart = health
+		}
 	}
 	return disks, err
 }
@@ -148,12 +148,24 @@ func (b *BtrfsDiskStatsStub) HasErrors(device string) (bool, error) {
 	return b.errors[device], nil
 }
 
+type SmartStub struct {
+	health map[string]*model.SmartHealth
+}
+
+func (s *SmartStub) Health(device string) (*model.SmartHealth, error) {
+	health, ok := s.health[device]
+	if !ok {
+		return nil, fmt.Errorf("smart is not available")
+	}
+	return health, nil
+}
+
 func TestDisks_RootPartition_HasFreeSpace_Extendable(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false},
+		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{freeSpace: true}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{freeSpace: true}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	partition, err := disks.RootPartition()
 	assert.Nil(t, err)
 	assert.True(t, partition.Extendable)
@@ -162,9 +174,9 @@ func TestDisks_RootPartition_HasFreeSpace_Extendable(t *testing.T) {
 func TestDisks_RootPartition_HasNoFreeSpace_NonExtendable(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false},
+		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{freeSpace: false}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{freeSpace: false}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	partition, err := disks.RootPartition()
 	assert.Nil(t, err)
 	assert.False(t, partition.Extendable)
@@ -173,9 +185,9 @@ func TestDisks_RootPartition_HasNoFreeSpace_NonExtendable(t *testing.T) {
 func TestDisks_DeactivateDisk_TriggerError_NotFail(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false},
+		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: true}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: true}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.Deactivate()
 	assert.Nil(t, err)
 }
@@ -183,9 +195,9 @@ func TestDisks_DeactivateDisk_TriggerError_NotFail(t *testing.T) {
 func TestDisks_DeactivateDisk_TriggerNotError_NotFail(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false},
+		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.Deactivate()
 	assert.Nil(t, err)
 }
@@ -193,12 +205,12 @@ func TestDisks_DeactivateDisk_TriggerNotError_NotFail(t *testing.T) {
 func TestDisks_DeactivateDisk_TriggerEventBeforeRemove(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false},
+		{"", "", "", []model.Partition{{"", "", "/", true, "", false}}, false, "", "", "", false, false, nil},
 	}
 	callOrder := &CallOrder{order: 0}
 	trigger := &TriggerStub{error: false, callOrderShared: callOrder}
 	systemd := &SystemdStub{callOrderShared: callOrder}
-	disks := NewDisks(&DisksConfigStub{}, trigger, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, trigger, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.Deactivate()
 	assert.Nil(t, err)
 	assert.Less(t, trigger.callOrder, systemd.callOrder)
@@ -208,9 +220,9 @@ func TestDisks_DeactivateDisk_TriggerEventBeforeRemove(t *testing.T) {
 func TestDisks_ActivatePartition_SupportedFs(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "ext4", false}}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "ext4", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivatePartition("/dev/sda1")
 	assert.Nil(t, err)
 }
@@ -218,9 +230,9 @@ func TestDisks_ActivatePartition_SupportedFs(t *testing.T) {
 func TestDisks_ActivatePartition_Btrfs(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "", true, "btrfs", false}}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "", true, "btrfs", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivatePartition("/dev/sda1")
 	assert.Nil(t, err)
 }
@@ -228,9 +240,9 @@ func TestDisks_ActivatePartition_Btrfs(t *testing.T) {
 func TestDisks_ActivatePartition_NotSupportedFs(t *testing.T) {
 
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "fat32", false}}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "fat32", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivatePartition("/dev/sda1")
 	assert.NotNil(t, err)
 }
@@ -239,9 +251,9 @@ func TestDisks_ActivateDisks_None(t *testing.T) {
 	executor := &DisksExecutorStub{}
 
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "fat32", false}}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "fat32", false}}, false, "", "", "", false, false, nil},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, executor, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, executor, &BtrfsDisksStub{}, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivateDisks([]string{}, false)
 	assert.NotNil(t, err)
 	assert.Equal(t, err, disks.GetLastError())
@@ -250,11 +262,11 @@ func TestDisks_ActivateDisks_None(t *testing.T) {
 
 func TestDisks_ActivateDisks_UseUuid(t *testing.T) {
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{}, true, "uuid1", "", "", false, false},
-		{"", "/dev/sdb", "", []model.Partition{}, false, "uuid2", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{}, true, "uuid1", "", "", false, false, nil},
+		{"", "/dev/sdb", "", []model.Partition{}, false, "uuid2", "", "", false, false, nil},
 	}
 	btrfs := &BtrfsDisksStub{}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivateDisks([]string{"/dev/sdb"}, true)
 	assert.Nil(t, err)
 	assert.Nil(t, disks.GetLastError())
@@ -265,11 +277,11 @@ func TestDisks_ActivateDisks_UseUuid(t *testing.T) {
 
 func TestDisks_ActivateDisks_UseUuidExpand(t *testing.T) {
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{}, true, "uuid1", "", "", false, false},
-		{"", "/dev/sdb", "", []model.Partition{}, false, "uuid2", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{}, true, "uuid1", "", "", false, false, nil},
+		{"", "/dev/sdb", "", []model.Partition{}, false, "uuid2", "", "", false, false, nil},
 	}
 	btrfs := &BtrfsDisksStub{}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivateDisks([]string{"/dev/sda", "/dev/sdb"}, true)
 	assert.Nil(t, err)
 	assert.Nil(t, disks.GetLastError())
@@ -280,11 +292,11 @@ func TestDisks_ActivateDisks_UseUuidExpand(t *testing.T) {
 
 func TestDisks_ActivateDisks_0_To_2_UseFirstUuid(t *testing.T) {
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{}, false, "uuid1", "", "", false, false},
-		{"", "/dev/sdb", "", []model.Partition{}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{}, false, "uuid1", "", "", false, false, nil},
+		{"", "/dev/sdb", "", []model.Partition{}, false, "", "", "", false, false, nil},
 	}
 	btrfs := &BtrfsDisksStub{}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, &SystemdStub{}, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivateDisks([]string{"/dev/sda", "/dev/sdb"}, true)
 	assert.Nil(t, err)
 	assert.Nil(t, disks.GetLastError())
@@ -295,12 +307,12 @@ func TestDisks_ActivateDisks_0_To_2_UseFirstUuid(t *testing.T) {
 
 func TestDisks_ActivateDisks_PartitionToDisk_Deactivate(t *testing.T) {
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "fat32", false}}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{{"", "/dev/sda1", "/", true, "fat32", false}}, false, "", "", "", false, false, nil},
 	}
 	btrfs := &BtrfsDisksStub{}
 	systemd := &SystemdStub{}
 
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivateDisks([]string{"/dev/sda"}, true)
 	assert.Nil(t, err)
 	assert.Nil(t, disks.GetLastError())
@@ -312,12 +324,12 @@ func TestDisks_ActivateDisks_PartitionToDisk_Deactivate(t *testing.T) {
 
 func TestDisks_ActivateDisks_BterfsError(t *testing.T) {
 	allDisks := []model.Disk{
-		{"", "/dev/sda", "", []model.Partition{}, false, "", "", "", false, false},
+		{"", "/dev/sda", "", []model.Partition{}, false, "", "", "", false, false, nil},
 	}
 	btrfs := &BtrfsDisksStub{error: true}
 	systemd := &SystemdStub{}
 
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	err := disks.ActivateDisks([]string{"/dev/sda"}, true)
 	assert.NotNil(t, err)
 	assert.Equal(t, err, disks.GetLastError())
@@ -330,7 +342,7 @@ func TestDisks_ClearLastError(t *testing.T) {
 	btrfs := &BtrfsDisksStub{error: true}
 	systemd := &SystemdStub{}
 
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, log.Default())
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, &BtrfsDiskStatsStub{}, &SmartStub{}, log.Default())
 	assert.Nil(t, disks.GetLastError())
 	err := disks.ActivateDisks([]string{"/dev/sda"}, true)
 	assert.NotNil(t, err)
@@ -341,8 +353,8 @@ func TestDisks_ClearLastError(t *testing.T) {
 
 func TestDisks_AvailableDisks(t *testing.T) {
 	allDisks := []model.Disk{
-		{"", "/dev/loop0", "", []model.Partition{}, false, "uuid1", "", "", false, false},
-		{"", "/dev/loop1", "", []model.Partition{}, false, "uuid2", "", "", false, false},
+		{"", "/dev/loop0", "", []model.Partition{}, false, "uuid1", "", "", false, false, nil},
+		{"", "/dev/loop1", "", []model.Partition{}, false, "uuid2", "", "", false, false, nil},
 	}
 	btrfs := &BtrfsDisksStub{error: true}
 	systemd := &SystemdStub{}
@@ -357,12 +369,17 @@ func TestDisks_AvailableDisks(t *testing.T) {
 			"/dev/loop1": false,
 		},
 	}
-	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, stats, log.Default())
+	smart := &SmartStub{health: map[string]*model.SmartHealth{
+		"/dev/loop0": {Status: model.SmartWarning, Passed: true, ReallocatedSectors: 8},
+	}}
+	disks := NewDisks(&DisksConfigStub{}, &TriggerStub{error: false}, &LsblkDisksStub{disks: allDisks}, systemd, &DisksFreeSpaceCheckerStub{}, &DisksLinkerStub{}, &DisksExecutorStub{}, btrfs, stats, smart, log.Default())
 	available, err := disks.AvailableDisks()
 	assert.Nil(t, err)
 	assert.Len(t, available, 2)
 	assert.Equal(t, "raid1", available[0].Raid)
 	assert.True(t, available[0].HasErrors)
+	assert.Equal(t, model.SmartWarning, available[0].Smart.Status)
 	assert.Equal(t, "raid2", available[1].Raid)
 	assert.False(t, available[1].HasErrors)
+	assert.Nil(t, available[1].Smart)
 }
@@ -6,16 +6,17 @@ import (
 )
 
 type Disk struct {
-	Name       string      `json:"name"`
-	Device     string      `json:"device"`
-	Size       string      `json:"size"`
-	Partitions []Partition `json:"partitions"`
-	Active     bool        `json:"active"`
-	Uuid       string      `json:"uuid"`
-	MountPoint string      `json:"mount_point"`
-	Raid       string      `json:"raid"`
-	HasErrors  bool        `json:"has_errors"`
-	Boot       bool        `json:"boot"`
+	Name       string       `json:"name"`
+	Device     string       `json:"device"`
+	Size       string       `json:"size"`
+	Partitions []Partition  `json:"partitions"`
+	Active     bool         `json:"active"`
+	Uuid       string       `json:"uuid"`
+	MountPoint string       `json:"mount_point"`
+	Raid       string       `json:"raid"`
+	HasErrors  bool         `json:"has_errors"`
+	Boot       bool         `json:"boot"`
+	Smart      *SmartHealth `json:"smart,omitempty"`
 }
 
 type UiDeviceEntry struct {
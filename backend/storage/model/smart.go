@@ -0,0 +1,19 @@
+package model
+
+const (
+	SmartOk      = "ok"
+	SmartWarning = "warning"
+	SmartFailing = "failing"
+)
+
+// SmartHealth is the SMART summary of a disk, it is failing when the disk self-assessment fails
+// and a warning when sectors are reallocated or pending or the disk runs hot.
+type SmartHealth struct {
+	Status             string `json:"status"`
+	Serial             string `json:"serial,omitempty"`
+	Passed             bool   `json:"passed"`
+	ReallocatedSectors uint64 `json:"reallocated_sectors"`
+	PendingSectors     uint64 `json:"pending_sectors"`
+	Temperature        int    `json:"temperature"`
+	PowerOnHours       uint64 `json:"power_on_hours"`
+}
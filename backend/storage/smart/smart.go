@@ -0,0 +1,120 @@
+package smart
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/syncloud/platform/cli"
+	"github.com/syncloud/platform/storage/model"
+	"go.uber.org/zap"
+)
+
+const (
+	attributeReallocated = 5
+	attributePending     = 197
+	// hotTemperature is the temperature in Celsius a disk gets a warning at.
+	hotTemperature = 55
+	// exitUnusable are the smartctl exit status bits of a failed command line or a device
+	// which could not be opened or is in standby.
+	exitUnusable = 0x3
+)
+
+var ErrUnavailable = errors.New("smart is not available")
+
+type Smart struct {
+	executor cli.Executor
+	logger   *zap.Logger
+}
+
+func New(executor cli.Executor, logger *zap.Logger) *Smart {
+	return &Smart{executor: executor, logger: logger}
+}
+
+type attribute struct {
+	Id  int `json:"id"`
+	Raw struct {
+		Value uint64 `json:"value"`
+	} `json:"raw"`
+}
+
+type output struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String string `json:"string"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	AtaSmartAttributes struct {
+		Table []attribute `json:"table"`
+	} `json:"ata_smart_attributes"`
+	PowerOnTime struct {
+		Hours uint64 `json:"hours"`
+	} `json:"power_on_time"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+}
+
+// Health reads SMART of a device, a sleeping disk is not woken up and reported as unavailable.
+// smartctl exits with a non zero status bit mask for a failing disk too, so the output is parsed anyway.
+func (s *Smart) Health(device string) (*model.SmartHealth, error) {
+	out, execErr := s.executor.CombinedOutput("smartctl", "--json", "-n", "standby", "-H", "-A", "-i", device)
+	health, err := Parse(out)
+	if err != nil {
+		if execErr != nil {
+			return nil, fmt.Errorf("smartctl %s: %v: %w", device, execErr, err)
+		}
+		return nil, fmt.Errorf("smartctl %s: %w", device, err)
+	}
+	return health, nil
+}
+
+func Parse(data []byte) (*model.SmartHealth, error) {
+	var result output
+	err := json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Smartctl.ExitStatus&exitUnusable != 0 || result.SmartStatus == nil {
+		var messages []string
+		for _, message := range result.Smartctl.Messages {
+			messages = append(messages, message.String)
+		}
+		if len(messages) == 0 {
+			return nil, ErrUnavailable
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, strings.Join(messages, ", "))
+	}
+	health := &model.SmartHealth{
+		Serial:       result.SerialNumber,
+		Passed:       result.SmartStatus.Passed,
+		Temperature:  result.Temperature.Current,
+		PowerOnHours: result.PowerOnTime.Hours,
+	}
+	for _, attr := range result.AtaSmartAttributes.Table {
+		switch attr.Id {
+		case attributeReallocated:
+			health.ReallocatedSectors = attr.Raw.Value
+		case attributePending:
+			health.PendingSectors = attr.Raw.Value
+		}
+	}
+	health.Status = status(health)
+	return health, nil
+}
+
+func status(health *model.SmartHealth) string {
+	if !health.Passed {
+		return model.SmartFailing
+	}
+	if health.ReallocatedSectors > 0 || health.PendingSectors > 0 || health.Temperature >= hotTemperature {
+		return model.SmartWarning
+	}
+	return model.SmartOk
+}
@@ -0,0 +1,93 @@
+package smart
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syncloud/platform/log"
+	"github.com/syncloud/platform/storage/model"
+)
+
+type ExecutorStub struct {
+	output []byte
+	err    error
+	args   []string
+}
+
+func (e *ExecutorStub) CombinedOutput(_ string, args ...string) ([]byte, error) {
+	e.args = args
+	return e.output, e.err
+}
+
+func fixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(path.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse_UsbHdd_Warning(t *testing.T) {
+	health, err := Parse(fixture(t, "usb_hdd.json"))
+	require.NoError(t, err)
+	assert.Equal(t, &model.SmartHealth{
+		Status:             model.SmartWarning,
+		Serial:             "WD-WX21A75P3KTE",
+		Passed:             true,
+		ReallocatedSectors: 8,
+		PendingSectors:     2,
+		Temperature:        41,
+		PowerOnHours:       20711,
+	}, health)
+}
+
+func TestParse_Ssd_Ok(t *testing.T) {
+	health, err := Parse(fixture(t, "ssd.json"))
+	require.NoError(t, err)
+	assert.Equal(t, model.SmartOk, health.Status)
+	assert.Equal(t, 33, health.Temperature)
+	assert.Equal(t, uint64(1843), health.PowerOnHours)
+}
+
+func TestParse_Failing(t *testing.T) {
+	health, err := Parse(fixture(t, "failing.json"))
+	require.NoError(t, err)
+	assert.Equal(t, model.SmartFailing, health.Status)
+	assert.False(t, health.Passed)
+	assert.Equal(t, uint64(3960), health.ReallocatedSectors)
+}
+
+func TestParse_Hot_Warning(t *testing.T) {
+	health, err := Parse([]byte(`{"smartctl": {"exit_status": 0}, "smart_status": {"passed": true}, "temperature": {"current": 58}}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.SmartWarning, health.Status)
+}
+
+func TestParse_Standby_Unavailable(t *testing.T) {
+	_, err := Parse(fixture(t, "standby.json"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Contains(t, err.Error(), "STANDBY")
+}
+
+func TestHealth_FailingDiskExitStatus(t *testing.T) {
+	executor := &ExecutorStub{output: fixture(t, "failing.json"), err: errors.New("exit status 24")}
+	health, err := New(executor, log.Default()).Health("/dev/sdc")
+	require.NoError(t, err)
+	assert.Equal(t, model.SmartFailing, health.Status)
+	assert.Equal(t, "/dev/sdc", executor.args[len(executor.args)-1])
+	assert.Contains(t, executor.args, "standby")
+}
+
+func TestHealth_Unsupported(t *testing.T) {
+	executor := &ExecutorStub{output: fixture(t, "unsupported.json"), err: errors.New("exit status 1")}
+	_, err := New(executor, log.Default()).Health("/dev/mmcblk0")
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestHealth_NotInstalled(t *testing.T) {
+	executor := &ExecutorStub{output: []byte("smartctl: not found"), err: errors.New("exit status 127")}
+	_, err := New(executor, log.Default()).Health("/dev/sda")
+	assert.Error(t, err)
+}
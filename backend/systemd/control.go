@@ -45,6 +45,19 @@ func (c *Control) ReloadService(service string) error {
 	return err
 }
 
+// IsServiceActive checks a snap service, systemctl is-active fails for a service which is not active.
+func (c *Control) IsServiceActive(service string) (bool, error) {
+	output, err := c.executor.CombinedOutput("systemctl", "is-active", c.serviceName(service))
+	status := strings.TrimSpace(string(output))
+	if err != nil {
+		if status == "" {
+			return false, err
+		}
+		return false, nil
+	}
+	return status == "active", nil
+}
+
 func (c *Control) serviceName(service string) string {
 	return fmt.Sprintf("snap.%s", service)
 }
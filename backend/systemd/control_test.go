@@ -96,3 +96,26 @@ func TestControl_RestartService(t *testing.T) {
 	assert.Len(t, executor.calls, 1)
 	assert.Equal(t, executor.calls[0], "restart snap.app1")
 }
+
+func TestControl_IsServiceActive(t *testing.T) {
+	status := "active"
+	var statusErr error
+	executorFunc := ExecutorFunc(
+		func(arg string) (string, error) {
+			return status + "\n", statusErr
+		})
+	control := New(executorFunc, &ConfigStub{}, log.Default())
+
+	active, err := control.IsServiceActive("nextcloud.php-fpm")
+	assert.Nil(t, err)
+	assert.True(t, active)
+
+	status, statusErr = "failed", fmt.Errorf("exit status 3")
+	active, err = control.IsServiceActive("nextcloud.php-fpm")
+	assert.Nil(t, err)
+	assert.False(t, active)
+
+	status, statusErr = "", fmt.Errorf("not found")
+	_, err = control.IsServiceActive("nextcloud.php-fpm")
+	assert.NotNil(t, err)
+}
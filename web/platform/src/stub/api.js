@@ -877,7 +877,7 @@ export function mock () {
           { time: new Date(now - 3 * 3600 * 1000).toISOString(), kind: 'swapoff_file', path: '/swapfile' },
           { time: new Date(now - 3 * 3600 * 1000 - 200).toISOString(), kind: 'zram_enabled', size_bytes: 1939916800 }
         ]
-        return new Response(200, {}, { success: true, data: events })
+        return new Response(200, {}, { success: true, data: { events } })
       })
     }
   })